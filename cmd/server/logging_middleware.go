@@ -5,9 +5,10 @@
 package main
 
 import (
-	"log"
 	"net/http"
 	"time"
+
+	"kapua-mcp-server/pkg/utils"
 )
 
 // responseWriter wraps http.ResponseWriter to capture the status code.
@@ -22,30 +23,29 @@ func (rw *responseWriter) WriteHeader(code int) {
 }
 
 func LoggingHandler(handler http.Handler) http.Handler {
+	logger := utils.NewDefaultLogger("HTTP")
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
 		// Create a response writer wrapper to capture status code.
 		wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
 
-		// Log request details.
-		log.Printf("[REQUEST] %s | %s | %s %s",
-			start.Format(time.RFC3339),
-			r.RemoteAddr,
-			r.Method,
-			r.URL.Path)
+		requestLogger := logger.With(
+			"remote_addr", r.RemoteAddr,
+			"method", r.Method,
+			"path", r.URL.Path,
+		)
+		if sessionID := r.Header.Get("Mcp-Session-Id"); sessionID != "" {
+			requestLogger = requestLogger.With("session_id", sessionID)
+		}
+		requestLogger.Info("HTTP request received")
 
 		// Call the actual handler.
 		handler.ServeHTTP(wrapped, r)
 
-		// Log response details.
-		duration := time.Since(start)
-		log.Printf("[RESPONSE] %s | %s | %s %s | Status: %d | Duration: %v",
-			time.Now().Format(time.RFC3339),
-			r.RemoteAddr,
-			r.Method,
-			r.URL.Path,
-			wrapped.statusCode,
-			duration)
+		requestLogger.With(
+			"status", wrapped.statusCode,
+			"duration_ms", time.Since(start).Milliseconds(),
+		).Info("HTTP request completed")
 	})
 }
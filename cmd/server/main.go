@@ -2,25 +2,37 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
 
 	"kapua-mcp-server/internal/kapua/config"
 	mcpserver "kapua-mcp-server/internal/mcp"
+	"kapua-mcp-server/pkg/tracing"
+	"kapua-mcp-server/pkg/utils"
 )
 
 type kapuaServer interface {
 	Handler(*mcpserver.HTTPConfig) http.Handler
-	ListenAndServe(string, http.Handler) error
+	ListenAndServe(context.Context, string, http.Handler) error
+	ListenAndServeTLS(context.Context, string, http.Handler, *tls.Config) error
 	RunTransport(context.Context, string, mcpsdk.Transport) error
+	Shutdown(context.Context) error
 }
 
+// defaultShutdownTimeout is how long in-flight requests may run after SIGINT/SIGTERM.
+const defaultShutdownTimeout = 30 * time.Second
+
 var newServer = func(ctx context.Context, cfg *config.Config) (kapuaServer, error) {
 	return mcpserver.NewServer(ctx, cfg)
 }
@@ -29,23 +41,46 @@ var (
 	httpMode = flag.Bool("http", false, "Run the MCP server with the HTTP streamable transport instead of stdio")
 	host     = flag.String("host", "localhost", "For http-streamable server, the host to listen on")
 	port     = flag.Int("port", 8000, "For http-streamable server, the port number to listen on")
+
+	tlsCert       = flag.String("tls-cert", "", "For http-streamable server, TLS certificate file (enables HTTPS; overrides MCP_TLS_CERT_FILE)")
+	tlsKey        = flag.String("tls-key", "", "For http-streamable server, TLS private key file (overrides MCP_TLS_KEY_FILE)")
+	tlsClientCA   = flag.String("tls-client-ca", "", "For http-streamable server, CA bundle required for client certificates (mTLS; overrides MCP_TLS_CLIENT_CA_FILE)")
+	tlsMinVersion = flag.String("tls-min-version", "", "For http-streamable server, minimum TLS version: 1.2 or 1.3 (overrides MCP_TLS_MIN_VERSION)")
+
+	shutdownTimeoutFlag = flag.Duration("shutdown-timeout", 0, "How long in-flight requests may finish on shutdown, e.g. 45s (overrides MCP_SHUTDOWN_TIMEOUT; default 30s)")
+
+	readOnly = flag.Bool("read-only", false, "Register only the tools that do not change devices (overrides KAPUA_READ_ONLY)")
 )
 
 func main() {
 	out := flag.CommandLine.Output()
 	flag.Usage = func() {
-		fmt.Fprintf(out, "Usage: %s [-http] [-port <port>] [-host <host>]\n\n", os.Args[0])
+		fmt.Fprintf(out, "Usage: %s [-http] [-port <port>] [-host <host>] [-tls-cert <file> -tls-key <file> [-tls-client-ca <file>]] [-shutdown-timeout <duration>] [-read-only]\n\n", os.Args[0])
 		fmt.Fprintf(out, "Kapua MCP Server for Eclipse Kapua IoT Device Management.\n")
 		fmt.Fprintf(out, "Options:\n")
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
 	flag.Parse()
+	utils.SetupDefaultLogger()
 
 	kapuaCfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
+	if *readOnly {
+		kapuaCfg.Kapua.ReadOnly = true
+	}
+
+	shutdownTimeout, err := resolveShutdownTimeout(*shutdownTimeoutFlag)
+	if err != nil {
+		log.Fatalf("Invalid shutdown timeout: %v", err)
+	}
+
+	shutdownTracing, err := tracing.SetupFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure tracing: %v", err)
+	}
 
 	var httpCfg *mcpserver.HTTPConfig
 	if *httpMode {
@@ -59,43 +94,106 @@ func main() {
 		if *port != 8000 {
 			httpCfg.SetPort(*port)
 		}
+		if *tlsCert != "" {
+			httpCfg.TLSCertFile = *tlsCert
+		}
+		if *tlsKey != "" {
+			httpCfg.TLSKeyFile = *tlsKey
+		}
+		if *tlsClientCA != "" {
+			httpCfg.TLSClientCAFile = *tlsClientCA
+		}
+		if *tlsMinVersion != "" {
+			httpCfg.TLSMinVersion = *tlsMinVersion
+		}
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	srv, err := newServer(ctx, kapuaCfg)
+	startupCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	srv, err := newServer(startupCtx, kapuaCfg)
+	cancel()
 	if err != nil {
 		log.Fatalf("Failed to initialise MCP server: %v", err)
 	}
 
 	if *httpMode {
-		if err := runHTTPServer(srv, httpCfg); err != nil {
-			log.Fatalf("Server failed: %v", err)
-		}
+		err = runHTTPServer(ctx, srv, httpCfg)
 	} else {
-		if err := runStdioServer(srv); err != nil {
-			log.Fatalf("Server failed: %v", err)
-		}
+		err = runStdioServer(ctx, srv)
+	}
+	stop()
+
+	if shutdownErr := shutdownServer(srv, shutdownTimeout); shutdownErr != nil {
+		log.Printf("Shutdown incomplete: %v", shutdownErr)
+	}
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+	if tracingErr := shutdownTracing(flushCtx); tracingErr != nil {
+		log.Printf("Failed to flush traces: %v", tracingErr)
+	}
+	cancelFlush()
+	if err != nil {
+		log.Fatalf("Server failed: %v", err)
+	}
+}
+
+// resolveShutdownTimeout returns the -shutdown-timeout flag if set, otherwise
+// MCP_SHUTDOWN_TIMEOUT (a duration such as "45s" or a number of seconds).
+func resolveShutdownTimeout(flagValue time.Duration) (time.Duration, error) {
+	if flagValue > 0 {
+		return flagValue, nil
+	}
+	value := strings.TrimSpace(os.Getenv("MCP_SHUTDOWN_TIMEOUT"))
+	if value == "" {
+		return defaultShutdownTimeout, nil
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("MCP_SHUTDOWN_TIMEOUT must be a positive duration or number of seconds, got %q", value)
 	}
+	return timeout, nil
 }
 
-func runHTTPServer(srv kapuaServer, httpCfg *mcpserver.HTTPConfig) error {
+// shutdownServer drains srv within timeout and logs it out of Kapua.
+func shutdownServer(srv kapuaServer, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return srv.Shutdown(ctx)
+}
+
+// runHTTPServer serves the HTTP transport until ctx is cancelled.
+func runHTTPServer(ctx context.Context, srv kapuaServer, httpCfg *mcpserver.HTTPConfig) error {
 	if httpCfg == nil {
 		return fmt.Errorf("http transport requires configuration")
 	}
 
 	addr := fmt.Sprintf("%s:%d", httpCfg.Host, httpCfg.Port)
 	handlerWithLogging := LoggingHandler(srv.Handler(httpCfg))
-	if err := srv.ListenAndServe(addr, handlerWithLogging); err != nil {
+
+	if httpCfg.TLSEnabled() {
+		tlsCfg, err := httpCfg.TLSConfig()
+		if err != nil {
+			return fmt.Errorf("invalid TLS configuration: %w", err)
+		}
+		if err := srv.ListenAndServeTLS(ctx, addr, handlerWithLogging, tlsCfg); err != nil {
+			return fmt.Errorf("server failed: %w", err)
+		}
+		return nil
+	}
+
+	if err := srv.ListenAndServe(ctx, addr, handlerWithLogging); err != nil {
 		return fmt.Errorf("server failed: %w", err)
 	}
 
 	return nil
 }
 
-func runStdioServer(srv kapuaServer) error {
-	ctx := context.Background()
+// runStdioServer serves the stdio transport until the client disconnects or ctx is cancelled.
+func runStdioServer(ctx context.Context, srv kapuaServer) error {
 	loggingTransport := &mcpsdk.LoggingTransport{Transport: &mcpsdk.StdioTransport{}, Writer: os.Stderr}
 	if err := srv.RunTransport(ctx, "stdio", loggingTransport); err != nil {
 		return fmt.Errorf("server failed: %w", err)
@@ -2,9 +2,12 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"net/http"
+	"strings"
 	"testing"
+	"time"
 
 	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
 
@@ -18,11 +21,15 @@ type stubKapuaServer struct {
 	receivedHandler        http.Handler
 	receivedHTTPConfig     *mcpserver.HTTPConfig
 	listenCalled           bool
+	listenTLSConfig        *tls.Config
 	runTransportCalled     bool
 	runTransportErr        error
 	runTransportName       string
 	runTransportTransport  mcpsdk.Transport
 	runTransportContextNil bool
+	shutdownCalled         bool
+	shutdownDeadline       time.Time
+	shutdownErr            error
 }
 
 func (s *stubKapuaServer) Handler(cfg *mcpserver.HTTPConfig) http.Handler {
@@ -33,13 +40,24 @@ func (s *stubKapuaServer) Handler(cfg *mcpserver.HTTPConfig) http.Handler {
 	return s.handler
 }
 
-func (s *stubKapuaServer) ListenAndServe(addr string, handler http.Handler) error {
+func (s *stubKapuaServer) ListenAndServe(_ context.Context, addr string, handler http.Handler) error {
 	s.listenCalled = true
 	s.listenAddr = addr
 	s.receivedHandler = handler
 	return s.listenErr
 }
 
+func (s *stubKapuaServer) ListenAndServeTLS(ctx context.Context, addr string, handler http.Handler, tlsCfg *tls.Config) error {
+	s.listenTLSConfig = tlsCfg
+	return s.ListenAndServe(ctx, addr, handler)
+}
+
+func (s *stubKapuaServer) Shutdown(ctx context.Context) error {
+	s.shutdownCalled = true
+	s.shutdownDeadline, _ = ctx.Deadline()
+	return s.shutdownErr
+}
+
 func (s *stubKapuaServer) RunTransport(ctx context.Context, name string, transport mcpsdk.Transport) error {
 	s.runTransportCalled = true
 	s.runTransportName = name
@@ -52,7 +70,7 @@ func TestRunHTTPServer(t *testing.T) {
 	stub := &stubKapuaServer{}
 	httpCfg := &mcpserver.HTTPConfig{Host: "localhost", Port: 0}
 
-	if err := runHTTPServer(stub, httpCfg); err != nil {
+	if err := runHTTPServer(context.Background(), stub, httpCfg); err != nil {
 		t.Fatalf("runHTTPServer returned error: %v", err)
 	}
 	if !stub.listenCalled {
@@ -73,7 +91,7 @@ func TestRunHTTPServer(t *testing.T) {
 }
 
 func TestRunHTTPServerNilConfig(t *testing.T) {
-	if err := runHTTPServer(&stubKapuaServer{}, nil); err == nil || err.Error() != "http transport requires configuration" {
+	if err := runHTTPServer(context.Background(), &stubKapuaServer{}, nil); err == nil || err.Error() != "http transport requires configuration" {
 		t.Fatalf("expected configuration error, got %v", err)
 	}
 }
@@ -81,7 +99,7 @@ func TestRunHTTPServerNilConfig(t *testing.T) {
 func TestRunHTTPServerListenError(t *testing.T) {
 	stub := &stubKapuaServer{listenErr: errors.New("listen failure")}
 
-	err := runHTTPServer(stub, &mcpserver.HTTPConfig{Host: "localhost", Port: 0})
+	err := runHTTPServer(context.Background(), stub, &mcpserver.HTTPConfig{Host: "localhost", Port: 0})
 	if err == nil || !errors.Is(err, stub.listenErr) {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -90,7 +108,7 @@ func TestRunHTTPServerListenError(t *testing.T) {
 func TestRunStdioServer(t *testing.T) {
 	stub := &stubKapuaServer{}
 
-	if err := runStdioServer(stub); err != nil {
+	if err := runStdioServer(context.Background(), stub); err != nil {
 		t.Fatalf("runStdioServer returned error: %v", err)
 	}
 	if !stub.runTransportCalled {
@@ -116,7 +134,7 @@ func TestRunStdioServer(t *testing.T) {
 func TestRunStdioServerTransportError(t *testing.T) {
 	stub := &stubKapuaServer{runTransportErr: errors.New("transport failure")}
 
-	err := runStdioServer(stub)
+	err := runStdioServer(context.Background(), stub)
 	if err == nil || !errors.Is(err, stub.runTransportErr) {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -124,3 +142,61 @@ func TestRunStdioServerTransportError(t *testing.T) {
 		t.Fatalf("expected RunTransport to be called for stdio transport")
 	}
 }
+
+func TestRunHTTPServerInvalidTLSConfig(t *testing.T) {
+	stub := &stubKapuaServer{}
+	httpCfg := &mcpserver.HTTPConfig{Host: "localhost", Port: 0, TLSCertFile: "tls.crt"}
+
+	err := runHTTPServer(context.Background(), stub, httpCfg)
+	if err == nil || !strings.Contains(err.Error(), "invalid TLS configuration") {
+		t.Fatalf("expected TLS configuration error, got %v", err)
+	}
+	if stub.listenCalled {
+		t.Fatalf("expected server not to start with invalid TLS configuration")
+	}
+}
+
+func TestShutdownServerAppliesTimeout(t *testing.T) {
+	stub := &stubKapuaServer{shutdownErr: errors.New("logout failure")}
+
+	before := time.Now()
+	err := shutdownServer(stub, 5*time.Second)
+	if !errors.Is(err, stub.shutdownErr) {
+		t.Fatalf("expected shutdown error to be returned, got %v", err)
+	}
+	if !stub.shutdownCalled {
+		t.Fatal("expected Shutdown to be called")
+	}
+	if remaining := stub.shutdownDeadline.Sub(before); remaining < 4*time.Second || remaining > 6*time.Second {
+		t.Fatalf("expected a ~5s shutdown deadline, got %v", remaining)
+	}
+}
+
+func TestResolveShutdownTimeout(t *testing.T) {
+	cases := []struct {
+		env     string
+		flag    time.Duration
+		want    time.Duration
+		wantErr bool
+	}{
+		{env: "", want: defaultShutdownTimeout},
+		{env: "45", want: 45 * time.Second},
+		{env: "1m30s", want: 90 * time.Second},
+		{env: "45", flag: 10 * time.Second, want: 10 * time.Second},
+		{env: "-5s", wantErr: true},
+		{env: "soon", wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Setenv("MCP_SHUTDOWN_TIMEOUT", tc.env)
+		got, err := resolveShutdownTimeout(tc.flag)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("env %q: expected error", tc.env)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("env %q flag %v: got %v, %v; want %v", tc.env, tc.flag, got, err, tc.want)
+		}
+	}
+}
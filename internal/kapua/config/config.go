@@ -3,9 +3,11 @@ package config
 import (
 	"bufio"
 	"fmt"
+	"math"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Config holds all configuration for the Kapua MCP server
@@ -19,16 +21,59 @@ type KapuaConfig struct {
 	Username    string `json:"username"`
 	Password    string `json:"password"`
 	APIKey      string `json:"api_key"`     // API key for KAPUA_AUTH_METHOD=apikey
-	AuthMethod  string `json:"auth_method"` // "password" (default) or "apikey"
+	AuthMethod  string `json:"auth_method"` // "password" (default), "apikey" or "jwt"
+	JWT         string `json:"jwt"`         // Inline token for KAPUA_AUTH_METHOD=jwt
+	JWTFile     string `json:"jwt_file"`    // Token file for KAPUA_AUTH_METHOD=jwt, re-read when it rotates
 	Timeout     int    `json:"timeout"`     // in seconds
+
+	// Retries of idempotent requests (GET, PUT, DELETE) after transient failures
+	MaxRetries     int           `json:"max_retries"`      // Retries after the first attempt; 0 disables retrying
+	RetryBaseDelay time.Duration `json:"retry_base_delay"` // Backoff before the first retry, doubled on each one
+	RetryMaxDelay  time.Duration `json:"retry_max_delay"`  // Upper bound for a single backoff
+
+	// Client-side limits shared by every request to Kapua
+	RateLimit   float64 `json:"rate_limit"`    // Requests per second; 0 means unlimited
+	RateBurst   int     `json:"rate_burst"`    // Requests allowed at once above RateLimit
+	MaxInFlight int     `json:"max_in_flight"` // Concurrent requests; 0 means unlimited
+
+	// Circuit breaker that fails fast while Kapua is unreachable
+	BreakerThreshold int           `json:"breaker_threshold"` // Consecutive failed calls that open the circuit; 0 disables it
+	BreakerCooldown  time.Duration `json:"breaker_cooldown"`  // How long the circuit stays open before a probe
+
+	// Response cache for read-mostly Kapua data; off by default, a TTL of 0 disables caching of that data
+	CacheTTLDevices        time.Duration `json:"cache_ttl_devices"`        // Device lists and lookups
+	CacheTTLInventory      time.Duration `json:"cache_ttl_inventory"`      // Device inventories
+	CacheTTLSnapshots      time.Duration `json:"cache_ttl_snapshots"`      // Snapshot lists and contents
+	CacheTTLConfigurations time.Duration `json:"cache_ttl_configurations"` // Device configurations
+
+	FleetPollInterval        time.Duration `json:"fleet_poll_interval"`        // Refresh interval of the background fleet index; 0 disables it
+	SubscriptionPollInterval time.Duration `json:"subscription_poll_interval"` // Device poll interval for resource subscriptions; 0 disables them
+
+	PromptsDir string `json:"prompts_dir"` // Directory of custom MCP prompt templates (*.json)
+	ReadOnly   bool   `json:"read_only"`   // Register only the tools that do not change devices
+
+	// Multi-factor authentication for KAPUA_AUTH_METHOD=password
+	MFACode      string `json:"mfa_code"`       // One-time MFA code used on the next login
+	TOTPSecret   string `json:"totp_secret"`    // Base32 TOTP secret used to generate MFA codes
+	MFAElicit    bool   `json:"mfa_elicit"`     // Ask the MCP client for the MFA code at startup
+	TrustKeyFile string `json:"trust_key_file"` // File where the Kapua trust key is persisted
 }
 
 // Load loads configuration from environment variables and .env file
 func Load() (*Config, error) {
 	config := &Config{
 		Kapua: KapuaConfig{
-			Timeout:    30, // default timeout
-			AuthMethod: "password",
+			Timeout:          30, // default timeout
+			AuthMethod:       "password",
+			MaxRetries:       3,
+			RetryBaseDelay:   200 * time.Millisecond,
+			RetryMaxDelay:    10 * time.Second,
+			RateBurst:        10,
+			MaxInFlight:      16,
+			BreakerThreshold: 5,
+			BreakerCooldown:  30 * time.Second,
+
+			SubscriptionPollInterval: 30 * time.Second,
 		},
 	}
 
@@ -51,6 +96,13 @@ func Load() (*Config, error) {
 		if config.Kapua.APIKey == "" {
 			return nil, fmt.Errorf("KAPUA_API_KEY is required when KAPUA_AUTH_METHOD=apikey")
 		}
+	case "jwt":
+		if config.Kapua.JWT == "" && config.Kapua.JWTFile == "" {
+			return nil, fmt.Errorf("KAPUA_JWT or KAPUA_JWT_FILE is required when KAPUA_AUTH_METHOD=jwt")
+		}
+		if config.Kapua.JWT != "" && config.Kapua.JWTFile != "" {
+			return nil, fmt.Errorf("KAPUA_JWT and KAPUA_JWT_FILE are mutually exclusive")
+		}
 	case "password", "":
 		config.Kapua.AuthMethod = "password"
 		if config.Kapua.Username == "" {
@@ -59,13 +111,65 @@ func Load() (*Config, error) {
 		if config.Kapua.Password == "" {
 			return nil, fmt.Errorf("KAPUA_PASSWORD is required")
 		}
+		if config.Kapua.MFACode != "" && config.Kapua.TOTPSecret != "" {
+			return nil, fmt.Errorf("KAPUA_MFA_CODE and KAPUA_TOTP_SECRET are mutually exclusive")
+		}
 	default:
-		return nil, fmt.Errorf("unsupported KAPUA_AUTH_METHOD %q: must be \"password\" or \"apikey\"", config.Kapua.AuthMethod)
+		return nil, fmt.Errorf("unsupported KAPUA_AUTH_METHOD %q: must be \"password\", \"apikey\" or \"jwt\"", config.Kapua.AuthMethod)
+	}
+
+	if config.Kapua.RetryMaxDelay < config.Kapua.RetryBaseDelay {
+		return nil, fmt.Errorf("KAPUA_RETRY_MAX_DELAY must not be shorter than KAPUA_RETRY_BASE_DELAY")
 	}
 
 	return config, nil
 }
 
+// parseRetries parses KAPUA_MAX_RETRIES; zero disables retries.
+func parseRetries(value string) (int, error) {
+	v, err := strconv.Atoi(value)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid KAPUA_MAX_RETRIES %q: must be a non-negative integer", value)
+	}
+	return v, nil
+}
+
+// parseRateLimit parses KAPUA_RATE_LIMIT in requests per second; zero disables it.
+func parseRateLimit(value string) (float64, error) {
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil || v < 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+		return 0, fmt.Errorf("invalid KAPUA_RATE_LIMIT %q: must be a non-negative number of requests per second", value)
+	}
+	return v, nil
+}
+
+// parseCount parses a non-negative integer setting, rejecting values below min.
+func parseCount(key, value string, min int) (int, error) {
+	v, err := strconv.Atoi(value)
+	if err != nil || v < min {
+		return 0, fmt.Errorf("invalid %s %q: must be an integer of at least %d", key, value, min)
+	}
+	return v, nil
+}
+
+// parseDelay parses a positive duration such as "250ms" or "2s".
+func parseDelay(key, value string) (time.Duration, error) {
+	v, err := time.ParseDuration(value)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be a positive duration such as 250ms or 2s", key, value)
+	}
+	return v, nil
+}
+
+// parseOptionalDuration parses a duration such as "30s" where 0 disables the feature.
+func parseOptionalDuration(key, value string) (time.Duration, error) {
+	v, err := time.ParseDuration(value)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be a duration such as 30s or 5m, or 0 to disable", key, value)
+	}
+	return v, nil
+}
+
 // parseTimeout parses and validates a timeout string value; it must be a positive integer.
 func parseTimeout(value string) (int, error) {
 	v, err := strconv.Atoi(value)
@@ -78,6 +182,15 @@ func parseTimeout(value string) (int, error) {
 	return v, nil
 }
 
+// parseBool parses a boolean setting such as "true", "false", "1" or "0".
+func parseBool(key, value string) (bool, error) {
+	v, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s %q: must be true or false", key, value)
+	}
+	return v, nil
+}
+
 // loadFromEnvFile loads configuration from a .env style file
 func loadFromEnvFile(config *Config, filename string) error {
 	file, err := os.Open(filename)
@@ -104,53 +217,186 @@ func loadFromEnvFile(config *Config, filename string) error {
 		key := strings.TrimSpace(parts[0])
 		value := strings.TrimSpace(parts[1])
 
-		// Set configuration values
-		switch key {
-		case "KAPUA_API_ENDPOINT":
-			config.Kapua.APIEndpoint = value
-		case "KAPUA_USER":
-			config.Kapua.Username = value
-		case "KAPUA_PASSWORD":
-			config.Kapua.Password = value
-		case "KAPUA_API_KEY":
-			config.Kapua.APIKey = value
-		case "KAPUA_AUTH_METHOD":
-			config.Kapua.AuthMethod = value
-		case "KAPUA_TIMEOUT":
-			v, err := parseTimeout(value)
-			if err != nil {
-				return err
-			}
-			config.Kapua.Timeout = v
+		if err := setValue(config, key, value); err != nil {
+			return err
 		}
 	}
 
 	return scanner.Err()
 }
 
+// envKeys lists the variables read by loadFromEnv, in the order they are applied.
+var envKeys = []string{
+	"KAPUA_API_ENDPOINT",
+	"KAPUA_USER",
+	"KAPUA_PASSWORD",
+	"KAPUA_API_KEY",
+	"KAPUA_AUTH_METHOD",
+	"KAPUA_JWT",
+	"KAPUA_JWT_FILE",
+	"KAPUA_TIMEOUT",
+	"KAPUA_MAX_RETRIES",
+	"KAPUA_RETRY_BASE_DELAY",
+	"KAPUA_RETRY_MAX_DELAY",
+	"KAPUA_RATE_LIMIT",
+	"KAPUA_RATE_BURST",
+	"KAPUA_MAX_IN_FLIGHT",
+	"KAPUA_BREAKER_THRESHOLD",
+	"KAPUA_BREAKER_COOLDOWN",
+	"KAPUA_CACHE_TTL_DEVICES",
+	"KAPUA_CACHE_TTL_INVENTORY",
+	"KAPUA_CACHE_TTL_SNAPSHOTS",
+	"KAPUA_CACHE_TTL_CONFIGURATIONS",
+	"KAPUA_FLEET_POLL_INTERVAL",
+	"KAPUA_SUBSCRIPTION_POLL_INTERVAL",
+	"KAPUA_PROMPTS_DIR",
+	"KAPUA_READ_ONLY",
+	"KAPUA_MFA_CODE",
+	"KAPUA_TOTP_SECRET",
+	"KAPUA_MFA_ELICIT",
+	"KAPUA_TRUST_KEY_FILE",
+}
+
 // loadFromEnv loads configuration from environment variables
 func loadFromEnv(config *Config) error {
-	if endpoint := os.Getenv("KAPUA_API_ENDPOINT"); endpoint != "" {
-		config.Kapua.APIEndpoint = endpoint
-	}
-	if username := os.Getenv("KAPUA_USER"); username != "" {
-		config.Kapua.Username = username
-	}
-	if password := os.Getenv("KAPUA_PASSWORD"); password != "" {
-		config.Kapua.Password = password
-	}
-	if apiKey := os.Getenv("KAPUA_API_KEY"); apiKey != "" {
-		config.Kapua.APIKey = apiKey
-	}
-	if authMethod := os.Getenv("KAPUA_AUTH_METHOD"); authMethod != "" {
-		config.Kapua.AuthMethod = authMethod
+	for _, key := range envKeys {
+		if value := os.Getenv(key); value != "" {
+			if err := setValue(config, key, value); err != nil {
+				return err
+			}
+		}
 	}
-	if timeout := os.Getenv("KAPUA_TIMEOUT"); timeout != "" {
-		v, err := parseTimeout(timeout)
+	return nil
+}
+
+// setValue applies a single KEY=VALUE setting shared by the .env file and the environment.
+// Unknown keys are ignored.
+func setValue(config *Config, key, value string) error {
+	switch key {
+	case "KAPUA_API_ENDPOINT":
+		config.Kapua.APIEndpoint = value
+	case "KAPUA_USER":
+		config.Kapua.Username = value
+	case "KAPUA_PASSWORD":
+		config.Kapua.Password = value
+	case "KAPUA_API_KEY":
+		config.Kapua.APIKey = value
+	case "KAPUA_AUTH_METHOD":
+		config.Kapua.AuthMethod = value
+	case "KAPUA_JWT":
+		config.Kapua.JWT = value
+	case "KAPUA_JWT_FILE":
+		config.Kapua.JWTFile = value
+	case "KAPUA_TIMEOUT":
+		v, err := parseTimeout(value)
 		if err != nil {
 			return err
 		}
 		config.Kapua.Timeout = v
+	case "KAPUA_MAX_RETRIES":
+		v, err := parseRetries(value)
+		if err != nil {
+			return err
+		}
+		config.Kapua.MaxRetries = v
+	case "KAPUA_RETRY_BASE_DELAY":
+		v, err := parseDelay(key, value)
+		if err != nil {
+			return err
+		}
+		config.Kapua.RetryBaseDelay = v
+	case "KAPUA_RETRY_MAX_DELAY":
+		v, err := parseDelay(key, value)
+		if err != nil {
+			return err
+		}
+		config.Kapua.RetryMaxDelay = v
+	case "KAPUA_RATE_LIMIT":
+		v, err := parseRateLimit(value)
+		if err != nil {
+			return err
+		}
+		config.Kapua.RateLimit = v
+	case "KAPUA_RATE_BURST":
+		v, err := parseCount(key, value, 1)
+		if err != nil {
+			return err
+		}
+		config.Kapua.RateBurst = v
+	case "KAPUA_MAX_IN_FLIGHT":
+		v, err := parseCount(key, value, 0)
+		if err != nil {
+			return err
+		}
+		config.Kapua.MaxInFlight = v
+	case "KAPUA_BREAKER_THRESHOLD":
+		v, err := parseCount(key, value, 0)
+		if err != nil {
+			return err
+		}
+		config.Kapua.BreakerThreshold = v
+	case "KAPUA_BREAKER_COOLDOWN":
+		v, err := parseDelay(key, value)
+		if err != nil {
+			return err
+		}
+		config.Kapua.BreakerCooldown = v
+	case "KAPUA_CACHE_TTL_DEVICES":
+		v, err := parseOptionalDuration(key, value)
+		if err != nil {
+			return err
+		}
+		config.Kapua.CacheTTLDevices = v
+	case "KAPUA_CACHE_TTL_INVENTORY":
+		v, err := parseOptionalDuration(key, value)
+		if err != nil {
+			return err
+		}
+		config.Kapua.CacheTTLInventory = v
+	case "KAPUA_CACHE_TTL_SNAPSHOTS":
+		v, err := parseOptionalDuration(key, value)
+		if err != nil {
+			return err
+		}
+		config.Kapua.CacheTTLSnapshots = v
+	case "KAPUA_CACHE_TTL_CONFIGURATIONS":
+		v, err := parseOptionalDuration(key, value)
+		if err != nil {
+			return err
+		}
+		config.Kapua.CacheTTLConfigurations = v
+	case "KAPUA_FLEET_POLL_INTERVAL":
+		v, err := parseOptionalDuration(key, value)
+		if err != nil {
+			return err
+		}
+		config.Kapua.FleetPollInterval = v
+	case "KAPUA_SUBSCRIPTION_POLL_INTERVAL":
+		v, err := parseOptionalDuration(key, value)
+		if err != nil {
+			return err
+		}
+		config.Kapua.SubscriptionPollInterval = v
+	case "KAPUA_PROMPTS_DIR":
+		config.Kapua.PromptsDir = value
+	case "KAPUA_READ_ONLY":
+		v, err := parseBool(key, value)
+		if err != nil {
+			return err
+		}
+		config.Kapua.ReadOnly = v
+	case "KAPUA_MFA_CODE":
+		config.Kapua.MFACode = value
+	case "KAPUA_TOTP_SECRET":
+		config.Kapua.TOTPSecret = value
+	case "KAPUA_MFA_ELICIT":
+		v, err := parseBool(key, value)
+		if err != nil {
+			return err
+		}
+		config.Kapua.MFAElicit = v
+	case "KAPUA_TRUST_KEY_FILE":
+		config.Kapua.TrustKeyFile = value
 	}
 	return nil
 }
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoadFromEnvFile(t *testing.T) {
@@ -248,3 +249,293 @@ func TestLoadAPIKeyFromEnvFile(t *testing.T) {
 		t.Errorf("expected APIKey file-api-key, got %q", cfg.Kapua.APIKey)
 	}
 }
+
+func TestLoadMFASettings(t *testing.T) {
+	t.Setenv("KAPUA_API_ENDPOINT", "http://example.com/api")
+	t.Setenv("KAPUA_USER", "user")
+	t.Setenv("KAPUA_PASSWORD", "pass")
+	t.Setenv("KAPUA_TOTP_SECRET", "JBSWY3DPEHPK3PXP")
+	t.Setenv("KAPUA_MFA_ELICIT", "true")
+	t.Setenv("KAPUA_TRUST_KEY_FILE", "/var/lib/kapua-mcp/trust-key")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if cfg.Kapua.TOTPSecret != "JBSWY3DPEHPK3PXP" || !cfg.Kapua.MFAElicit || cfg.Kapua.TrustKeyFile != "/var/lib/kapua-mcp/trust-key" {
+		t.Errorf("unexpected MFA settings: %+v", cfg.Kapua)
+	}
+}
+
+func TestLoadMFACodeAndTOTPSecretConflict(t *testing.T) {
+	t.Setenv("KAPUA_API_ENDPOINT", "http://example.com/api")
+	t.Setenv("KAPUA_USER", "user")
+	t.Setenv("KAPUA_PASSWORD", "pass")
+	t.Setenv("KAPUA_MFA_CODE", "123456")
+	t.Setenv("KAPUA_TOTP_SECRET", "JBSWY3DPEHPK3PXP")
+
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "mutually exclusive") {
+		t.Fatalf("expected mutually exclusive error, got %v", err)
+	}
+}
+
+func TestLoadInvalidMFAElicit(t *testing.T) {
+	t.Setenv("KAPUA_API_ENDPOINT", "http://example.com/api")
+	t.Setenv("KAPUA_USER", "user")
+	t.Setenv("KAPUA_PASSWORD", "pass")
+	t.Setenv("KAPUA_MFA_ELICIT", "maybe")
+
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "invalid KAPUA_MFA_ELICIT") {
+		t.Fatalf("expected invalid KAPUA_MFA_ELICIT error, got %v", err)
+	}
+}
+
+func TestLoadJWTAuthMethod(t *testing.T) {
+	t.Setenv("KAPUA_API_ENDPOINT", "http://example.com/api")
+	t.Setenv("KAPUA_AUTH_METHOD", "jwt")
+	t.Setenv("KAPUA_JWT_FILE", "/var/run/secrets/tokens/kapua")
+	t.Setenv("KAPUA_USER", "")
+	t.Setenv("KAPUA_PASSWORD", "")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if cfg.Kapua.JWTFile != "/var/run/secrets/tokens/kapua" {
+		t.Errorf("unexpected JWTFile %q", cfg.Kapua.JWTFile)
+	}
+}
+
+func TestLoadJWTAuthMethodValidation(t *testing.T) {
+	t.Setenv("KAPUA_API_ENDPOINT", "http://example.com/api")
+	t.Setenv("KAPUA_AUTH_METHOD", "jwt")
+
+	t.Setenv("KAPUA_JWT", "")
+	t.Setenv("KAPUA_JWT_FILE", "")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "KAPUA_JWT or KAPUA_JWT_FILE is required") {
+		t.Fatalf("expected missing JWT error, got %v", err)
+	}
+
+	t.Setenv("KAPUA_JWT", "inline")
+	t.Setenv("KAPUA_JWT_FILE", "/tmp/token")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "mutually exclusive") {
+		t.Fatalf("expected mutually exclusive error, got %v", err)
+	}
+}
+
+func TestLoadRetrySettings(t *testing.T) {
+	t.Setenv("KAPUA_API_ENDPOINT", "http://example.com/api")
+	t.Setenv("KAPUA_USER", "user")
+	t.Setenv("KAPUA_PASSWORD", "pass")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if cfg.Kapua.MaxRetries != 3 || cfg.Kapua.RetryBaseDelay != 200*time.Millisecond || cfg.Kapua.RetryMaxDelay != 10*time.Second {
+		t.Errorf("unexpected retry defaults: %d %v %v", cfg.Kapua.MaxRetries, cfg.Kapua.RetryBaseDelay, cfg.Kapua.RetryMaxDelay)
+	}
+
+	t.Setenv("KAPUA_MAX_RETRIES", "0")
+	t.Setenv("KAPUA_RETRY_BASE_DELAY", "500ms")
+	t.Setenv("KAPUA_RETRY_MAX_DELAY", "2s")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if cfg.Kapua.MaxRetries != 0 || cfg.Kapua.RetryBaseDelay != 500*time.Millisecond || cfg.Kapua.RetryMaxDelay != 2*time.Second {
+		t.Errorf("unexpected retry settings: %d %v %v", cfg.Kapua.MaxRetries, cfg.Kapua.RetryBaseDelay, cfg.Kapua.RetryMaxDelay)
+	}
+}
+
+func TestLoadRetrySettingsValidation(t *testing.T) {
+	cases := map[string]map[string]string{
+		"negative retries": {"KAPUA_MAX_RETRIES": "-1"},
+		"invalid retries":  {"KAPUA_MAX_RETRIES": "three"},
+		"invalid delay":    {"KAPUA_RETRY_BASE_DELAY": "200"},
+		"max below base":   {"KAPUA_RETRY_BASE_DELAY": "5s", "KAPUA_RETRY_MAX_DELAY": "1s"},
+	}
+	for name, env := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("KAPUA_API_ENDPOINT", "http://example.com/api")
+			t.Setenv("KAPUA_USER", "user")
+			t.Setenv("KAPUA_PASSWORD", "pass")
+			for key, value := range env {
+				t.Setenv(key, value)
+			}
+			if _, err := Load(); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestLoadRequestLimits(t *testing.T) {
+	t.Setenv("KAPUA_API_ENDPOINT", "http://example.com/api")
+	t.Setenv("KAPUA_USER", "user")
+	t.Setenv("KAPUA_PASSWORD", "pass")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if cfg.Kapua.RateLimit != 0 || cfg.Kapua.RateBurst != 10 || cfg.Kapua.MaxInFlight != 16 {
+		t.Errorf("unexpected limit defaults: %v %d %d", cfg.Kapua.RateLimit, cfg.Kapua.RateBurst, cfg.Kapua.MaxInFlight)
+	}
+
+	t.Setenv("KAPUA_RATE_LIMIT", "2.5")
+	t.Setenv("KAPUA_RATE_BURST", "5")
+	t.Setenv("KAPUA_MAX_IN_FLIGHT", "0")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if cfg.Kapua.RateLimit != 2.5 || cfg.Kapua.RateBurst != 5 || cfg.Kapua.MaxInFlight != 0 {
+		t.Errorf("unexpected limits: %v %d %d", cfg.Kapua.RateLimit, cfg.Kapua.RateBurst, cfg.Kapua.MaxInFlight)
+	}
+
+	for key, value := range map[string]string{"KAPUA_RATE_LIMIT": "-1", "KAPUA_RATE_BURST": "0", "KAPUA_MAX_IN_FLIGHT": "many"} {
+		t.Run(key, func(t *testing.T) {
+			t.Setenv(key, value)
+			if _, err := Load(); err == nil || !strings.Contains(err.Error(), key) {
+				t.Fatalf("expected %s error, got %v", key, err)
+			}
+		})
+	}
+}
+
+func TestLoadBreakerSettings(t *testing.T) {
+	t.Setenv("KAPUA_API_ENDPOINT", "http://example.com/api")
+	t.Setenv("KAPUA_USER", "user")
+	t.Setenv("KAPUA_PASSWORD", "pass")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if cfg.Kapua.BreakerThreshold != 5 || cfg.Kapua.BreakerCooldown != 30*time.Second {
+		t.Errorf("unexpected breaker defaults: %d %v", cfg.Kapua.BreakerThreshold, cfg.Kapua.BreakerCooldown)
+	}
+
+	t.Setenv("KAPUA_BREAKER_THRESHOLD", "0")
+	t.Setenv("KAPUA_BREAKER_COOLDOWN", "1m")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if cfg.Kapua.BreakerThreshold != 0 || cfg.Kapua.BreakerCooldown != time.Minute {
+		t.Errorf("unexpected breaker settings: %d %v", cfg.Kapua.BreakerThreshold, cfg.Kapua.BreakerCooldown)
+	}
+
+	for key, value := range map[string]string{"KAPUA_BREAKER_THRESHOLD": "-1", "KAPUA_BREAKER_COOLDOWN": "soon"} {
+		t.Run(key, func(t *testing.T) {
+			t.Setenv(key, value)
+			if _, err := Load(); err == nil || !strings.Contains(err.Error(), key) {
+				t.Fatalf("expected %s error, got %v", key, err)
+			}
+		})
+	}
+}
+
+func TestLoadCacheTTLs(t *testing.T) {
+	t.Setenv("KAPUA_API_ENDPOINT", "http://example.com/api")
+	t.Setenv("KAPUA_USER", "user")
+	t.Setenv("KAPUA_PASSWORD", "pass")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if cfg.Kapua.CacheTTLDevices != 0 || cfg.Kapua.CacheTTLInventory != 0 ||
+		cfg.Kapua.CacheTTLSnapshots != 0 || cfg.Kapua.CacheTTLConfigurations != 0 {
+		t.Errorf("expected caching to be off by default: %+v", cfg.Kapua)
+	}
+
+	t.Setenv("KAPUA_CACHE_TTL_DEVICES", "0")
+	t.Setenv("KAPUA_CACHE_TTL_INVENTORY", "5m")
+	t.Setenv("KAPUA_CACHE_TTL_SNAPSHOTS", "30s")
+	t.Setenv("KAPUA_CACHE_TTL_CONFIGURATIONS", "0s")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if cfg.Kapua.CacheTTLDevices != 0 || cfg.Kapua.CacheTTLInventory != 5*time.Minute ||
+		cfg.Kapua.CacheTTLSnapshots != 30*time.Second || cfg.Kapua.CacheTTLConfigurations != 0 {
+		t.Errorf("unexpected cache TTLs: %+v", cfg.Kapua)
+	}
+
+	for _, value := range []string{"-1s", "forever"} {
+		t.Run(value, func(t *testing.T) {
+			t.Setenv("KAPUA_CACHE_TTL_DEVICES", value)
+			if _, err := Load(); err == nil || !strings.Contains(err.Error(), "KAPUA_CACHE_TTL_DEVICES") {
+				t.Fatalf("expected KAPUA_CACHE_TTL_DEVICES error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadPollIntervals(t *testing.T) {
+	t.Setenv("KAPUA_API_ENDPOINT", "http://example.com/api")
+	t.Setenv("KAPUA_USER", "user")
+	t.Setenv("KAPUA_PASSWORD", "pass")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if cfg.Kapua.FleetPollInterval != 0 || cfg.Kapua.SubscriptionPollInterval != 30*time.Second {
+		t.Errorf("unexpected poll interval defaults: fleet %v, subscriptions %v", cfg.Kapua.FleetPollInterval, cfg.Kapua.SubscriptionPollInterval)
+	}
+
+	t.Setenv("KAPUA_FLEET_POLL_INTERVAL", "2m")
+	t.Setenv("KAPUA_SUBSCRIPTION_POLL_INTERVAL", "0")
+	if cfg, err = Load(); err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if cfg.Kapua.FleetPollInterval != 2*time.Minute || cfg.Kapua.SubscriptionPollInterval != 0 {
+		t.Errorf("unexpected poll intervals: fleet %v, subscriptions %v", cfg.Kapua.FleetPollInterval, cfg.Kapua.SubscriptionPollInterval)
+	}
+
+	t.Setenv("KAPUA_FLEET_POLL_INTERVAL", "soon")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "KAPUA_FLEET_POLL_INTERVAL") {
+		t.Fatalf("expected KAPUA_FLEET_POLL_INTERVAL error, got %v", err)
+	}
+}
+
+func TestLoadPromptsDir(t *testing.T) {
+	t.Setenv("KAPUA_API_ENDPOINT", "http://example.com/api")
+	t.Setenv("KAPUA_USER", "user")
+	t.Setenv("KAPUA_PASSWORD", "pass")
+	t.Setenv("KAPUA_PROMPTS_DIR", "/etc/kapua-mcp/prompts")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if cfg.Kapua.PromptsDir != "/etc/kapua-mcp/prompts" {
+		t.Errorf("unexpected prompts directory %q", cfg.Kapua.PromptsDir)
+	}
+}
+
+func TestLoadReadOnly(t *testing.T) {
+	t.Setenv("KAPUA_API_ENDPOINT", "http://example.com/api")
+	t.Setenv("KAPUA_USER", "user")
+	t.Setenv("KAPUA_PASSWORD", "pass")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if cfg.Kapua.ReadOnly {
+		t.Fatal("expected read-only mode to be off by default")
+	}
+
+	t.Setenv("KAPUA_READ_ONLY", "true")
+	if cfg, err = Load(); err != nil || !cfg.Kapua.ReadOnly {
+		t.Fatalf("expected read-only mode, got %v: %v", cfg, err)
+	}
+	t.Setenv("KAPUA_READ_ONLY", "sometimes")
+	if _, err := Load(); err == nil {
+		t.Fatal("expected an error for an invalid KAPUA_READ_ONLY")
+	}
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	completionCacheTTL     = 30 * time.Second
+	maxCompletionValues    = 100 // The most values MCP allows in one completion
+	completionChannelLimit = 1000
+)
+
+// completionCache keeps the candidates of recent completions, so that completing
+// while the user types does not query Kapua on every keystroke.
+type completionCache struct {
+	mu      sync.Mutex
+	entries map[string]completionEntry
+}
+
+type completionEntry struct {
+	values  []string
+	expires time.Time
+}
+
+// get returns the cached values for key, calling load when they are missing or
+// expired. Failed loads are not cached.
+func (c *completionCache) get(key string, load func() ([]string, error)) ([]string, error) {
+	now := timeNow()
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.values, nil
+	}
+
+	values, err := load()
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = map[string]completionEntry{}
+	}
+	for cached, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, cached)
+		}
+	}
+	c.entries[key] = completionEntry{values: values, expires: now.Add(completionCacheTTL)}
+	return values, nil
+}
+
+// Complete answers completion/complete for prompt arguments and resource template
+// parameters. Candidates are chosen by argument name, so every prompt or template
+// with a clientId, deviceId, channel, snapshotId or componentId argument gets them.
+// Snapshot and component IDs need the device among the other arguments.
+func (h *KapuaHandler) Complete(ctx context.Context, params *mcp.CompleteParams) (*mcp.CompleteResult, error) {
+	var args map[string]string
+	if params.Context != nil {
+		args = params.Context.Arguments
+	}
+
+	var candidates []string
+	var err error
+	switch params.Argument.Name {
+	case "clientId":
+		candidates, err = h.deviceCandidates(ctx, false)
+	case "deviceId":
+		candidates, err = h.deviceCandidates(ctx, true)
+	case "channel":
+		candidates, err = h.channelCandidates(ctx, args["clientId"])
+	case "snapshotId":
+		candidates, err = h.snapshotCandidates(ctx, args)
+	case "componentId":
+		candidates, err = h.componentCandidates(ctx, args)
+	}
+	if err != nil {
+		h.logger.WithContext(ctx).Warn("Completing %s failed: %v", params.Argument.Name, err)
+		return nil, fmt.Errorf("failed to complete %s: %w", params.Argument.Name, err)
+	}
+	return completionResult(candidates, params.Argument.Value), nil
+}
+
+// deviceCandidates returns the client IDs of all devices, plus their Kapua IDs
+// when withIDs is set, from the fleet index when there is one.
+func (h *KapuaHandler) deviceCandidates(ctx context.Context, withIDs bool) ([]string, error) {
+	if snapshot := h.fleetIndex.current(); snapshot != nil {
+		var candidates []string
+		for _, entry := range snapshot.devices {
+			candidates = append(candidates, entry.device.ClientID)
+			if withIDs {
+				candidates = append(candidates, string(entry.device.ID))
+			}
+		}
+		return candidates, nil
+	}
+
+	key := "clientIds"
+	if withIDs {
+		key = "deviceIds"
+	}
+	return h.completions.get(key, func() ([]string, error) {
+		devices, _, err := listAllDevices(ctx, h.client)
+		if err != nil {
+			return nil, err
+		}
+		var candidates []string
+		for _, device := range devices {
+			candidates = append(candidates, device.ClientID)
+			if withIDs {
+				candidates = append(candidates, string(device.ID))
+			}
+		}
+		return candidates, nil
+	})
+}
+
+// channelCandidates returns the data channel names, of clientID only when set.
+func (h *KapuaHandler) channelCandidates(ctx context.Context, clientID string) ([]string, error) {
+	return h.completions.get("channels/"+clientID, func() ([]string, error) {
+		result, err := h.client.ListDataChannels(ctx, clientID, completionChannelLimit)
+		if err != nil {
+			return nil, err
+		}
+		candidates := make([]string, 0, len(result.Items))
+		for _, channel := range result.Items {
+			candidates = append(candidates, channel.Name)
+		}
+		return candidates, nil
+	})
+}
+
+func (h *KapuaHandler) snapshotCandidates(ctx context.Context, args map[string]string) ([]string, error) {
+	deviceID, ok := h.completionDevice(ctx, args)
+	if !ok {
+		return nil, nil
+	}
+	return h.completions.get("snapshots/"+deviceID, func() ([]string, error) {
+		snapshots, err := h.client.ListDeviceSnapshots(ctx, deviceID)
+		if err != nil || snapshots == nil {
+			return nil, err
+		}
+		candidates := make([]string, 0, len(snapshots.SnapshotID))
+		for _, snapshot := range snapshots.SnapshotID {
+			candidates = append(candidates, snapshot.ID)
+		}
+		return candidates, nil
+	})
+}
+
+func (h *KapuaHandler) componentCandidates(ctx context.Context, args map[string]string) ([]string, error) {
+	deviceID, ok := h.completionDevice(ctx, args)
+	if !ok {
+		return nil, nil
+	}
+	return h.completions.get("components/"+deviceID, func() ([]string, error) {
+		configurations, err := h.client.ReadDeviceConfigurations(ctx, deviceID)
+		if err != nil || configurations == nil {
+			return nil, err
+		}
+		candidates := make([]string, 0, len(configurations.Configuration))
+		for _, component := range configurations.Configuration {
+			candidates = append(candidates, component.ID)
+		}
+		return candidates, nil
+	})
+}
+
+// completionDevice resolves the device named by the deviceId or clientId
+// completion argument. ok is false when neither is given yet or the device is
+// unknown, which leaves nothing to complete.
+func (h *KapuaHandler) completionDevice(ctx context.Context, args map[string]string) (string, bool) {
+	ref := DeviceRef{DeviceID: args["deviceId"]}
+	if ref.DeviceID == "" {
+		ref.ClientID = args["clientId"]
+	}
+	if ref.DeviceID == "" && ref.ClientID == "" {
+		return "", false
+	}
+	if err := h.resolveDevice(ctx, &ref); err != nil {
+		h.logger.WithContext(ctx).Debug("No completions for an unresolved device: %v", err)
+		return "", false
+	}
+	return ref.DeviceID, true
+}
+
+// completionResult returns the distinct candidates starting with prefix, ignoring
+// case, in order and capped at maxCompletionValues.
+func completionResult(candidates []string, prefix string) *mcp.CompleteResult {
+	prefix = strings.ToLower(prefix)
+	seen := map[string]bool{}
+	values := []string{}
+	for _, candidate := range candidates {
+		if candidate == "" || seen[candidate] || !strings.HasPrefix(strings.ToLower(candidate), prefix) {
+			continue
+		}
+		seen[candidate] = true
+		values = append(values, candidate)
+	}
+	sort.Strings(values)
+
+	result := &mcp.CompleteResult{Completion: mcp.CompletionResultDetails{Total: len(values)}}
+	if len(values) > maxCompletionValues {
+		values = values[:maxCompletionValues]
+		result.Completion.HasMore = true
+	}
+	result.Completion.Values = values
+	return result
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func complete(t *testing.T, handler *KapuaHandler, name, value string, args map[string]string) []string {
+	t.Helper()
+	result, err := handler.Complete(context.Background(), &mcp.CompleteParams{
+		Ref:      &mcp.CompleteReference{Type: "ref/prompt", Name: "test"},
+		Argument: mcp.CompleteParamsArgument{Name: name, Value: value},
+		Context:  &mcp.CompleteContext{Arguments: args},
+	})
+	if err != nil {
+		t.Fatalf("Complete(%s=%q) returned error: %v", name, value, err)
+	}
+	return result.Completion.Values
+}
+
+func TestCompleteArguments(t *testing.T) {
+	now := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	stubTimeNow(t, now)
+	requests := map[string]int{}
+	handler := newHandlerWithServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path+"?"+r.URL.RawQuery]++
+		switch r.URL.Path {
+		case "/v1/tenant/devices":
+			if r.URL.Query().Get("clientId") == "gateway-07" {
+				_, _ = w.Write([]byte(`{"items":[{"id":"AQ","clientId":"gateway-07"}]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"totalCount":3,"items":[{"id":"AQ","clientId":"gateway-07"},{"id":"Ag","clientId":"Gateway-08"},{"id":"Aw","clientId":"pump-1"}]}`))
+		case "/v1/tenant/data/channels":
+			_, _ = w.Write([]byte(`{"items":[{"name":"sensors/room"},{"name":"sensors/boiler"},{"name":"alerts"}]}`))
+		case "/v1/tenant/devices/AQ/snapshots":
+			_, _ = w.Write([]byte(`{"snapshotId":[{"id":"1700000000"},{"id":"1710000000"}]}`))
+		case "/v1/tenant/devices/AQ/configurations":
+			_, _ = w.Write([]byte(`{"configuration":[{"id":"org.eclipse.kura.clock.ClockService"},{"id":"org.eclipse.kura.web.Console"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	if got := complete(t, handler, "clientId", "gate", nil); !reflect.DeepEqual(got, []string{"Gateway-08", "gateway-07"}) {
+		t.Fatalf("unexpected clientId completions %v", got)
+	}
+	if got := complete(t, handler, "clientId", "p", nil); !reflect.DeepEqual(got, []string{"pump-1"}) {
+		t.Fatalf("unexpected clientId completions %v", got)
+	}
+	if got := complete(t, handler, "deviceId", "A", nil); !reflect.DeepEqual(got, []string{"AQ", "Ag", "Aw"}) {
+		t.Fatalf("unexpected deviceId completions %v", got)
+	}
+	if got := complete(t, handler, "channel", "sensors/", map[string]string{"clientId": "gateway-07"}); !reflect.DeepEqual(got, []string{"sensors/boiler", "sensors/room"}) {
+		t.Fatalf("unexpected channel completions %v", got)
+	}
+	if requests["/v1/tenant/data/channels?clientId=gateway-07&limit=1000"] != 1 {
+		t.Fatalf("expected channels of gateway-07 to be queried: %v", requests)
+	}
+	if got := complete(t, handler, "snapshotId", "17", map[string]string{"clientId": "gateway-07"}); !reflect.DeepEqual(got, []string{"1700000000", "1710000000"}) {
+		t.Fatalf("unexpected snapshotId completions %v", got)
+	}
+	if got := complete(t, handler, "componentId", "org.eclipse.kura.c", map[string]string{"deviceId": "AQ"}); !reflect.DeepEqual(got, []string{"org.eclipse.kura.clock.ClockService"}) {
+		t.Fatalf("unexpected componentId completions %v", got)
+	}
+	if got := complete(t, handler, "snapshotId", "", nil); len(got) != 0 {
+		t.Fatalf("expected no snapshot completions without a device, got %v", got)
+	}
+	if got := complete(t, handler, "tag", "", nil); len(got) != 0 {
+		t.Fatalf("expected no completions for an unknown argument, got %v", got)
+	}
+
+	// Completions are cached while the user types, then refreshed.
+	lists := requests["/v1/tenant/devices?askTotalCount=true&limit=200&offset=0"]
+	complete(t, handler, "clientId", "gateway-0", nil)
+	if requests["/v1/tenant/devices?askTotalCount=true&limit=200&offset=0"] != lists {
+		t.Fatalf("expected cached client IDs: %v", requests)
+	}
+	stubTimeNow(t, now.Add(completionCacheTTL))
+	complete(t, handler, "clientId", "gateway-0", nil)
+	if requests["/v1/tenant/devices?askTotalCount=true&limit=200&offset=0"] != lists+1 {
+		t.Fatalf("expected client IDs to be refreshed: %v", requests)
+	}
+}
+
+func TestCompleteFromFleetIndex(t *testing.T) {
+	handler, fleet := newIndexedHandler(t, timeNow())
+	if err := handler.fleetIndex.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh returned error: %v", err)
+	}
+	lists := fleet.count("/v1/tenant/devices")
+	if got := complete(t, handler, "clientId", "", nil); !reflect.DeepEqual(got, []string{"alpha", "bravo", "charlie"}) {
+		t.Fatalf("unexpected completions %v", got)
+	}
+	if fleet.count("/v1/tenant/devices") != lists {
+		t.Fatal("expected client IDs from the index")
+	}
+}
+
+func TestCompletionResultLimit(t *testing.T) {
+	var candidates []string
+	for i := range 150 {
+		candidates = append(candidates, fmt.Sprintf("gw-%03d", i))
+	}
+	result := completionResult(append(candidates, "gw-000", ""), "GW-")
+	if len(result.Completion.Values) != maxCompletionValues || result.Completion.Total != 150 || !result.Completion.HasMore {
+		t.Fatalf("unexpected result: %d values, total %d, hasMore %v", len(result.Completion.Values), result.Completion.Total, result.Completion.HasMore)
+	}
+	if result.Completion.Values[0] != "gw-000" {
+		t.Fatalf("expected sorted values, got %v", result.Completion.Values[:3])
+	}
+}
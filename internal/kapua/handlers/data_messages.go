@@ -7,6 +7,7 @@ import (
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 
+	"kapua-mcp-server/internal/kapua/models"
 	"kapua-mcp-server/internal/kapua/services"
 )
 
@@ -30,12 +31,12 @@ type GetDataMessageParams struct {
 }
 
 // HandleListDataMessages lists data messages registered in Kapua for the current scope.
-func (h *KapuaHandler) HandleListDataMessages(ctx context.Context, req *mcp.CallToolRequest, params *ListDataMessagesParams) (*mcp.CallToolResult, any, error) {
+func (h *KapuaHandler) HandleListDataMessages(ctx context.Context, req *mcp.CallToolRequest, params *ListDataMessagesParams) (*mcp.CallToolResult, *models.DataMessageListResult, error) {
 	if params == nil {
 		params = &ListDataMessagesParams{}
 	}
 
-	h.logger.Info("Listing data messages")
+	h.logger.WithContext(ctx).Info("Listing data messages")
 
 	if params.Offset != nil && *params.Offset < 0 {
 		zero := 0
@@ -62,7 +63,7 @@ func (h *KapuaHandler) HandleListDataMessages(ctx context.Context, req *mcp.Call
 
 	result, err := h.client.ListDataMessages(ctx, query)
 	if err != nil {
-		h.logger.Error("List data messages failed: %v", err)
+		h.logger.WithContext(ctx).Error("List data messages failed: %v", err)
 		return nil, nil, fmt.Errorf("failed to list data messages: %w", err)
 	}
 
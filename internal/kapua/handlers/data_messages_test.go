@@ -85,11 +85,8 @@ func TestHandleListDataMessagesSuccess(t *testing.T) {
 		t.Fatalf("unexpected list payload: %+v", response)
 	}
 
-	typed, ok := data.(*models.DataMessageListResult)
-	if !ok {
-		t.Fatalf("expected *models.DataMessageListResult, got %T", data)
-	}
-	if len(typed.Items) != 1 {
+	typed := data
+	if typed == nil || len(typed.Items) != 1 {
 		t.Fatalf("expected one item, got %d", len(typed.Items))
 	}
 }
@@ -112,8 +109,7 @@ func TestHandleListDataMessagesNoParams(t *testing.T) {
 		t.Fatalf("unexpected summary: %s", summary)
 	}
 
-	typed, ok := data.(*models.DataMessageListResult)
-	if !ok || len(typed.Items) != 0 {
+	if data == nil || len(data.Items) != 0 {
 		t.Fatalf("unexpected result: %+v", data)
 	}
 }
@@ -8,6 +8,7 @@ import (
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 
+	"kapua-mcp-server/internal/kapua/models"
 	"kapua-mcp-server/internal/kapua/services"
 )
 
@@ -30,12 +31,12 @@ type ListDeviceLogsParams struct {
 }
 
 // HandleListDeviceLogs lists device logs registered in Kapua for the current scope.
-func (h *KapuaHandler) HandleListDeviceLogs(ctx context.Context, req *mcp.CallToolRequest, params *ListDeviceLogsParams) (*mcp.CallToolResult, any, error) {
+func (h *KapuaHandler) HandleListDeviceLogs(ctx context.Context, req *mcp.CallToolRequest, params *ListDeviceLogsParams) (*mcp.CallToolResult, *models.DeviceLogListResult, error) {
 	if params == nil {
 		params = &ListDeviceLogsParams{}
 	}
 
-	h.logger.Info("Listing device logs")
+	h.logger.WithContext(ctx).Info("Listing device logs")
 
 	if params.Offset != nil && *params.Offset < 0 {
 		zero := 0
@@ -67,7 +68,7 @@ func (h *KapuaHandler) HandleListDeviceLogs(ctx context.Context, req *mcp.CallTo
 
 	result, err := h.client.ListDeviceLogs(ctx, query)
 	if err != nil {
-		h.logger.Error("List device logs failed: %v", err)
+		h.logger.WithContext(ctx).Error("List device logs failed: %v", err)
 		if errors.Is(err, services.ErrDeviceLogsNotSupported) {
 			return nil, nil, err
 		}
@@ -82,8 +82,7 @@ func TestHandleListDeviceLogsSuccess(t *testing.T) {
 		t.Fatalf("unexpected list payload: %+v", list)
 	}
 
-	typed, ok := data.(*models.DeviceLogListResult)
-	if !ok || len(typed.Items) != 1 {
+	if data == nil || len(data.Items) != 1 {
 		t.Fatalf("unexpected data: %+v", data)
 	}
 }
@@ -105,8 +104,7 @@ func TestHandleListDeviceLogsNoParams(t *testing.T) {
 		t.Fatalf("unexpected summary: %s", summary)
 	}
 
-	typed, ok := data.(*models.DeviceLogListResult)
-	if !ok || len(typed.Items) != 0 {
+	if data == nil || len(data.Items) != 0 {
 		t.Fatalf("unexpected result: %+v", data)
 	}
 }
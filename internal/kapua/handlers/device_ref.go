@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"kapua-mcp-server/internal/kapua/models"
+)
+
+// DeviceRef is embedded in the parameters of device-scoped tools. Users know
+// devices by client ID or display name rather than by Kapua's opaque device ID,
+// so deviceId accepts any of the three.
+type DeviceRef struct {
+	DeviceID string `json:"deviceId,omitempty" jsonschema:"The device: its Kapua device ID, client ID or display name. Either deviceId or clientId is required"`
+	ClientID string `json:"clientId,omitempty" jsonschema:"The client ID of the device, as an alternative to deviceId"`
+}
+
+// resolveDevice replaces ref.DeviceID with the Kapua ID of the device ref names.
+// A deviceId is tried as a device ID, then as a client ID, then as a display
+// name. Lookups use the fleet index when it knows the device, and otherwise
+// Kapua, remembering the answer for deviceRefCacheTTL.
+func (h *KapuaHandler) resolveDevice(ctx context.Context, ref *DeviceRef) error {
+	var (
+		deviceID string
+		err      error
+	)
+	switch {
+	case ref.DeviceID == "" && ref.ClientID == "":
+		return fmt.Errorf("deviceId or clientId is required")
+	case ref.DeviceID != "" && ref.ClientID != "":
+		return fmt.Errorf("set either deviceId or clientId, not both")
+	case ref.ClientID != "":
+		deviceID, err = h.deviceIDForClientID(ctx, ref.ClientID)
+	default:
+		deviceID, err = h.lookupDevice(ctx, ref.DeviceID)
+	}
+	if err != nil {
+		return err
+	}
+	ref.DeviceID = deviceID
+	return nil
+}
+
+const (
+	deviceRefCacheTTL  = 30 * time.Second
+	maxDeviceNamePages = 5 // Pages of matchTerm results searched for a display name
+)
+
+// deviceRefCache remembers what recent device references resolved to, so that a
+// conversation naming the same device again does not repeat its lookups.
+type deviceRefCache struct {
+	mu      sync.Mutex
+	entries map[string]deviceRefEntry
+}
+
+type deviceRefEntry struct {
+	deviceID string
+	expires  time.Time
+}
+
+func (c *deviceRefCache) get(ref string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[ref]
+	if !ok || !timeNow().Before(entry.expires) {
+		return "", false
+	}
+	return entry.deviceID, true
+}
+
+func (c *deviceRefCache) put(ref, deviceID string) {
+	now := timeNow()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = map[string]deviceRefEntry{}
+	}
+	for cached, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, cached)
+		}
+	}
+	c.entries[ref] = deviceRefEntry{deviceID: deviceID, expires: now.Add(deviceRefCacheTTL)}
+}
+
+// lookupDevice returns the Kapua ID of the device whose ID, client ID or display
+// name is ref. A ref that matches nothing is assumed to be a device ID, so Kapua
+// reports unknown devices as before.
+func (h *KapuaHandler) lookupDevice(ctx context.Context, ref string) (string, error) {
+	if snapshot := h.fleetIndex.current(); snapshot != nil {
+		if _, ok := snapshot.byID[ref]; ok {
+			return ref, nil
+		}
+		if entry, ok := snapshot.byClientID[ref]; ok {
+			return string(entry.device.ID), nil
+		}
+		var named []models.Device
+		for _, entry := range snapshot.devices {
+			if strings.EqualFold(entry.device.DisplayName, ref) {
+				named = append(named, entry.device)
+			}
+		}
+		if len(named) > 0 {
+			return deviceIDForName(ref, named)
+		}
+	}
+
+	if deviceID, ok := h.deviceRefs.get(ref); ok {
+		return deviceID, nil
+	}
+	deviceID, err := h.queryDevice(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	if deviceID != "" {
+		h.deviceRefs.put(ref, deviceID)
+		return deviceID, nil
+	}
+	return ref, nil
+}
+
+// queryDevice asks Kapua for the device ref names: by ID when ref looks like a
+// Kapua ID, then by client ID, then by display name. It returns "" when a lookup
+// failed, so that ref is used as a device ID without being cached.
+func (h *KapuaHandler) queryDevice(ctx context.Context, ref string) (string, error) {
+	logger := h.logger.WithContext(ctx)
+	if looksLikeDeviceID(ref) {
+		if device, err := h.client.GetDevice(ctx, ref); err == nil && string(device.ID) == ref {
+			return ref, nil
+		}
+	}
+
+	result, err := h.client.ListDevices(ctx, map[string]string{"clientId": ref})
+	if err != nil {
+		logger.Warn("Looking up client ID %q failed, using it as a device ID: %v", ref, err)
+		return "", nil
+	}
+	for _, device := range result.Items {
+		if device.ClientID == ref && device.ID != "" {
+			return string(device.ID), nil
+		}
+	}
+
+	// matchTerm also matches other fields, so page through the results: a name is
+	// only unambiguous once every match has been seen.
+	var named []models.Device
+	for page := 0; ; page++ {
+		if page == maxDeviceNamePages {
+			return "", fmt.Errorf("more than %d devices match %q; pass a client ID or device ID instead", page*maxDevicePageSize, ref)
+		}
+		result, err := h.client.ListDevices(ctx, map[string]string{
+			"matchTerm": ref,
+			"limit":     strconv.Itoa(maxDevicePageSize),
+			"offset":    strconv.Itoa(page * maxDevicePageSize),
+		})
+		if err != nil {
+			logger.Warn("Looking up display name %q failed, using it as a device ID: %v", ref, err)
+			return "", nil
+		}
+		for _, device := range result.Items {
+			if strings.EqualFold(device.DisplayName, ref) && device.ID != "" {
+				named = append(named, device)
+			}
+		}
+		if len(named) > 1 || len(result.Items) < maxDevicePageSize {
+			break
+		}
+	}
+	if len(named) > 0 {
+		return deviceIDForName(ref, named)
+	}
+	return ref, nil
+}
+
+// looksLikeDeviceID reports whether ref can be the ID Kapua gives a device: the
+// unpadded base64url encoding of a random positive 64-bit integer, which takes 10
+// to 12 characters. Positive means the first byte has its high bit clear, so IDs
+// start with A-Z or a-f; most client IDs and display names fail the test and skip
+// the GetDevice call.
+func looksLikeDeviceID(ref string) bool {
+	if len(ref) < 10 || len(ref) > 12 {
+		return false
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(ref)
+	return err == nil && len(decoded) > 0 && decoded[0] < 0x80
+}
+
+// deviceIDForName returns the ID of the only device in named, and an error
+// listing them when several devices share the display name.
+func deviceIDForName(name string, named []models.Device) (string, error) {
+	if len(named) == 1 {
+		return string(named[0].ID), nil
+	}
+	candidates := make([]string, len(named))
+	for i, device := range named {
+		candidates[i] = fmt.Sprintf("%s (deviceId %s)", device.ClientID, device.ID)
+	}
+	sort.Strings(candidates)
+	return "", fmt.Errorf("%d devices are named %q: %s; pass a client ID or device ID instead", len(named), name, strings.Join(candidates, ", "))
+}
+
+// deviceIDForClientID returns the Kapua ID of the device with clientID, from the
+// fleet index when it knows the device.
+func (h *KapuaHandler) deviceIDForClientID(ctx context.Context, clientID string) (string, error) {
+	if snapshot := h.fleetIndex.current(); snapshot != nil {
+		if entry, ok := snapshot.byClientID[clientID]; ok {
+			return string(entry.device.ID), nil
+		}
+	}
+	result, err := h.client.ListDevices(ctx, map[string]string{"clientId": clientID})
+	if err != nil {
+		return "", fmt.Errorf("failed to look up client ID %q: %w", clientID, err)
+	}
+	for _, device := range result.Items {
+		if device.ClientID == clientID && device.ID != "" {
+			return string(device.ID), nil
+		}
+	}
+	return "", fmt.Errorf("no device with client ID %q", clientID)
+}
@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// withoutDeviceMatches answers device lookups with no matches, so that tool
+// tests see the given deviceId used as is, and passes other requests to fn.
+func withoutDeviceMatches(fn http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && r.URL.Path == "/v1/tenant/devices" {
+			_, _ = w.Write([]byte(`{"items":[]}`))
+			return
+		}
+		if id, ok := strings.CutPrefix(r.URL.Path, "/v1/tenant/devices/"); ok && r.Method == http.MethodGet && !strings.Contains(id, "/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fn(w, r)
+	}
+}
+
+func newLookupHandler(t *testing.T, requests *[]string) *KapuaHandler {
+	return newHandlerWithServer(t, func(w http.ResponseWriter, r *http.Request) {
+		*requests = append(*requests, r.URL.RawQuery)
+		query := r.URL.Query()
+		switch {
+		case r.URL.Path != "/v1/tenant/devices":
+			w.WriteHeader(http.StatusNotFound)
+		case query.Get("clientId") == "gateway-07":
+			_, _ = w.Write([]byte(`{"items":[{"id":"AQ","clientId":"gateway-07"}]}`))
+		case query.Get("matchTerm") == "boiler room":
+			_, _ = w.Write([]byte(`{"items":[{"id":"Ag","clientId":"gw-boiler","displayName":"Boiler Room"},{"id":"Aw","clientId":"gw-boiler-2","displayName":"Boiler room annex"}]}`))
+		case query.Get("matchTerm") == "pump":
+			_, _ = w.Write([]byte(`{"items":[{"id":"BA","clientId":"pump-1","displayName":"Pump"},{"id":"BQ","clientId":"pump-2","displayName":"pump"}]}`))
+		default:
+			_, _ = w.Write([]byte(`{"items":[]}`))
+		}
+	})
+}
+
+func TestResolveDevice(t *testing.T) {
+	cases := []struct {
+		name string
+		ref  DeviceRef
+		want string
+		err  string
+	}{
+		{"client ID", DeviceRef{DeviceID: "gateway-07"}, "AQ", ""},
+		{"clientId field", DeviceRef{ClientID: "gateway-07"}, "AQ", ""},
+		{"display name", DeviceRef{DeviceID: "boiler room"}, "Ag", ""},
+		{"device ID", DeviceRef{DeviceID: "Xa9"}, "Xa9", ""},
+		{"ambiguous name", DeviceRef{DeviceID: "pump"}, "", `2 devices are named "pump": pump-1 (deviceId BA), pump-2 (deviceId BQ)`},
+		{"unknown clientId", DeviceRef{ClientID: "Xa9"}, "", `no device with client ID "Xa9"`},
+		{"missing", DeviceRef{}, "", "deviceId or clientId is required"},
+		{"both", DeviceRef{DeviceID: "AQ", ClientID: "gateway-07"}, "", "not both"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var requests []string
+			handler := newLookupHandler(t, &requests)
+			ref := tc.ref
+			err := handler.resolveDevice(context.Background(), &ref)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error containing %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveDevice returned error: %v", err)
+			}
+			if ref.DeviceID != tc.want {
+				t.Fatalf("resolved %q, want %q (requests %v)", ref.DeviceID, tc.want, requests)
+			}
+		})
+	}
+}
+
+func TestResolveDeviceFromFleetIndex(t *testing.T) {
+	handler, fleet := newIndexedHandler(t, timeNow())
+	if err := handler.fleetIndex.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh returned error: %v", err)
+	}
+	lists := fleet.count("/v1/tenant/devices")
+
+	for ref, want := range map[string]string{
+		"dev-2":               "dev-2",
+		"charlie":             "dev-3",
+		"boiler ROOM gateway": "dev-1",
+	} {
+		resolved := DeviceRef{DeviceID: ref}
+		if err := handler.resolveDevice(context.Background(), &resolved); err != nil || resolved.DeviceID != want {
+			t.Errorf("resolveDevice(%s) = %q, %v; want %q", ref, resolved.DeviceID, err, want)
+		}
+	}
+	if fleet.count("/v1/tenant/devices") != lists {
+		t.Fatal("expected references to be resolved from the index")
+	}
+}
+
+func TestDeviceToolsAcceptClientIDs(t *testing.T) {
+	var paths []string
+	handler := newHandlerWithServer(t, func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		switch r.URL.Path {
+		case "/v1/tenant/devices":
+			_, _ = w.Write([]byte(`{"items":[{"id":"AQ","clientId":"gateway-07"}]}`))
+		case "/v1/tenant/devices/AQ/inventory":
+			_, _ = w.Write([]byte(`{"inventoryItems":[]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	params := &DeviceInventoryParams{DeviceRef: DeviceRef{DeviceID: "gateway-07"}}
+	if _, _, err := handler.HandleDeviceInventoryRead(context.Background(), nil, params); err != nil {
+		t.Fatalf("HandleDeviceInventoryRead returned error: %v", err)
+	}
+	if len(paths) != 2 || paths[1] != "/v1/tenant/devices/AQ/inventory" {
+		t.Fatalf("unexpected requests: %v", paths)
+	}
+}
+
+func TestLookupDeviceByID(t *testing.T) {
+	var requests []string
+	handler := newHandlerWithServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path+"?"+r.URL.RawQuery)
+		if r.URL.Path == "/v1/tenant/devices/f0bQdxzNSqw" {
+			_, _ = w.Write([]byte(`{"id":"f0bQdxzNSqw","clientId":"gw-1"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"items":[]}`))
+	})
+
+	for range 2 {
+		ref := DeviceRef{DeviceID: "f0bQdxzNSqw"}
+		if err := handler.resolveDevice(context.Background(), &ref); err != nil || ref.DeviceID != "f0bQdxzNSqw" {
+			t.Fatalf("resolveDevice = %q, %v", ref.DeviceID, err)
+		}
+	}
+	if len(requests) != 1 || requests[0] != "/v1/tenant/devices/f0bQdxzNSqw?" {
+		t.Fatalf("expected a single GetDevice call, got %v", requests)
+	}
+}
+
+func TestLookupDeviceCachesReferences(t *testing.T) {
+	var requests []string
+	handler := newLookupHandler(t, &requests)
+	for range 3 {
+		ref := DeviceRef{DeviceID: "boiler room"}
+		if err := handler.resolveDevice(context.Background(), &ref); err != nil || ref.DeviceID != "Ag" {
+			t.Fatalf("resolveDevice = %q, %v", ref.DeviceID, err)
+		}
+	}
+	if len(requests) != 2 {
+		t.Fatalf("expected one client ID and one name lookup, got %v", requests)
+	}
+
+	stubTimeNow(t, timeNow().Add(deviceRefCacheTTL))
+	ref := DeviceRef{DeviceID: "boiler room"}
+	if err := handler.resolveDevice(context.Background(), &ref); err != nil || len(requests) != 4 {
+		t.Fatalf("expected an expired reference to be looked up again, got %v, %v", requests, err)
+	}
+}
+
+func TestLookupDevicePagesThroughNameMatches(t *testing.T) {
+	page := func(offset int, named ...string) []byte {
+		var items []string
+		for i := range maxDevicePageSize - len(named) {
+			items = append(items, fmt.Sprintf(`{"id":"id-%d-%d","displayName":"Pump station %d"}`, offset, i, i))
+		}
+		for i, name := range named {
+			items = append(items, fmt.Sprintf(`{"id":"named-%d-%d","clientId":"c-%d-%d","displayName":%q}`, offset, i, offset, i, name))
+		}
+		return []byte(`{"items":[` + strings.Join(items, ",") + `]}`)
+	}
+	newHandler := func(pages func(offset int) []byte) *KapuaHandler {
+		return newHandlerWithServer(t, func(w http.ResponseWriter, r *http.Request) {
+			query := r.URL.Query()
+			if query.Get("matchTerm") == "" {
+				_, _ = w.Write([]byte(`{"items":[]}`))
+				return
+			}
+			offset, _ := strconv.Atoi(query.Get("offset"))
+			_, _ = w.Write(pages(offset))
+		})
+	}
+
+	// The second device named "pump" is on the second page.
+	handler := newHandler(func(offset int) []byte {
+		switch offset {
+		case 0:
+			return page(offset, "pump")
+		case maxDevicePageSize:
+			return []byte(`{"items":[{"id":"BQ","clientId":"pump-2","displayName":"Pump"}]}`)
+		}
+		return []byte(`{"items":[]}`)
+	})
+	ref := DeviceRef{DeviceID: "pump"}
+	if err := handler.resolveDevice(context.Background(), &ref); err == nil || !strings.Contains(err.Error(), "2 devices are named") {
+		t.Fatalf("expected the name to be ambiguous, got %q, %v", ref.DeviceID, err)
+	}
+
+	// Matches never run out: give up rather than guess.
+	handler = newHandler(func(offset int) []byte { return page(offset) })
+	ref = DeviceRef{DeviceID: "pump"}
+	if err := handler.resolveDevice(context.Background(), &ref); err == nil || !strings.Contains(err.Error(), "pass a client ID or device ID") {
+		t.Fatalf("expected too many matches, got %q, %v", ref.DeviceID, err)
+	}
+}
+
+func TestLooksLikeDeviceID(t *testing.T) {
+	for ref, want := range map[string]bool{
+		"f0bQdxzNSqw":   true,
+		"AQAAAAAAAAAB":  true,
+		"AQ":            false, // Too short for a random 64-bit ID
+		"gateway-07":    false, // Would encode a negative number
+		"boiler room":   false,
+		"00:1A:2B:3C":   false,
+		"f0bQdxzNSqwXY": false,
+	} {
+		if got := looksLikeDeviceID(ref); got != want {
+			t.Errorf("looksLikeDeviceID(%q) = %v, want %v", ref, got, want)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 
@@ -24,6 +25,7 @@ type ListDevicesParams struct {
 	MatchTerm        string                  `json:"matchTerm,omitempty" jsonschema:"Search term to match against device fields"`
 	Limit            int                     `json:"limit,omitempty" jsonschema:"Maximum number of devices to return (default: 50)"`
 	Offset           int                     `json:"offset,omitempty" jsonschema:"Number of devices to skip (default: 0)"`
+	CacheControl
 }
 
 // CreateDeviceParams defines parameters for creating a device
@@ -47,8 +49,8 @@ type DeleteDeviceParams struct {
 // MCP Tool Handlers
 
 // HandleListDevices handles listing Kapua devices with structured JSON response
-func (h *KapuaHandler) HandleListDevices(ctx context.Context, req *mcp.CallToolRequest, params *ListDevicesParams) (*mcp.CallToolResult, any, error) {
-	h.logger.Info("Listing devices:")
+func (h *KapuaHandler) HandleListDevices(ctx context.Context, req *mcp.CallToolRequest, params *ListDevicesParams) (*mcp.CallToolResult, *models.DeviceListResult, error) {
+	h.logger.WithContext(ctx).Info("Listing devices:")
 
 	// Build query parameters
 	queryParams := make(map[string]string)
@@ -69,16 +71,16 @@ func (h *KapuaHandler) HandleListDevices(ctx context.Context, req *mcp.CallToolR
 		queryParams["offset"] = strconv.Itoa(params.Offset)
 	}
 
-	result, err := h.client.ListDevices(ctx, queryParams)
+	result, err := h.client.ListDevices(params.cacheContext(ctx), queryParams)
 	if err != nil {
-		h.logger.Error("List devices failed: %v", err)
+		h.logger.WithContext(ctx).Error("List devices failed: %v", err)
 		return nil, nil, fmt.Errorf("failed to list devices: %w", err)
 	}
 
 	// Return structured JSON data that LLMs can interpret
 	jsonData, err := json.Marshal(result)
 	if err != nil {
-		h.logger.Error("Failed to marshal device list: %v", err)
+		h.logger.WithContext(ctx).Error("Failed to marshal device list: %v", err)
 		return nil, nil, fmt.Errorf("failed to marshal response: %w", err)
 	}
 
@@ -98,17 +100,21 @@ func (h *KapuaHandler) HandleListDevices(ctx context.Context, req *mcp.CallToolR
 				Text: string(jsonData),
 			},
 		},
-	}, nil, nil
+	}, result, nil
 }
 
 // readDevicesResource returns all devices as a JSON resource
 func (h *KapuaHandler) readDevicesResource(ctx context.Context, uri *url.URL) (*mcp.ReadResourceResult, error) {
+	ctx = resourceCacheContext(ctx, uri)
 	limitParam := 0
 	if uri != nil {
 		if parsedLimit, err := strconv.Atoi(uri.Query().Get("limit")); err == nil && parsedLimit > 0 {
 			limitParam = parsedLimit
 		}
 	}
+	if snapshot := h.fleetIndex.current(); snapshot != nil && !noCacheRequested(uri) {
+		return devicesResourceResult(devicesResourceFromIndex(snapshot, limitParam))
+	}
 
 	var devices []models.Device
 	offset := 0
@@ -139,7 +145,7 @@ func (h *KapuaHandler) readDevicesResource(ctx context.Context, uri *url.URL) (*
 
 		result, err := h.client.ListDevices(ctx, queryParams)
 		if err != nil {
-			h.logger.Error("Failed to read devices resource: %v", err)
+			h.logger.WithContext(ctx).Error("Failed to read devices resource: %v", err)
 			return nil, fmt.Errorf("failed to read devices resource: %w", err)
 		}
 
@@ -148,6 +154,7 @@ func (h *KapuaHandler) readDevicesResource(ctx context.Context, uri *url.URL) (*
 		}
 
 		devices = append(devices, result.Items...)
+		reportProgress(ctx, len(devices), min(max(totalCount, len(devices)), targetCount), "Listed %d of %d devices")
 
 		if len(result.Items) == 0 || len(result.Items) < pageSize {
 			break
@@ -159,13 +166,17 @@ func (h *KapuaHandler) readDevicesResource(ctx context.Context, uri *url.URL) (*
 		totalCount = len(devices)
 	}
 
-	resourceData := map[string]interface{}{
+	now := timeNow()
+	return devicesResourceResult(map[string]interface{}{
 		"total_count":     totalCount,
 		"processed_count": len(devices),
 		"devices":         devices,
-		"last_updated":    fmt.Sprintf("%d", timeNow().Unix()),
-	}
+		"last_updated":    fmt.Sprintf("%d", now.Unix()),
+		"data_as_of":      now.UTC().Format(time.RFC3339),
+	})
+}
 
+func devicesResourceResult(resourceData map[string]interface{}) (*mcp.ReadResourceResult, error) {
 	jsonData, err := json.MarshalIndent(resourceData, "", "  ")
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal devices resource: %w", err)
@@ -11,11 +11,17 @@ import (
 // Asset tools
 
 type DeviceAssetsListParams struct {
-	DeviceID string `json:"deviceId" jsonschema:"The device ID to inspect assets"`
+	DeviceRef
 }
 
 func (h *KapuaHandler) HandleDeviceAssetsList(ctx context.Context, req *mcp.CallToolRequest, params *DeviceAssetsListParams) (*mcp.CallToolResult, any, error) {
-	h.logger.Info("Listing assets for device %s", params.DeviceID)
+	if params == nil {
+		return nil, nil, fmt.Errorf("deviceId or clientId is required")
+	}
+	if err := h.resolveDevice(ctx, &params.DeviceRef); err != nil {
+		return nil, nil, err
+	}
+	h.logger.WithContext(ctx).Info("Listing assets for device %s", params.DeviceID)
 	out, err := h.client.ListDeviceAssets(ctx, params.DeviceID)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to list device assets: %w", err)
@@ -24,12 +30,18 @@ func (h *KapuaHandler) HandleDeviceAssetsList(ctx context.Context, req *mcp.Call
 }
 
 type DeviceAssetsReadParams struct {
-	DeviceID string         `json:"deviceId" jsonschema:"The device ID to read assets from"`
-	Request  map[string]any `json:"request" jsonschema:"Assets read request as object"`
+	DeviceRef
+	Request map[string]any `json:"request" jsonschema:"Assets read request as object"`
 }
 
 func (h *KapuaHandler) HandleDeviceAssetsRead(ctx context.Context, req *mcp.CallToolRequest, params *DeviceAssetsReadParams) (*mcp.CallToolResult, any, error) {
-	h.logger.Info("Reading assets for device %s", params.DeviceID)
+	if params == nil {
+		return nil, nil, fmt.Errorf("deviceId or clientId is required")
+	}
+	if err := h.resolveDevice(ctx, &params.DeviceRef); err != nil {
+		return nil, nil, err
+	}
+	h.logger.WithContext(ctx).Info("Reading assets for device %s", params.DeviceID)
 	out, err := h.client.ReadDeviceAssets(ctx, params.DeviceID, params.Request)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to read device assets: %w", err)
@@ -38,12 +50,18 @@ func (h *KapuaHandler) HandleDeviceAssetsRead(ctx context.Context, req *mcp.Call
 }
 
 type DeviceAssetsWriteParams struct {
-	DeviceID string         `json:"deviceId" jsonschema:"The device ID to write assets to"`
-	Values   map[string]any `json:"values" jsonschema:"Assets write values as object"`
+	DeviceRef
+	Values map[string]any `json:"values" jsonschema:"Assets write values as object"`
 }
 
 func (h *KapuaHandler) HandleDeviceAssetsWrite(ctx context.Context, req *mcp.CallToolRequest, params *DeviceAssetsWriteParams) (*mcp.CallToolResult, any, error) {
-	h.logger.Info("Writing assets for device %s", params.DeviceID)
+	if params == nil {
+		return nil, nil, fmt.Errorf("deviceId or clientId is required")
+	}
+	if err := h.resolveDevice(ctx, &params.DeviceRef); err != nil {
+		return nil, nil, err
+	}
+	h.logger.WithContext(ctx).Info("Writing assets for device %s", params.DeviceID)
 	out, err := h.client.WriteDeviceAssets(ctx, params.DeviceID, params.Values)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to write device assets: %w", err)
@@ -9,12 +9,18 @@ import (
 )
 
 type DeviceCommandExecuteParams struct {
-	DeviceID string         `json:"deviceId" jsonschema:"The device ID to execute command on"`
-	Command  map[string]any `json:"command" jsonschema:"Command payload as object"`
+	DeviceRef
+	Command map[string]any `json:"command" jsonschema:"Command payload as object"`
 }
 
 func (h *KapuaHandler) HandleDeviceCommandExecute(ctx context.Context, req *mcp.CallToolRequest, params *DeviceCommandExecuteParams) (*mcp.CallToolResult, any, error) {
-	h.logger.Info("Executing command on device %s", params.DeviceID)
+	if params == nil {
+		return nil, nil, fmt.Errorf("deviceId or clientId is required")
+	}
+	if err := h.resolveDevice(ctx, &params.DeviceRef); err != nil {
+		return nil, nil, err
+	}
+	h.logger.WithContext(ctx).Info("Executing command on device %s", params.DeviceID)
 	out, err := h.client.ExecuteDeviceCommand(ctx, params.DeviceID, params.Command)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to execute device command: %w", err)
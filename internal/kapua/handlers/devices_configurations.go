@@ -10,24 +10,29 @@ import (
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
-// DeviceIDArg is a minimal object wrapper to satisfy MCP input schema (must be type object)
+// DeviceID is a minimal object wrapper to satisfy MCP input schema (must be type object)
 type DeviceID struct {
-	DeviceID string `json:"deviceId" jsonschema:"The Kapua device ID (required)"`
+	DeviceRef
+	CacheControl
 }
 
-// HandleDeviceConfigurationsRead reads all configurations for a device by deviceId only
-func (h *KapuaHandler) HandleDeviceConfigurationsRead(ctx context.Context, req *mcp.CallToolRequest, args *DeviceID) (*mcp.CallToolResult, any, error) {
-	if args == nil || args.DeviceID == "" {
-		return nil, nil, fmt.Errorf("deviceId is required")
+// HandleDeviceConfigurationsRead reads all configurations for a device
+func (h *KapuaHandler) HandleDeviceConfigurationsRead(ctx context.Context, req *mcp.CallToolRequest, args *DeviceID) (*mcp.CallToolResult, *models.DeviceConfiguration, error) {
+	if args == nil {
+		return nil, nil, fmt.Errorf("deviceId or clientId is required")
 	}
-	h.logger.Info("Reading configurations for device %s", args.DeviceID)
+	if err := h.resolveDevice(ctx, &args.DeviceRef); err != nil {
+		return nil, nil, err
+	}
+	h.logger.WithContext(ctx).Info("Reading configurations for device %s", args.DeviceID)
 	//dev := models.Device{KapuaEntity: models.KapuaEntity{ID: models.KapuaID(args.DeviceID)}}
-	conf, err := h.client.ReadDeviceConfigurations(ctx, args.DeviceID)
+	conf, err := h.client.ReadDeviceConfigurations(args.cacheContext(ctx), args.DeviceID)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to read device configurations: %w", err)
 	}
 	bytes, _ := json.Marshal(conf)
-	return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: string(bytes)}}}, conf, nil
+	summary := fmt.Sprintf("Retrieved %d component configurations", len(conf.Configuration))
+	return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: summary}, &mcp.TextContent{Text: string(bytes)}}}, conf, nil
 }
 
 type DeviceConfigurationsWriteParams struct {
@@ -35,13 +40,13 @@ type DeviceConfigurationsWriteParams struct {
 	Payload map[string]any `json:"payload" jsonschema:"Configurations write payload as object"`
 }
 
-func (h *KapuaHandler) HandleDeviceConfigurationsWrite(ctx context.Context, req *mcp.CallToolRequest, params *DeviceConfigurationsWriteParams) (*mcp.CallToolResult, any, error) {
-	h.logger.Info("Writing configurations for device %s", params.Device.ID)
+func (h *KapuaHandler) HandleDeviceConfigurationsWrite(ctx context.Context, req *mcp.CallToolRequest, params *DeviceConfigurationsWriteParams) (*mcp.CallToolResult, *OperationResult, error) {
+	h.logger.WithContext(ctx).Info("Writing configurations for device %s", params.Device.ID)
 	if err := h.client.WriteDeviceConfigurations(ctx, params.Device, params.Payload); err != nil {
 		return nil, nil, fmt.Errorf("failed to write device configurations: %w", err)
 	}
 	summary := fmt.Sprintf("Updated configurations for device %s", params.Device.ID)
-	return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: summary}}}, map[string]string{"status": "updated"}, nil
+	return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: summary}}}, &OperationResult{Status: OperationUpdated, DeviceID: string(params.Device.ID)}, nil
 }
 
 type DeviceComponentConfigurationReadParams struct {
@@ -49,8 +54,8 @@ type DeviceComponentConfigurationReadParams struct {
 	ComponentID string        `json:"componentId" jsonschema:"The component ID"`
 }
 
-func (h *KapuaHandler) HandleDeviceComponentConfigurationRead(ctx context.Context, req *mcp.CallToolRequest, params *DeviceComponentConfigurationReadParams) (*mcp.CallToolResult, any, error) {
-	h.logger.Info("Reading component configuration %s for device %s", params.ComponentID, params.Device.ID)
+func (h *KapuaHandler) HandleDeviceComponentConfigurationRead(ctx context.Context, req *mcp.CallToolRequest, params *DeviceComponentConfigurationReadParams) (*mcp.CallToolResult, *models.DeviceConfiguration, error) {
+	h.logger.WithContext(ctx).Info("Reading component configuration %s for device %s", params.ComponentID, params.Device.ID)
 	conf, err := h.client.ReadDeviceComponentConfiguration(ctx, params.Device, params.ComponentID)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to read device component configuration: %w", err)
@@ -65,11 +70,11 @@ type DeviceComponentConfigurationWriteParams struct {
 	Payload     map[string]any `json:"payload" jsonschema:"Component configuration write payload as object"`
 }
 
-func (h *KapuaHandler) HandleDeviceComponentConfigurationWrite(ctx context.Context, req *mcp.CallToolRequest, params *DeviceComponentConfigurationWriteParams) (*mcp.CallToolResult, any, error) {
-	h.logger.Info("Writing component configuration %s for device %s", params.ComponentID, params.Device.ID)
+func (h *KapuaHandler) HandleDeviceComponentConfigurationWrite(ctx context.Context, req *mcp.CallToolRequest, params *DeviceComponentConfigurationWriteParams) (*mcp.CallToolResult, *OperationResult, error) {
+	h.logger.WithContext(ctx).Info("Writing component configuration %s for device %s", params.ComponentID, params.Device.ID)
 	if err := h.client.WriteDeviceComponentConfiguration(ctx, params.Device, params.ComponentID, params.Payload); err != nil {
 		return nil, nil, fmt.Errorf("failed to write device component configuration: %w", err)
 	}
 	summary := fmt.Sprintf("Updated component %s configuration for device %s", params.ComponentID, params.Device.ID)
-	return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: summary}}}, map[string]string{"status": "updated"}, nil
+	return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: summary}}}, &OperationResult{Status: OperationUpdated, DeviceID: string(params.Device.ID)}, nil
 }
@@ -16,7 +16,7 @@ func newConfigHandler(t *testing.T, fn http.HandlerFunc) *KapuaHandler {
 }
 
 func TestHandleDeviceConfigurationsReadSuccess(t *testing.T) {
-	handler := newConfigHandler(t, func(w http.ResponseWriter, r *http.Request) {
+	handler := newConfigHandler(t, withoutDeviceMatches(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != "/v1/tenant/devices/device-1/configurations" {
 			t.Fatalf("unexpected path %s", r.URL.Path)
 		}
@@ -24,9 +24,9 @@ func TestHandleDeviceConfigurationsReadSuccess(t *testing.T) {
 		data, _ := json.Marshal(payload)
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write(data)
-	})
+	}))
 
-	result, out, err := handler.HandleDeviceConfigurationsRead(context.Background(), nil, &DeviceID{DeviceID: "device-1"})
+	result, out, err := handler.HandleDeviceConfigurationsRead(context.Background(), nil, &DeviceID{DeviceRef: DeviceRef{DeviceID: "device-1"}})
 	if err != nil {
 		t.Fatalf("HandleDeviceConfigurationsRead returned error: %v", err)
 	}
@@ -34,7 +34,10 @@ func TestHandleDeviceConfigurationsReadSuccess(t *testing.T) {
 		t.Fatalf("expected configuration data")
 	}
 
-	txt := textContent(t, result.Content[0])
+	if summary := textContent(t, result.Content[0]); summary != "Retrieved 1 component configurations" {
+		t.Fatalf("unexpected summary %q", summary)
+	}
+	txt := textContent(t, result.Content[1])
 	var decoded models.DeviceConfiguration
 	if err := json.Unmarshal([]byte(txt), &decoded); err != nil {
 		t.Fatalf("failed to decode json content: %v", err)
@@ -57,7 +60,7 @@ func TestHandleDeviceConfigurationsReadError(t *testing.T) {
 		_, _ = w.Write([]byte("kapua error"))
 	})
 
-	_, _, err := handler.HandleDeviceConfigurationsRead(context.Background(), nil, &DeviceID{DeviceID: "device-1"})
+	_, _, err := handler.HandleDeviceConfigurationsRead(context.Background(), nil, &DeviceID{DeviceRef: DeviceRef{DeviceID: "device-1"}})
 	if err == nil || !strings.Contains(err.Error(), "failed to read device configurations") {
 		t.Fatalf("expected failure, got %v", err)
 	}
@@ -86,8 +89,7 @@ func TestHandleDeviceConfigurationsWriteSuccess(t *testing.T) {
 	if textContent(t, result.Content[0]) != "Updated configurations for device device-1" {
 		t.Fatalf("unexpected summary %s", textContent(t, result.Content[0]))
 	}
-	status, ok := meta.(map[string]string)
-	if !ok || status["status"] != "updated" {
+	if meta == nil || meta.Status != OperationUpdated {
 		t.Fatalf("unexpected metadata: %+v", meta)
 	}
 }
@@ -170,8 +172,7 @@ func TestHandleDeviceComponentConfigurationWriteSuccess(t *testing.T) {
 	if textContent(t, result.Content[0]) != "Updated component service-1 configuration for device device-1" {
 		t.Fatalf("unexpected summary: %s", textContent(t, result.Content[0]))
 	}
-	status, ok := meta.(map[string]string)
-	if !ok || status["status"] != "updated" {
+	if meta == nil || meta.Status != OperationUpdated {
 		t.Fatalf("unexpected metadata: %+v", meta)
 	}
 }
@@ -7,12 +7,14 @@ import (
 	"strconv"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"kapua-mcp-server/internal/kapua/models"
 )
 
 // ListDeviceEventsParams defines parameters for listing device events (logs)
 // for a Kapua device.
 type ListDeviceEventsParams struct {
-	DeviceID      string `json:"deviceId" jsonschema:"The Kapua device ID to read events for (required)"`
+	DeviceRef
 	Resource      string `json:"resource,omitempty" jsonschema:"Filter events by resource (e.g. LOG)"`
 	StartDate     string `json:"startDate,omitempty" jsonschema:"Filter events created on or after this RFC3339 timestamp"`
 	EndDate       string `json:"endDate,omitempty" jsonschema:"Filter events created on or before this RFC3339 timestamp"`
@@ -24,16 +26,16 @@ type ListDeviceEventsParams struct {
 }
 
 // HandleListDeviceEvents retrieves device events (logs) for a Kapua device.
-func (h *KapuaHandler) HandleListDeviceEvents(ctx context.Context, req *mcp.CallToolRequest, params *ListDeviceEventsParams) (*mcp.CallToolResult, any, error) {
+func (h *KapuaHandler) HandleListDeviceEvents(ctx context.Context, req *mcp.CallToolRequest, params *ListDeviceEventsParams) (*mcp.CallToolResult, *models.DeviceEventListResult, error) {
 	if params == nil {
 		return nil, nil, fmt.Errorf("device event parameters are required")
 	}
 
-	if params.DeviceID == "" {
-		return nil, nil, fmt.Errorf("deviceId is required")
+	if err := h.resolveDevice(ctx, &params.DeviceRef); err != nil {
+		return nil, nil, err
 	}
 
-	h.logger.Info("Listing device events for device %s", params.DeviceID)
+	h.logger.WithContext(ctx).Info("Listing device events for device %s", params.DeviceID)
 
 	queryParams := make(map[string]string)
 	if params.Resource != "" {
@@ -63,7 +65,7 @@ func (h *KapuaHandler) HandleListDeviceEvents(ctx context.Context, req *mcp.Call
 
 	result, err := h.client.ListDeviceEvents(ctx, params.DeviceID, queryParams)
 	if err != nil {
-		h.logger.Error("List device events failed: %v", err)
+		h.logger.WithContext(ctx).Error("List device events failed: %v", err)
 		return nil, nil, fmt.Errorf("failed to list device events: %w", err)
 	}
 
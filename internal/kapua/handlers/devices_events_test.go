@@ -12,7 +12,7 @@ import (
 )
 
 func TestHandleListDeviceEventsSuccess(t *testing.T) {
-	handler := newDeviceHandler(t, func(w http.ResponseWriter, r *http.Request) {
+	handler := newDeviceHandler(t, withoutDeviceMatches(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
 			t.Fatalf("expected GET, got %s", r.Method)
 		}
@@ -66,11 +66,11 @@ func TestHandleListDeviceEventsSuccess(t *testing.T) {
 
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write(body)
-	})
+	}))
 
 	askTotal := true
 	params := &ListDeviceEventsParams{
-		DeviceID:      "device-123",
+		DeviceRef:     DeviceRef{DeviceID: "device-123"},
 		Resource:      "LOG",
 		StartDate:     "2023-03-10T00:00:00Z",
 		EndDate:       "2023-03-11T00:00:00Z",
@@ -103,9 +103,9 @@ func TestHandleListDeviceEventsSuccess(t *testing.T) {
 		t.Fatalf("unexpected payload: %+v", body)
 	}
 
-	typed, ok := data.(*models.DeviceEventListResult)
-	if !ok {
-		t.Fatalf("expected *models.DeviceEventListResult, got %T", data)
+	typed := data
+	if typed == nil {
+		t.Fatal("expected a device event list")
 	}
 	if typed.TotalCount != 25 {
 		t.Fatalf("expected totalCount 25, got %d", typed.TotalCount)
@@ -124,7 +124,7 @@ func TestHandleListDeviceEventsTruncated(t *testing.T) {
 		_, _ = w.Write(body)
 	})
 
-	params := &ListDeviceEventsParams{DeviceID: "device-1", Limit: 2, Offset: 20}
+	params := &ListDeviceEventsParams{DeviceRef: DeviceRef{DeviceID: "device-1"}, Limit: 2, Offset: 20}
 	result, _, err := handler.HandleListDeviceEvents(context.Background(), nil, params)
 	if err != nil {
 		t.Fatalf("HandleListDeviceEvents returned error: %v", err)
@@ -137,7 +137,7 @@ func TestHandleListDeviceEventsTruncated(t *testing.T) {
 }
 
 func TestHandleListDeviceEventsTruncatedNegativeOffset(t *testing.T) {
-	handler := newDeviceHandler(t, func(w http.ResponseWriter, r *http.Request) {
+	handler := newDeviceHandler(t, withoutDeviceMatches(func(w http.ResponseWriter, r *http.Request) {
 		if got := r.URL.Query().Get("offset"); got != "" {
 			t.Errorf("negative offset must not be sent to API, got offset=%s", got)
 		}
@@ -149,9 +149,9 @@ func TestHandleListDeviceEventsTruncatedNegativeOffset(t *testing.T) {
 		body, _ := json.Marshal(payload)
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write(body)
-	})
+	}))
 
-	params := &ListDeviceEventsParams{DeviceID: "device-1", Limit: 1, Offset: -3}
+	params := &ListDeviceEventsParams{DeviceRef: DeviceRef{DeviceID: "device-1"}, Limit: 1, Offset: -3}
 	result, _, err := handler.HandleListDeviceEvents(context.Background(), nil, params)
 	if err != nil {
 		t.Fatalf("HandleListDeviceEvents returned error: %v", err)
@@ -172,7 +172,7 @@ func TestHandleListDeviceEventsServiceError(t *testing.T) {
 		_, _ = w.Write([]byte(`{"message":"kapua error"}`))
 	})
 
-	_, _, err := handler.HandleListDeviceEvents(context.Background(), nil, &ListDeviceEventsParams{DeviceID: "device-123"})
+	_, _, err := handler.HandleListDeviceEvents(context.Background(), nil, &ListDeviceEventsParams{DeviceRef: DeviceRef{DeviceID: "device-123"}})
 	if err == nil || !strings.Contains(err.Error(), "failed to list device events") {
 		t.Fatalf("expected wrapped service error, got %v", err)
 	}
@@ -185,7 +185,7 @@ func TestHandleListDeviceEventsMissingParams(t *testing.T) {
 		t.Fatalf("expected missing params error, got %v", err)
 	}
 
-	if _, _, err := handler.HandleListDeviceEvents(context.Background(), nil, &ListDeviceEventsParams{}); err == nil || !strings.Contains(err.Error(), "deviceId is required") {
+	if _, _, err := handler.HandleListDeviceEvents(context.Background(), nil, &ListDeviceEventsParams{}); err == nil || !strings.Contains(err.Error(), "deviceId or clientId is required") {
 		t.Fatalf("expected missing deviceId error, got %v", err)
 	}
 }
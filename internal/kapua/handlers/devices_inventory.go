@@ -11,15 +11,19 @@ import (
 )
 
 type DeviceInventoryParams struct {
-	DeviceID string `json:"deviceId" jsonschema:"The Kapua device ID (required)"`
+	DeviceRef
+	CacheControl
 }
 
-func (h *KapuaHandler) HandleDeviceInventoryRead(ctx context.Context, req *mcp.CallToolRequest, params *DeviceInventoryParams) (*mcp.CallToolResult, any, error) {
-	if params == nil || params.DeviceID == "" {
-		return nil, nil, fmt.Errorf("deviceId is required")
+func (h *KapuaHandler) HandleDeviceInventoryRead(ctx context.Context, req *mcp.CallToolRequest, params *DeviceInventoryParams) (*mcp.CallToolResult, *models.DeviceInventory, error) {
+	if params == nil {
+		return nil, nil, fmt.Errorf("deviceId or clientId is required")
 	}
-	h.logger.Info("Reading inventory for device %s", params.DeviceID)
-	inv, err := h.client.ReadDeviceInventory(ctx, params.DeviceID)
+	if err := h.resolveDevice(ctx, &params.DeviceRef); err != nil {
+		return nil, nil, err
+	}
+	h.logger.WithContext(ctx).Info("Reading inventory for device %s", params.DeviceID)
+	inv, err := h.client.ReadDeviceInventory(params.cacheContext(ctx), params.DeviceID)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to read device inventory: %w", err)
 	}
@@ -28,12 +32,15 @@ func (h *KapuaHandler) HandleDeviceInventoryRead(ctx context.Context, req *mcp.C
 	return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: summary}, &mcp.TextContent{Text: string(bytes)}}}, inv, nil
 }
 
-func (h *KapuaHandler) HandleDeviceInventoryBundles(ctx context.Context, req *mcp.CallToolRequest, params *DeviceInventoryParams) (*mcp.CallToolResult, any, error) {
-	if params == nil || params.DeviceID == "" {
-		return nil, nil, fmt.Errorf("deviceId is required")
+func (h *KapuaHandler) HandleDeviceInventoryBundles(ctx context.Context, req *mcp.CallToolRequest, params *DeviceInventoryParams) (*mcp.CallToolResult, *models.DeviceInventoryBundles, error) {
+	if params == nil {
+		return nil, nil, fmt.Errorf("deviceId or clientId is required")
+	}
+	if err := h.resolveDevice(ctx, &params.DeviceRef); err != nil {
+		return nil, nil, err
 	}
-	h.logger.Info("Listing inventory bundles for device %s", params.DeviceID)
-	inv, err := h.client.ListDeviceInventoryBundles(ctx, params.DeviceID)
+	h.logger.WithContext(ctx).Info("Listing inventory bundles for device %s", params.DeviceID)
+	inv, err := h.client.ListDeviceInventoryBundles(params.cacheContext(ctx), params.DeviceID)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to list device inventory bundles: %w", err)
 	}
@@ -43,38 +50,47 @@ func (h *KapuaHandler) HandleDeviceInventoryBundles(ctx context.Context, req *mc
 }
 
 type DeviceInventoryBundleActionParams struct {
-	DeviceID string                       `json:"deviceId" jsonschema:"The Kapua device ID (required)"`
-	Bundle   models.DeviceInventoryBundle `json:"bundle" jsonschema:"Bundle descriptor object with id/name/version/status/signed fields. Use kapua-device-inventory-bundles-list to discover bundles"`
+	DeviceRef
+	Bundle models.DeviceInventoryBundle `json:"bundle" jsonschema:"Bundle descriptor object with id/name/version/status/signed fields. Use kapua-device-inventory-bundles-list to discover bundles"`
 }
 
-func (h *KapuaHandler) HandleDeviceInventoryBundleStart(ctx context.Context, req *mcp.CallToolRequest, params *DeviceInventoryBundleActionParams) (*mcp.CallToolResult, any, error) {
-	if params == nil || params.DeviceID == "" {
-		return nil, nil, fmt.Errorf("deviceId is required")
+func (h *KapuaHandler) HandleDeviceInventoryBundleStart(ctx context.Context, req *mcp.CallToolRequest, params *DeviceInventoryBundleActionParams) (*mcp.CallToolResult, *OperationResult, error) {
+	if params == nil {
+		return nil, nil, fmt.Errorf("deviceId or clientId is required")
+	}
+	if err := h.resolveDevice(ctx, &params.DeviceRef); err != nil {
+		return nil, nil, err
 	}
-	h.logger.Info("Starting inventory bundle scan for device %s", params.DeviceID)
+	h.logger.WithContext(ctx).Info("Starting inventory bundle scan for device %s", params.DeviceID)
 	if err := h.client.StartDeviceInventoryBundle(ctx, params.DeviceID, params.Bundle); err != nil {
 		return nil, nil, fmt.Errorf("failed to start device inventory bundle: %w", err)
 	}
-	return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "Bundle inventory start requested"}}}, map[string]string{"status": "requested"}, nil
+	return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "Bundle inventory start requested"}}}, &OperationResult{Status: OperationRequested, DeviceID: params.DeviceID}, nil
 }
 
-func (h *KapuaHandler) HandleDeviceInventoryBundleStop(ctx context.Context, req *mcp.CallToolRequest, params *DeviceInventoryBundleActionParams) (*mcp.CallToolResult, any, error) {
-	if params == nil || params.DeviceID == "" {
-		return nil, nil, fmt.Errorf("deviceId is required")
+func (h *KapuaHandler) HandleDeviceInventoryBundleStop(ctx context.Context, req *mcp.CallToolRequest, params *DeviceInventoryBundleActionParams) (*mcp.CallToolResult, *OperationResult, error) {
+	if params == nil {
+		return nil, nil, fmt.Errorf("deviceId or clientId is required")
 	}
-	h.logger.Info("Stopping inventory bundle scan for device %s", params.DeviceID)
+	if err := h.resolveDevice(ctx, &params.DeviceRef); err != nil {
+		return nil, nil, err
+	}
+	h.logger.WithContext(ctx).Info("Stopping inventory bundle scan for device %s", params.DeviceID)
 	if err := h.client.StopDeviceInventoryBundle(ctx, params.DeviceID, params.Bundle); err != nil {
 		return nil, nil, fmt.Errorf("failed to stop device inventory bundle: %w", err)
 	}
-	return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "Bundle inventory stop requested"}}}, map[string]string{"status": "requested"}, nil
+	return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "Bundle inventory stop requested"}}}, &OperationResult{Status: OperationRequested, DeviceID: params.DeviceID}, nil
 }
 
-func (h *KapuaHandler) HandleDeviceInventoryContainers(ctx context.Context, req *mcp.CallToolRequest, params *DeviceInventoryParams) (*mcp.CallToolResult, any, error) {
-	if params == nil || params.DeviceID == "" {
-		return nil, nil, fmt.Errorf("deviceId is required")
+func (h *KapuaHandler) HandleDeviceInventoryContainers(ctx context.Context, req *mcp.CallToolRequest, params *DeviceInventoryParams) (*mcp.CallToolResult, *models.DeviceInventoryContainers, error) {
+	if params == nil {
+		return nil, nil, fmt.Errorf("deviceId or clientId is required")
+	}
+	if err := h.resolveDevice(ctx, &params.DeviceRef); err != nil {
+		return nil, nil, err
 	}
-	h.logger.Info("Listing inventory containers for device %s", params.DeviceID)
-	inv, err := h.client.ListDeviceInventoryContainers(ctx, params.DeviceID)
+	h.logger.WithContext(ctx).Info("Listing inventory containers for device %s", params.DeviceID)
+	inv, err := h.client.ListDeviceInventoryContainers(params.cacheContext(ctx), params.DeviceID)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to list device inventory containers: %w", err)
 	}
@@ -84,38 +100,47 @@ func (h *KapuaHandler) HandleDeviceInventoryContainers(ctx context.Context, req
 }
 
 type DeviceInventoryContainerActionParams struct {
-	DeviceID  string                          `json:"deviceId" jsonschema:"The Kapua device ID (required)"`
+	DeviceRef
 	Container models.DeviceInventoryContainer `json:"container" jsonschema:"Container descriptor object with name/version/containerType/state fields. Use kapua-device-inventory-containers-list to discover containers"`
 }
 
-func (h *KapuaHandler) HandleDeviceInventoryContainerStart(ctx context.Context, req *mcp.CallToolRequest, params *DeviceInventoryContainerActionParams) (*mcp.CallToolResult, any, error) {
-	if params == nil || params.DeviceID == "" {
-		return nil, nil, fmt.Errorf("deviceId is required")
+func (h *KapuaHandler) HandleDeviceInventoryContainerStart(ctx context.Context, req *mcp.CallToolRequest, params *DeviceInventoryContainerActionParams) (*mcp.CallToolResult, *OperationResult, error) {
+	if params == nil {
+		return nil, nil, fmt.Errorf("deviceId or clientId is required")
 	}
-	h.logger.Info("Starting inventory container scan for device %s", params.DeviceID)
+	if err := h.resolveDevice(ctx, &params.DeviceRef); err != nil {
+		return nil, nil, err
+	}
+	h.logger.WithContext(ctx).Info("Starting inventory container scan for device %s", params.DeviceID)
 	if err := h.client.StartDeviceInventoryContainer(ctx, params.DeviceID, params.Container); err != nil {
 		return nil, nil, fmt.Errorf("failed to start device inventory container: %w", err)
 	}
-	return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "Container inventory start requested"}}}, map[string]string{"status": "requested"}, nil
+	return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "Container inventory start requested"}}}, &OperationResult{Status: OperationRequested, DeviceID: params.DeviceID}, nil
 }
 
-func (h *KapuaHandler) HandleDeviceInventoryContainerStop(ctx context.Context, req *mcp.CallToolRequest, params *DeviceInventoryContainerActionParams) (*mcp.CallToolResult, any, error) {
-	if params == nil || params.DeviceID == "" {
-		return nil, nil, fmt.Errorf("deviceId is required")
+func (h *KapuaHandler) HandleDeviceInventoryContainerStop(ctx context.Context, req *mcp.CallToolRequest, params *DeviceInventoryContainerActionParams) (*mcp.CallToolResult, *OperationResult, error) {
+	if params == nil {
+		return nil, nil, fmt.Errorf("deviceId or clientId is required")
+	}
+	if err := h.resolveDevice(ctx, &params.DeviceRef); err != nil {
+		return nil, nil, err
 	}
-	h.logger.Info("Stopping inventory container scan for device %s", params.DeviceID)
+	h.logger.WithContext(ctx).Info("Stopping inventory container scan for device %s", params.DeviceID)
 	if err := h.client.StopDeviceInventoryContainer(ctx, params.DeviceID, params.Container); err != nil {
 		return nil, nil, fmt.Errorf("failed to stop device inventory container: %w", err)
 	}
-	return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "Container inventory stop requested"}}}, map[string]string{"status": "requested"}, nil
+	return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "Container inventory stop requested"}}}, &OperationResult{Status: OperationRequested, DeviceID: params.DeviceID}, nil
 }
 
-func (h *KapuaHandler) HandleDeviceInventorySystemPackages(ctx context.Context, req *mcp.CallToolRequest, params *DeviceInventoryParams) (*mcp.CallToolResult, any, error) {
-	if params == nil || params.DeviceID == "" {
-		return nil, nil, fmt.Errorf("deviceId is required")
+func (h *KapuaHandler) HandleDeviceInventorySystemPackages(ctx context.Context, req *mcp.CallToolRequest, params *DeviceInventoryParams) (*mcp.CallToolResult, *models.DeviceInventoryPackages, error) {
+	if params == nil {
+		return nil, nil, fmt.Errorf("deviceId or clientId is required")
 	}
-	h.logger.Info("Listing system packages for device %s", params.DeviceID)
-	inv, err := h.client.ListDeviceInventorySystemPackages(ctx, params.DeviceID)
+	if err := h.resolveDevice(ctx, &params.DeviceRef); err != nil {
+		return nil, nil, err
+	}
+	h.logger.WithContext(ctx).Info("Listing system packages for device %s", params.DeviceID)
+	inv, err := h.client.ListDeviceInventorySystemPackages(params.cacheContext(ctx), params.DeviceID)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to list device system packages: %w", err)
 	}
@@ -124,12 +149,15 @@ func (h *KapuaHandler) HandleDeviceInventorySystemPackages(ctx context.Context,
 	return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: summary}, &mcp.TextContent{Text: string(bytes)}}}, inv, nil
 }
 
-func (h *KapuaHandler) HandleDeviceInventoryDeploymentPackages(ctx context.Context, req *mcp.CallToolRequest, params *DeviceInventoryParams) (*mcp.CallToolResult, any, error) {
-	if params == nil || params.DeviceID == "" {
-		return nil, nil, fmt.Errorf("deviceId is required")
+func (h *KapuaHandler) HandleDeviceInventoryDeploymentPackages(ctx context.Context, req *mcp.CallToolRequest, params *DeviceInventoryParams) (*mcp.CallToolResult, *models.DeviceInventoryDeploymentPackages, error) {
+	if params == nil {
+		return nil, nil, fmt.Errorf("deviceId or clientId is required")
+	}
+	if err := h.resolveDevice(ctx, &params.DeviceRef); err != nil {
+		return nil, nil, err
 	}
-	h.logger.Info("Listing deployment packages for device %s", params.DeviceID)
-	inv, err := h.client.ListDeviceInventoryDeploymentPackages(ctx, params.DeviceID)
+	h.logger.WithContext(ctx).Info("Listing deployment packages for device %s", params.DeviceID)
+	inv, err := h.client.ListDeviceInventoryDeploymentPackages(params.cacheContext(ctx), params.DeviceID)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to list device deployment packages: %w", err)
 	}
@@ -11,7 +11,7 @@ import (
 )
 
 func TestHandleDeviceInventoryReadSuccess(t *testing.T) {
-	handler := newDeviceHandler(t, func(w http.ResponseWriter, r *http.Request) {
+	handler := newDeviceHandler(t, withoutDeviceMatches(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != "/v1/tenant/devices/device-1/inventory" {
 			t.Fatalf("unexpected path %s", r.URL.Path)
 		}
@@ -19,9 +19,9 @@ func TestHandleDeviceInventoryReadSuccess(t *testing.T) {
 		data, _ := json.Marshal(payload)
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write(data)
-	})
+	}))
 
-	params := &DeviceInventoryParams{DeviceID: "device-1"}
+	params := &DeviceInventoryParams{DeviceRef: DeviceRef{DeviceID: "device-1"}}
 	result, out, err := handler.HandleDeviceInventoryRead(context.Background(), nil, params)
 	if err != nil {
 		t.Fatalf("HandleDeviceInventoryRead returned error: %v", err)
@@ -57,14 +57,14 @@ func TestHandleDeviceInventoryReadError(t *testing.T) {
 		_, _ = w.Write([]byte("kapua error"))
 	})
 
-	_, _, err := handler.HandleDeviceInventoryRead(context.Background(), nil, &DeviceInventoryParams{DeviceID: "device-1"})
+	_, _, err := handler.HandleDeviceInventoryRead(context.Background(), nil, &DeviceInventoryParams{DeviceRef: DeviceRef{DeviceID: "device-1"}})
 	if err == nil || !strings.Contains(err.Error(), "failed to read device inventory") {
 		t.Fatalf("expected wrapped error, got %v", err)
 	}
 }
 
 func TestHandleDeviceInventoryBundleStart(t *testing.T) {
-	handler := newDeviceHandler(t, func(w http.ResponseWriter, r *http.Request) {
+	handler := newDeviceHandler(t, withoutDeviceMatches(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			t.Fatalf("expected POST, got %s", r.Method)
 		}
@@ -79,9 +79,9 @@ func TestHandleDeviceInventoryBundleStart(t *testing.T) {
 			t.Fatalf("unexpected bundle payload: %+v", payload)
 		}
 		w.WriteHeader(http.StatusNoContent)
-	})
+	}))
 
-	params := &DeviceInventoryBundleActionParams{DeviceID: "device-1", Bundle: models.DeviceInventoryBundle{ID: "bundle-1"}}
+	params := &DeviceInventoryBundleActionParams{DeviceRef: DeviceRef{DeviceID: "device-1"}, Bundle: models.DeviceInventoryBundle{ID: "bundle-1"}}
 	result, meta, err := handler.HandleDeviceInventoryBundleStart(context.Background(), nil, params)
 	if err != nil {
 		t.Fatalf("HandleDeviceInventoryBundleStart returned error: %v", err)
@@ -89,8 +89,7 @@ func TestHandleDeviceInventoryBundleStart(t *testing.T) {
 	if textContent(t, result.Content[0]) != "Bundle inventory start requested" {
 		t.Fatalf("unexpected content: %s", textContent(t, result.Content[0]))
 	}
-	status, ok := meta.(map[string]string)
-	if !ok || status["status"] != "requested" {
+	if meta == nil || meta.Status != OperationRequested {
 		t.Fatalf("unexpected metadata: %+v", meta)
 	}
 }
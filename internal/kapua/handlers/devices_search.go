@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"kapua-mcp-server/internal/kapua/models"
+)
+
+const defaultSearchLimit = 50
+
+// errFleetIndexLoading is returned by index-only tools before the first refresh.
+var errFleetIndexLoading = errors.New("the fleet index is still loading; retry shortly or use kapua-devices-list")
+
+// SearchDevicesParams defines the filters of kapua-devices-search. All filters must match.
+type SearchDevicesParams struct {
+	Query             string                  `json:"query,omitempty" jsonschema:"Case-insensitive text matched against device ID, client ID, display name, serial number and model"`
+	ClientID          string                  `json:"clientId,omitempty" jsonschema:"Exact client ID"`
+	ConnectionStatus  models.ConnectionStatus `json:"status,omitempty" jsonschema:"Connection status (CONNECTED/DISCONNECTED/MISSING/NULL)"`
+	FirmwareVersion   string                  `json:"firmwareVersion,omitempty" jsonschema:"Exact firmware version"`
+	TagID             string                  `json:"tagId,omitempty" jsonschema:"ID of a Kapua tag assigned to the device"`
+	SeenWithinMinutes int                     `json:"seenWithinMinutes,omitempty" jsonschema:"Only devices seen in the last N minutes"`
+	NotSeenForMinutes int                     `json:"notSeenForMinutes,omitempty" jsonschema:"Only devices not seen for at least N minutes, including devices never seen"`
+	Limit             int                     `json:"limit,omitempty" jsonschema:"Maximum number of devices to return (default: 50)"`
+}
+
+type DeviceSearchResult struct {
+	AsOf         string          `json:"asOf"`
+	TotalMatches int             `json:"totalMatches"`
+	Devices      []DeviceSummary `json:"devices"`
+}
+
+type DeviceSummary struct {
+	ID              string                  `json:"id"`
+	ClientID        string                  `json:"clientId,omitempty"`
+	DisplayName     string                  `json:"displayName,omitempty"`
+	Status          models.ConnectionStatus `json:"status,omitempty"`
+	FirmwareVersion string                  `json:"firmwareVersion,omitempty"`
+	ModelName       string                  `json:"modelName,omitempty"`
+	TagIDs          []models.KapuaID        `json:"tagIds,omitempty"`
+	LastSeen        string                  `json:"lastSeen,omitempty"`
+	CriticalEvents  int                     `json:"criticalEvents,omitempty"`
+}
+
+// HandleSearchDevices answers device searches from the fleet index without calling Kapua.
+func (h *KapuaHandler) HandleSearchDevices(ctx context.Context, req *mcp.CallToolRequest, params *SearchDevicesParams) (*mcp.CallToolResult, *DeviceSearchResult, error) {
+	snapshot := h.fleetIndex.current()
+	if snapshot == nil {
+		return nil, nil, errFleetIndexLoading
+	}
+	if params == nil {
+		params = &SearchDevicesParams{}
+	}
+	limit := params.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+
+	matches := snapshot.search(params, timeNow())
+	result := DeviceSearchResult{
+		AsOf:         snapshot.updatedAt.UTC().Format(time.RFC3339),
+		TotalMatches: len(matches),
+		Devices:      []DeviceSummary{},
+	}
+	for _, entry := range matches[:min(limit, len(matches))] {
+		summary := DeviceSummary{
+			ID:              string(entry.device.ID),
+			ClientID:        entry.device.ClientID,
+			DisplayName:     entry.device.DisplayName,
+			Status:          entry.status,
+			FirmwareVersion: entry.device.FirmwareVersion,
+			ModelName:       entry.device.ModelName,
+			TagIDs:          entry.device.TagIDs,
+			CriticalEvents:  len(entry.criticalEvents),
+		}
+		if !entry.lastSeen.IsZero() {
+			summary.LastSeen = entry.lastSeen.UTC().Format(time.RFC3339)
+		}
+		result.Devices = append(result.Devices, summary)
+	}
+	h.logger.WithContext(ctx).Info("Device search matched %d devices in the fleet index", len(matches))
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal response: %w", err)
+	}
+	summary := fmt.Sprintf("Found %d devices (fleet index as of %s).", len(matches), result.AsOf)
+	if len(matches) > limit {
+		summary += fmt.Sprintf(" Showing the first %d; narrow the filters or raise limit.", limit)
+	}
+	return &mcp.CallToolResult{Content: []mcp.Content{
+		&mcp.TextContent{Text: summary},
+		&mcp.TextContent{Text: string(jsonData)},
+	}}, &result, nil
+}
+
+// search returns the indexed devices matching every filter in params, in Kapua order.
+func (s *fleetSnapshot) search(params *SearchDevicesParams, now time.Time) []*indexedDevice {
+	// Start from the narrowest index that applies, then check every filter.
+	candidates := s.devices
+	switch {
+	case params.ClientID != "":
+		candidates = nil
+		if entry, ok := s.byClientID[params.ClientID]; ok {
+			candidates = []*indexedDevice{entry}
+		}
+	case params.TagID != "":
+		candidates = s.byTag[params.TagID]
+	case params.FirmwareVersion != "":
+		candidates = s.byFirmware[params.FirmwareVersion]
+	case params.ConnectionStatus != "":
+		candidates = s.byStatus[params.ConnectionStatus]
+	}
+
+	query := strings.ToLower(params.Query)
+	var matches []*indexedDevice
+	for _, entry := range candidates {
+		device := entry.device
+		switch {
+		case params.ClientID != "" && device.ClientID != params.ClientID,
+			params.ConnectionStatus != "" && entry.status != params.ConnectionStatus,
+			params.FirmwareVersion != "" && device.FirmwareVersion != params.FirmwareVersion,
+			params.TagID != "" && !hasTag(device, params.TagID),
+			params.SeenWithinMinutes > 0 && (entry.lastSeen.IsZero() || entry.lastSeen.Before(now.Add(-time.Duration(params.SeenWithinMinutes)*time.Minute))),
+			params.NotSeenForMinutes > 0 && !entry.lastSeen.IsZero() && entry.lastSeen.After(now.Add(-time.Duration(params.NotSeenForMinutes)*time.Minute)),
+			query != "" && !matchesQuery(device, query):
+			continue
+		}
+		matches = append(matches, entry)
+	}
+	return matches
+}
+
+func hasTag(device models.Device, tagID string) bool {
+	for _, tag := range device.TagIDs {
+		if string(tag) == tagID {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesQuery(device models.Device, query string) bool {
+	for _, field := range []string{string(device.ID), device.ClientID, device.DisplayName, device.SerialNumber, device.ModelID, device.ModelName} {
+		if strings.Contains(strings.ToLower(field), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// devicesResourceFromIndex builds the kapua://devices content from snapshot.
+func devicesResourceFromIndex(snapshot *fleetSnapshot, limit int) map[string]interface{} {
+	if limit <= 0 {
+		limit = deviceResourceDefaultLimit
+	}
+	entries := snapshot.devices[:min(limit, len(snapshot.devices))]
+	devices := make([]models.Device, 0, len(entries))
+	for _, entry := range entries {
+		devices = append(devices, entry.device)
+	}
+	return map[string]interface{}{
+		"total_count":     snapshot.totalCount,
+		"processed_count": len(devices),
+		"devices":         devices,
+		"last_updated":    fmt.Sprintf("%d", snapshot.updatedAt.Unix()),
+		"data_as_of":      snapshot.updatedAt.UTC().Format(time.RFC3339),
+		"source":          "fleet-index",
+	}
+}
+
+// fleetHealthFromIndex builds a fleet health report from snapshot. The caller must
+// make sure the critical lookback fits in fleetIndexEventWindow.
+func fleetHealthFromIndex(snapshot *fleetSnapshot, cfg fleetHealthConfig) fleetHealthReport {
+	now := timeNow()
+	cutoff := now.Add(-time.Duration(cfg.staleMinutes) * time.Minute)
+	criticalSince := now.Add(-time.Duration(cfg.criticalMinutes) * time.Minute)
+	entries := snapshot.devices[:min(cfg.deviceLimit, len(snapshot.devices))]
+
+	report := fleetHealthReport{
+		GeneratedAt:             now.UTC().Format(time.RFC3339),
+		DataAsOf:                snapshot.updatedAt.UTC().Format(time.RFC3339),
+		TotalDevices:            snapshot.totalCount,
+		ProcessedDevices:        len(entries),
+		StaleSinceMinutes:       cfg.staleMinutes,
+		CriticalLookbackMinutes: cfg.criticalMinutes,
+		Warnings:                append([]string(nil), snapshot.warnings...),
+	}
+	for _, entry := range entries {
+		switch entry.status {
+		case models.ConnectionStatusConnected:
+			report.Online++
+		case models.ConnectionStatusDisconnected, models.ConnectionStatusMissing, models.ConnectionStatusNull:
+			report.Offline++
+		default:
+			report.Unknown++
+		}
+		if !entry.lastSeen.IsZero() && entry.lastSeen.Before(cutoff) {
+			report.StaleDevices = append(report.StaleDevices, staleDevice{
+				ID:             string(entry.device.ID),
+				ClientID:       entry.device.ClientID,
+				Status:         entry.status,
+				LastSeen:       entry.lastSeen.UTC().Format(time.RFC3339),
+				LastSeenSource: entry.lastSeenSource,
+			})
+		}
+		if events := eventsSince(entry.criticalEvents, criticalSince); len(events) > 0 {
+			report.DevicesWithCriticalEvents = append(report.DevicesWithCriticalEvents, criticalDevice{
+				ID:       string(entry.device.ID),
+				ClientID: entry.device.ClientID,
+				Status:   entry.status,
+				Events:   events,
+			})
+		}
+	}
+	if snapshot.totalCount > len(entries) {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("processed %d of %d devices; increase limit to inspect full fleet", len(entries), snapshot.totalCount))
+	}
+	return report
+}
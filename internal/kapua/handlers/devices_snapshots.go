@@ -6,25 +6,37 @@ import (
 	"fmt"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"kapua-mcp-server/internal/kapua/models"
 )
 
 type DeviceSnapshotsParams struct {
-	DeviceID string `json:"deviceId" jsonschema:"The Kapua device ID (required)"`
+	DeviceRef
+	CacheControl
 }
 
 type DeviceSnapshotLookupParams struct {
-	DeviceID   string `json:"deviceId" jsonschema:"The Kapua device ID (required)"`
+	DeviceRef
 	SnapshotID string `json:"snapshotId" jsonschema:"The snapshot ID to read or rollback to (required). Use kapua-device-snapshots-list to discover available IDs"`
 }
 
+type DeviceSnapshotReadParams struct {
+	DeviceRef
+	SnapshotID string `json:"snapshotId" jsonschema:"The snapshot ID to read (required). Use kapua-device-snapshots-list to discover available IDs"`
+	CacheControl
+}
+
 // HandleDeviceSnapshotsList lists available snapshots for a device and returns both
 // a quick summary and the raw Kapua payload to the MCP client.
-func (h *KapuaHandler) HandleDeviceSnapshotsList(ctx context.Context, req *mcp.CallToolRequest, params *DeviceSnapshotsParams) (*mcp.CallToolResult, any, error) {
-	if params == nil || params.DeviceID == "" {
-		return nil, nil, fmt.Errorf("deviceId is required")
+func (h *KapuaHandler) HandleDeviceSnapshotsList(ctx context.Context, req *mcp.CallToolRequest, params *DeviceSnapshotsParams) (*mcp.CallToolResult, *models.DeviceSnapshots, error) {
+	if params == nil {
+		return nil, nil, fmt.Errorf("deviceId or clientId is required")
 	}
-	h.logger.Info("Listing snapshots for device %s", params.DeviceID)
-	snapshots, err := h.client.ListDeviceSnapshots(ctx, params.DeviceID)
+	if err := h.resolveDevice(ctx, &params.DeviceRef); err != nil {
+		return nil, nil, err
+	}
+	h.logger.WithContext(ctx).Info("Listing snapshots for device %s", params.DeviceID)
+	snapshots, err := h.client.ListDeviceSnapshots(params.cacheContext(ctx), params.DeviceID)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to list device snapshots: %w", err)
 	}
@@ -41,15 +53,18 @@ func (h *KapuaHandler) HandleDeviceSnapshotsList(ctx context.Context, req *mcp.C
 }
 
 // HandleDeviceSnapshotConfigurationsRead returns the configuration payload for a given snapshot.
-func (h *KapuaHandler) HandleDeviceSnapshotConfigurationsRead(ctx context.Context, req *mcp.CallToolRequest, params *DeviceSnapshotLookupParams) (*mcp.CallToolResult, any, error) {
-	if params == nil || params.DeviceID == "" {
-		return nil, nil, fmt.Errorf("deviceId is required")
+func (h *KapuaHandler) HandleDeviceSnapshotConfigurationsRead(ctx context.Context, req *mcp.CallToolRequest, params *DeviceSnapshotReadParams) (*mcp.CallToolResult, *models.DeviceConfiguration, error) {
+	if params == nil {
+		return nil, nil, fmt.Errorf("deviceId or clientId is required")
 	}
 	if params.SnapshotID == "" {
 		return nil, nil, fmt.Errorf("snapshotId is required")
 	}
-	h.logger.Info("Reading snapshot %s for device %s", params.SnapshotID, params.DeviceID)
-	conf, err := h.client.ReadDeviceSnapshotConfigurations(ctx, params.DeviceID, params.SnapshotID)
+	if err := h.resolveDevice(ctx, &params.DeviceRef); err != nil {
+		return nil, nil, err
+	}
+	h.logger.WithContext(ctx).Info("Reading snapshot %s for device %s", params.SnapshotID, params.DeviceID)
+	conf, err := h.client.ReadDeviceSnapshotConfigurations(params.cacheContext(ctx), params.DeviceID, params.SnapshotID)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to read device snapshot configurations: %w", err)
 	}
@@ -66,19 +81,22 @@ func (h *KapuaHandler) HandleDeviceSnapshotConfigurationsRead(ctx context.Contex
 }
 
 // HandleDeviceSnapshotRollback triggers a rollback to the provided snapshot on the device.
-func (h *KapuaHandler) HandleDeviceSnapshotRollback(ctx context.Context, req *mcp.CallToolRequest, params *DeviceSnapshotLookupParams) (*mcp.CallToolResult, any, error) {
-	if params == nil || params.DeviceID == "" {
-		return nil, nil, fmt.Errorf("deviceId is required")
+func (h *KapuaHandler) HandleDeviceSnapshotRollback(ctx context.Context, req *mcp.CallToolRequest, params *DeviceSnapshotLookupParams) (*mcp.CallToolResult, *OperationResult, error) {
+	if params == nil {
+		return nil, nil, fmt.Errorf("deviceId or clientId is required")
 	}
 	if params.SnapshotID == "" {
 		return nil, nil, fmt.Errorf("snapshotId is required")
 	}
-	h.logger.Info("Requesting rollback of device %s to snapshot %s", params.DeviceID, params.SnapshotID)
+	if err := h.resolveDevice(ctx, &params.DeviceRef); err != nil {
+		return nil, nil, err
+	}
+	h.logger.WithContext(ctx).Info("Requesting rollback of device %s to snapshot %s", params.DeviceID, params.SnapshotID)
 	if err := h.client.RollbackDeviceSnapshot(ctx, params.DeviceID, params.SnapshotID); err != nil {
 		return nil, nil, fmt.Errorf("failed to rollback device snapshot: %w", err)
 	}
 	summary := fmt.Sprintf("Rollback to snapshot %s requested for device %s", params.SnapshotID, params.DeviceID)
 	return &mcp.CallToolResult{Content: []mcp.Content{
 		&mcp.TextContent{Text: summary},
-	}}, map[string]string{"status": "requested"}, nil
+	}}, &OperationResult{Status: OperationRequested, DeviceID: params.DeviceID}, nil
 }
@@ -11,7 +11,7 @@ import (
 )
 
 func TestHandleDeviceSnapshotsListSuccess(t *testing.T) {
-	handler := newDeviceHandler(t, func(w http.ResponseWriter, r *http.Request) {
+	handler := newDeviceHandler(t, withoutDeviceMatches(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != "/v1/tenant/devices/device-1/snapshots" {
 			t.Fatalf("unexpected path %s", r.URL.Path)
 		}
@@ -19,9 +19,9 @@ func TestHandleDeviceSnapshotsListSuccess(t *testing.T) {
 		data, _ := json.Marshal(payload)
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write(data)
-	})
+	}))
 
-	params := &DeviceSnapshotsParams{DeviceID: "device-1"}
+	params := &DeviceSnapshotsParams{DeviceRef: DeviceRef{DeviceID: "device-1"}}
 	result, out, err := handler.HandleDeviceSnapshotsList(context.Background(), nil, params)
 	if err != nil {
 		t.Fatalf("HandleDeviceSnapshotsList returned error: %v", err)
@@ -57,22 +57,22 @@ func TestHandleDeviceSnapshotsListError(t *testing.T) {
 		_, _ = w.Write([]byte("kapua error"))
 	})
 
-	_, _, err := handler.HandleDeviceSnapshotsList(context.Background(), nil, &DeviceSnapshotsParams{DeviceID: "device-1"})
+	_, _, err := handler.HandleDeviceSnapshotsList(context.Background(), nil, &DeviceSnapshotsParams{DeviceRef: DeviceRef{DeviceID: "device-1"}})
 	if err == nil || !strings.Contains(err.Error(), "failed to list device snapshots") {
 		t.Fatalf("expected wrapped error, got %v", err)
 	}
 }
 
 func TestHandleDeviceSnapshotConfigurationsReadSuccess(t *testing.T) {
-	handler := newDeviceHandler(t, func(w http.ResponseWriter, r *http.Request) {
+	handler := newDeviceHandler(t, withoutDeviceMatches(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != "/v1/tenant/devices/device-1/snapshots/snap-1" {
 			t.Fatalf("unexpected path %s", r.URL.Path)
 		}
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte(`{"configuration":[{"id":"component-1"}]}`))
-	})
+	}))
 
-	params := &DeviceSnapshotLookupParams{DeviceID: "device-1", SnapshotID: "snap-1"}
+	params := &DeviceSnapshotReadParams{DeviceRef: DeviceRef{DeviceID: "device-1"}, SnapshotID: "snap-1"}
 	result, out, err := handler.HandleDeviceSnapshotConfigurationsRead(context.Background(), nil, params)
 	if err != nil {
 		t.Fatalf("HandleDeviceSnapshotConfigurationsRead returned error: %v", err)
@@ -93,10 +93,10 @@ func TestHandleDeviceSnapshotConfigurationsReadMissingParams(t *testing.T) {
 	if _, _, err := handler.HandleDeviceSnapshotConfigurationsRead(context.Background(), nil, nil); err == nil {
 		t.Fatal("expected error for nil params")
 	}
-	if _, _, err := handler.HandleDeviceSnapshotConfigurationsRead(context.Background(), nil, &DeviceSnapshotLookupParams{SnapshotID: "snap-1"}); err == nil {
+	if _, _, err := handler.HandleDeviceSnapshotConfigurationsRead(context.Background(), nil, &DeviceSnapshotReadParams{SnapshotID: "snap-1"}); err == nil {
 		t.Fatal("expected error for missing deviceId")
 	}
-	if _, _, err := handler.HandleDeviceSnapshotConfigurationsRead(context.Background(), nil, &DeviceSnapshotLookupParams{DeviceID: "device-1"}); err == nil {
+	if _, _, err := handler.HandleDeviceSnapshotConfigurationsRead(context.Background(), nil, &DeviceSnapshotReadParams{DeviceRef: DeviceRef{DeviceID: "device-1"}}); err == nil {
 		t.Fatal("expected error for missing snapshotId")
 	}
 }
@@ -107,14 +107,14 @@ func TestHandleDeviceSnapshotConfigurationsReadError(t *testing.T) {
 		_, _ = w.Write([]byte("kapua error"))
 	})
 
-	_, _, err := handler.HandleDeviceSnapshotConfigurationsRead(context.Background(), nil, &DeviceSnapshotLookupParams{DeviceID: "device-1", SnapshotID: "snap-1"})
+	_, _, err := handler.HandleDeviceSnapshotConfigurationsRead(context.Background(), nil, &DeviceSnapshotReadParams{DeviceRef: DeviceRef{DeviceID: "device-1"}, SnapshotID: "snap-1"})
 	if err == nil || !strings.Contains(err.Error(), "failed to read device snapshot configurations") {
 		t.Fatalf("expected wrapped error, got %v", err)
 	}
 }
 
 func TestHandleDeviceSnapshotRollbackSuccess(t *testing.T) {
-	handler := newDeviceHandler(t, func(w http.ResponseWriter, r *http.Request) {
+	handler := newDeviceHandler(t, withoutDeviceMatches(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			t.Fatalf("expected POST, got %s", r.Method)
 		}
@@ -122,9 +122,9 @@ func TestHandleDeviceSnapshotRollbackSuccess(t *testing.T) {
 			t.Fatalf("unexpected path %s", r.URL.Path)
 		}
 		w.WriteHeader(http.StatusNoContent)
-	})
+	}))
 
-	params := &DeviceSnapshotLookupParams{DeviceID: "device-1", SnapshotID: "snap-1"}
+	params := &DeviceSnapshotLookupParams{DeviceRef: DeviceRef{DeviceID: "device-1"}, SnapshotID: "snap-1"}
 	result, meta, err := handler.HandleDeviceSnapshotRollback(context.Background(), nil, params)
 	if err != nil {
 		t.Fatalf("HandleDeviceSnapshotRollback returned error: %v", err)
@@ -135,8 +135,7 @@ func TestHandleDeviceSnapshotRollbackSuccess(t *testing.T) {
 	if summary := textContent(t, result.Content[0]); summary != "Rollback to snapshot snap-1 requested for device device-1" {
 		t.Fatalf("unexpected summary: %s", summary)
 	}
-	metaMap, ok := meta.(map[string]string)
-	if !ok || metaMap["status"] != "requested" {
+	if meta == nil || meta.Status != OperationRequested || meta.DeviceID == "" {
 		t.Fatalf("unexpected metadata: %+v", meta)
 	}
 }
@@ -149,7 +148,7 @@ func TestHandleDeviceSnapshotRollbackMissingParams(t *testing.T) {
 	if _, _, err := handler.HandleDeviceSnapshotRollback(context.Background(), nil, &DeviceSnapshotLookupParams{SnapshotID: "snap-1"}); err == nil {
 		t.Fatal("expected error for missing deviceId")
 	}
-	if _, _, err := handler.HandleDeviceSnapshotRollback(context.Background(), nil, &DeviceSnapshotLookupParams{DeviceID: "device-1"}); err == nil {
+	if _, _, err := handler.HandleDeviceSnapshotRollback(context.Background(), nil, &DeviceSnapshotLookupParams{DeviceRef: DeviceRef{DeviceID: "device-1"}}); err == nil {
 		t.Fatal("expected error for missing snapshotId")
 	}
 }
@@ -160,7 +159,7 @@ func TestHandleDeviceSnapshotRollbackError(t *testing.T) {
 		_, _ = w.Write([]byte("kapua error"))
 	})
 
-	_, _, err := handler.HandleDeviceSnapshotRollback(context.Background(), nil, &DeviceSnapshotLookupParams{DeviceID: "device-1", SnapshotID: "snap-1"})
+	_, _, err := handler.HandleDeviceSnapshotRollback(context.Background(), nil, &DeviceSnapshotLookupParams{DeviceRef: DeviceRef{DeviceID: "device-1"}, SnapshotID: "snap-1"})
 	if err == nil || !strings.Contains(err.Error(), "failed to rollback device snapshot") {
 		t.Fatalf("expected wrapped error, got %v", err)
 	}
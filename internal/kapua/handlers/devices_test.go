@@ -5,11 +5,16 @@ import (
 	"encoding/json"
 	"net/http"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 
+	"kapua-mcp-server/internal/kapua/config"
 	"kapua-mcp-server/internal/kapua/models"
+	"kapua-mcp-server/internal/kapua/services"
+	"kapua-mcp-server/pkg/utils"
 )
 
 func newDeviceHandler(t *testing.T, fn http.HandlerFunc) *KapuaHandler {
@@ -150,3 +155,44 @@ func TestHandleListDevicesServiceError(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 }
+
+func TestDeviceReadsHonourNoCache(t *testing.T) {
+	var requests atomic.Int32
+	client := services.NewKapuaClient(&config.KapuaConfig{APIEndpoint: "http://kapua.test", Timeout: 5, CacheTTLDevices: time.Minute})
+	client.SetHTTPClient(&http.Client{Transport: handlerRoundTripper{handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		_, _ = w.Write([]byte(`{"items":[{"id":"device-1","clientId":"client-1"}],"size":1}`))
+	})}})
+	client.SetTokenInfo(&models.AccessToken{KapuaEntity: models.KapuaEntity{ScopeID: models.KapuaID("tenant")}})
+	handler := &KapuaHandler{client: client, logger: utils.NewDefaultLogger("KapuaDeviceHandlerTest")}
+	ctx := context.Background()
+
+	for range 2 {
+		if _, _, err := handler.HandleListDevices(ctx, nil, &ListDevicesParams{}); err != nil {
+			t.Fatalf("HandleListDevices returned error: %v", err)
+		}
+	}
+	if requests.Load() != 1 {
+		t.Fatalf("expected the second call to be served from cache, got %d requests", requests.Load())
+	}
+
+	if _, _, err := handler.HandleListDevices(ctx, nil, &ListDevicesParams{CacheControl: CacheControl{NoCache: true}}); err != nil {
+		t.Fatalf("HandleListDevices returned error: %v", err)
+	}
+	if requests.Load() != 2 {
+		t.Fatalf("expected noCache to reach Kapua, got %d requests", requests.Load())
+	}
+
+	if _, err := handler.ReadResource(ctx, "kapua://devices?limit=1"); err != nil {
+		t.Fatalf("ReadResource returned error: %v", err)
+	}
+	if _, err := handler.ReadResource(ctx, "kapua://devices?limit=1"); err != nil {
+		t.Fatalf("ReadResource returned error: %v", err)
+	}
+	if _, err := handler.ReadResource(ctx, "kapua://devices?limit=1&noCache=true"); err != nil {
+		t.Fatalf("ReadResource returned error: %v", err)
+	}
+	if requests.Load() != 4 {
+		t.Fatalf("expected one cached and one fresh resource read, got %d requests", requests.Load())
+	}
+}
@@ -11,8 +11,13 @@ import (
 	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"kapua-mcp-server/internal/kapua/models"
+	"kapua-mcp-server/internal/kapua/services"
+	"kapua-mcp-server/pkg/metrics"
+	"kapua-mcp-server/pkg/tracing"
 )
 
 const (
@@ -24,6 +29,11 @@ const (
 	maxCriticalEventsPerDevice = 5
 )
 
+var fleetHealthScanDuration = metrics.NewHistogramVec(
+	"kapua_fleet_health_scan_duration_seconds",
+	"Duration of kapua://fleet-health scans by result (success or error).",
+	[]float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}, "result")
+
 type fleetHealthConfig struct {
 	staleMinutes     int
 	criticalMinutes  int
@@ -33,6 +43,7 @@ type fleetHealthConfig struct {
 
 type fleetHealthReport struct {
 	GeneratedAt               string           `json:"generated_at"`
+	DataAsOf                  string           `json:"data_as_of"` // When the device data was fetched from Kapua
 	TotalDevices              int              `json:"total_devices"`
 	ProcessedDevices          int              `json:"processed_devices"`
 	Online                    int              `json:"online"`
@@ -45,6 +56,12 @@ type fleetHealthReport struct {
 	Warnings                  []string         `json:"warnings,omitempty"`
 }
 
+// eventTarget is a device whose recent events the scan checks.
+type eventTarget struct {
+	device models.Device
+	status models.ConnectionStatus
+}
+
 type staleDevice struct {
 	ID             string                  `json:"id"`
 	ClientID       string                  `json:"clientId,omitempty"`
@@ -60,9 +77,25 @@ type criticalDevice struct {
 	Events   []models.DeviceEvent    `json:"events,omitempty"`
 }
 
-func (h *KapuaHandler) readFleetHealthResource(ctx context.Context, uri *url.URL) (*mcp.ReadResourceResult, error) {
+func (h *KapuaHandler) readFleetHealthResource(ctx context.Context, uri *url.URL) (result *mcp.ReadResourceResult, err error) {
+	start := time.Now()
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		fleetHealthScanDuration.Observe(time.Since(start).Seconds(), status)
+	}()
+
+	// The scan issues one request per device; let interactive tool calls go first.
+	ctx = services.WithPriority(resourceCacheContext(ctx, uri), services.PriorityBulk)
 	cfg := parseFleetHealthConfig(uri)
-	h.logger.Info("Building fleet health report (stale>%d min, critical>%d min, limit=%d)", cfg.staleMinutes, cfg.criticalMinutes, cfg.deviceLimit)
+	if snapshot := h.fleetIndex.current(); snapshot != nil && !noCacheRequested(uri) &&
+		time.Duration(cfg.criticalMinutes)*time.Minute <= fleetIndexEventWindow {
+		h.logger.WithContext(ctx).Info("Building fleet health report from the fleet index (stale>%d min, critical>%d min, limit=%d)", cfg.staleMinutes, cfg.criticalMinutes, cfg.deviceLimit)
+		return fleetHealthResult(fleetHealthFromIndex(snapshot, cfg))
+	}
+	h.logger.WithContext(ctx).Info("Building fleet health report (stale>%d min, critical>%d min, limit=%d)", cfg.staleMinutes, cfg.criticalMinutes, cfg.deviceLimit)
 
 	var allDevices []models.Device
 	totalDevices := 0
@@ -106,10 +139,7 @@ func (h *KapuaHandler) readFleetHealthResource(ctx context.Context, uri *url.URL
 
 	online, offline, unknown := 0, 0, 0
 	var staleDevices []staleDevice
-	var eventTargets []struct {
-		device models.Device
-		status models.ConnectionStatus
-	}
+	var eventTargets []eventTarget
 
 	for _, device := range allDevices {
 		status := connectionStatus(device)
@@ -137,64 +167,60 @@ func (h *KapuaHandler) readFleetHealthResource(ctx context.Context, uri *url.URL
 			continue
 		}
 
-		eventTargets = append(eventTargets, struct {
-			device models.Device
-			status models.ConnectionStatus
-		}{device: device, status: status})
+		eventTargets = append(eventTargets, eventTarget{device: device, status: status})
 	}
 
 	var criticalDevices []criticalDevice
 	var warnings []string
 
-	var wg sync.WaitGroup
 	var mu sync.Mutex
-	sem := make(chan struct{}, cfg.eventConcurrency)
-
-	for _, target := range eventTargets {
-		target := target
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			sem <- struct{}{}
-			defer func() { <-sem }()
-
-			deviceID := string(target.device.ID)
-			eventsResult, err := h.client.ListDeviceEvents(ctx, deviceID, map[string]string{
-				"startDate": criticalSince.Format(time.RFC3339),
-				"limit":     "20",
-				"sortParam": "receivedOn",
-				"sortDir":   "DESCENDING",
-			})
-			if err != nil {
-				mu.Lock()
-				warnings = append(warnings, fmt.Sprintf("device %s: %v", labelForDevice(target.device), err))
-				mu.Unlock()
-				return
-			}
-
-			criticalEvents := filterCriticalEvents(eventsResult.Items)
-			if len(criticalEvents) == 0 {
-				return
-			}
-
-			if len(criticalEvents) > maxCriticalEventsPerDevice {
-				criticalEvents = criticalEvents[:maxCriticalEventsPerDevice]
-			}
-
+	processConcurrently(ctx, eventTargets, cfg.eventConcurrency, "Checked events of %d of %d devices", func(target eventTarget) {
+		deviceID := string(target.device.ID)
+		eventsCtx, span := tracing.Tracer().Start(ctx, "fleet-health device events",
+			trace.WithAttributes(attribute.String("kapua.device.id", deviceID)))
+		defer span.End()
+		eventsResult, err := h.client.ListDeviceEvents(eventsCtx, deviceID, map[string]string{
+			"startDate": criticalSince.Format(time.RFC3339),
+			"limit":     "20",
+			"sortParam": "receivedOn",
+			"sortDir":   "DESCENDING",
+		})
+		if err != nil {
+			tracing.RecordError(span, err)
 			mu.Lock()
-			criticalDevices = append(criticalDevices, criticalDevice{
-				ID:       deviceID,
-				ClientID: target.device.ClientID,
-				Status:   target.status,
-				Events:   criticalEvents,
-			})
+			warnings = append(warnings, fmt.Sprintf("device %s: %v", labelForDevice(target.device), err))
 			mu.Unlock()
-		}()
+			return
+		}
+
+		criticalEvents := filterCriticalEvents(eventsResult.Items)
+		if len(criticalEvents) == 0 {
+			return
+		}
+
+		if len(criticalEvents) > maxCriticalEventsPerDevice {
+			criticalEvents = criticalEvents[:maxCriticalEventsPerDevice]
+		}
+
+		mu.Lock()
+		criticalDevices = append(criticalDevices, criticalDevice{
+			ID:       deviceID,
+			ClientID: target.device.ClientID,
+			Status:   target.status,
+			Events:   criticalEvents,
+		})
+		mu.Unlock()
+	})
+
+	// A cancelled scan (client cancellation or server shutdown) returns no partial report.
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("fleet health scan cancelled: %w", err)
 	}
-	wg.Wait()
 
+	generatedAt := timeNow().UTC().Format(time.RFC3339)
 	report := fleetHealthReport{
-		GeneratedAt:               timeNow().UTC().Format(time.RFC3339),
+		GeneratedAt:               generatedAt,
+		DataAsOf:                  generatedAt,
 		TotalDevices:              totalDevices,
 		ProcessedDevices:          len(allDevices),
 		Online:                    online,
@@ -211,6 +237,10 @@ func (h *KapuaHandler) readFleetHealthResource(ctx context.Context, uri *url.URL
 		report.Warnings = append(report.Warnings, fmt.Sprintf("processed %d of %d devices; increase limit to inspect full fleet", len(allDevices), totalDevices))
 	}
 
+	return fleetHealthResult(report)
+}
+
+func fleetHealthResult(report fleetHealthReport) (*mcp.ReadResourceResult, error) {
 	jsonData, err := json.MarshalIndent(report, "", "  ")
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal fleet health resource: %w", err)
@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 	"testing"
@@ -84,10 +85,14 @@ func TestReadFleetHealthResource(t *testing.T) {
 		}
 	})
 
+	scans := fleetHealthScanDuration.Count("success")
 	result, err := handler.ReadResource(context.Background(), "kapua://fleet-health?staleMinutes=90&criticalMinutes=120&limit=5")
 	if err != nil {
 		t.Fatalf("ReadResource returned error: %v", err)
 	}
+	if fleetHealthScanDuration.Count("success") != scans+1 {
+		t.Fatalf("expected scan duration to be recorded")
+	}
 	if result == nil || len(result.Contents) != 1 {
 		t.Fatalf("expected single content entry, got %+v", result)
 	}
@@ -114,6 +119,39 @@ func TestReadFleetHealthResource(t *testing.T) {
 	}
 }
 
+func TestReadFleetHealthResourceCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	eventCalls := 0
+	handler := newHandlerWithServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/tenant/devices" {
+			eventCalls++
+			_, _ = w.Write([]byte(`{"items":[]}`))
+			return
+		}
+		result := models.DeviceListResult{TotalCount: 2, Items: []models.Device{
+			{KapuaEntity: models.KapuaEntity{ID: models.KapuaID("dev-1")}},
+			{KapuaEntity: models.KapuaEntity{ID: models.KapuaID("dev-2")}},
+		}}
+		body, _ := json.Marshal(result)
+		_, _ = w.Write(body)
+		cancel()
+	})
+
+	failedScans := fleetHealthScanDuration.Count("error")
+	_, err := handler.ReadResource(ctx, "kapua://fleet-health?limit=5")
+	if err == nil || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation error, got %v", err)
+	}
+	if fleetHealthScanDuration.Count("error") != failedScans+1 {
+		t.Fatalf("expected failed scan to be recorded")
+	}
+	if eventCalls != 0 {
+		t.Fatalf("expected no event lookups after cancellation, got %d", eventCalls)
+	}
+}
+
 func ptrTime(t time.Time) *time.Time {
 	return &t
 }
@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"kapua-mcp-server/internal/kapua/models"
+	"kapua-mcp-server/internal/kapua/services"
+	"kapua-mcp-server/pkg/metrics"
+	"kapua-mcp-server/pkg/utils"
+)
+
+// fleetIndexEventWindow is how far back the index keeps critical device events.
+const fleetIndexEventWindow = defaultCriticalMinutes * time.Minute
+
+var (
+	fleetIndexRefreshDuration = metrics.NewHistogramVec(
+		"kapua_fleet_index_refresh_duration_seconds",
+		"Duration of background fleet index refreshes by result (success or error).",
+		[]float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}, "result")
+	fleetIndexDevices = metrics.NewGauge(
+		"kapua_fleet_index_devices",
+		"Devices in the in-memory fleet index.")
+)
+
+// FleetIndex is an in-memory model of the fleet, refreshed by a background poller.
+// kapua://devices, kapua://fleet-health and kapua-devices-search answer from it
+// instead of paging Kapua on every read.
+type FleetIndex struct {
+	client   *services.KapuaClient
+	interval time.Duration
+	logger   *utils.Logger
+	snapshot atomic.Pointer[fleetSnapshot]
+
+	stop context.CancelFunc
+	done chan struct{}
+}
+
+// indexedDevice is a device with the attributes derived when it was indexed.
+type indexedDevice struct {
+	device         models.Device
+	status         models.ConnectionStatus
+	lastSeen       time.Time
+	lastSeenSource string
+	criticalEvents []models.DeviceEvent // Within fleetIndexEventWindow, newest first
+}
+
+// fleetSnapshot is the result of one refresh. It is never modified once stored.
+type fleetSnapshot struct {
+	updatedAt  time.Time
+	totalCount int
+	devices    []*indexedDevice // In Kapua order
+	byID       map[string]*indexedDevice
+	byClientID map[string]*indexedDevice
+	byStatus   map[models.ConnectionStatus][]*indexedDevice
+	byFirmware map[string][]*indexedDevice
+	byTag      map[string][]*indexedDevice
+	warnings   []string
+}
+
+// NewFleetIndex returns an index of the devices visible to client, refreshed every
+// interval once started.
+func NewFleetIndex(client *services.KapuaClient, interval time.Duration) *FleetIndex {
+	return &FleetIndex{
+		client:   client,
+		interval: interval,
+		logger:   utils.NewDefaultLogger("FleetIndex"),
+	}
+}
+
+// Start refreshes the index in the background, right away and then every interval,
+// until Stop is called.
+func (x *FleetIndex) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	x.stop, x.done = cancel, make(chan struct{})
+	go func() {
+		defer close(x.done)
+		ticker := time.NewTicker(x.interval)
+		defer ticker.Stop()
+		for {
+			if err := x.Refresh(ctx); err != nil && ctx.Err() == nil {
+				x.logger.Warn("Fleet index refresh failed, keeping the previous data: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop ends background refreshes and waits for a running one to return.
+func (x *FleetIndex) Stop() {
+	if x == nil || x.stop == nil {
+		return
+	}
+	x.stop()
+	<-x.done
+}
+
+// current returns the latest snapshot, or nil before the first refresh completes.
+func (x *FleetIndex) current() *fleetSnapshot {
+	if x == nil {
+		return nil
+	}
+	return x.snapshot.Load()
+}
+
+// Refresh pages the whole device list and fetches recent events for devices whose
+// last event changed since the previous refresh, then replaces the snapshot.
+func (x *FleetIndex) Refresh(ctx context.Context) (err error) {
+	start := time.Now()
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		fleetIndexRefreshDuration.Observe(time.Since(start).Seconds(), status)
+	}()
+
+	// Interactive tool calls go first, and the poll fetches past the response cache.
+	ctx = services.WithPriority(services.WithoutCache(ctx), services.PriorityBulk)
+	devices, total, err := listAllDevices(ctx, x.client)
+	if err != nil {
+		return err
+	}
+
+	previous := x.snapshot.Load()
+	now := timeNow()
+	since := now.Add(-fleetIndexEventWindow)
+	entries := make([]*indexedDevice, len(devices))
+	var warnings []string
+	type refreshTarget struct {
+		entry, old *indexedDevice
+	}
+	var targets []refreshTarget
+
+	for i, device := range devices {
+		entry := &indexedDevice{device: device, status: connectionStatus(device)}
+		entry.lastSeen, entry.lastSeenSource = lastSeenForDevice(device)
+		entries[i] = entry
+
+		deviceID := string(device.ID)
+		if deviceID == "" {
+			continue
+		}
+		var old *indexedDevice
+		if previous != nil {
+			old = previous.byID[deviceID]
+		}
+		if old != nil && device.LastEventID != "" && old.device.LastEventID == device.LastEventID {
+			entry.criticalEvents = eventsSince(old.criticalEvents, since)
+			continue
+		}
+		targets = append(targets, refreshTarget{entry: entry, old: old})
+	}
+
+	var mu sync.Mutex
+	processConcurrently(ctx, targets, defaultEventConcurrency, "Fetched events of %d of %d devices", func(target refreshTarget) {
+		device := target.entry.device
+		events, err := x.client.ListDeviceEvents(ctx, string(device.ID), map[string]string{
+			"startDate": since.Format(time.RFC3339),
+			"limit":     "20",
+			"sortParam": "receivedOn",
+			"sortDir":   "DESCENDING",
+		})
+		if err != nil {
+			mu.Lock()
+			warnings = append(warnings, fmt.Sprintf("device %s: %v", labelForDevice(device), err))
+			mu.Unlock()
+			if target.old != nil {
+				target.entry.criticalEvents = eventsSince(target.old.criticalEvents, since)
+			}
+			return
+		}
+		critical := filterCriticalEvents(events.Items)
+		if len(critical) > maxCriticalEventsPerDevice {
+			critical = critical[:maxCriticalEventsPerDevice]
+		}
+		target.entry.criticalEvents = critical
+	})
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("fleet index refresh cancelled: %w", err)
+	}
+
+	snapshot := newFleetSnapshot(now, total, entries, warnings)
+	x.snapshot.Store(snapshot)
+	fleetIndexDevices.Set(float64(len(entries)))
+	x.logger.Info("Fleet index refreshed: %d devices, events fetched for %d, took %v",
+		len(entries), len(targets), time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// listAllDevices pages through every device in the scope and returns them with
+// the total count.
+func listAllDevices(ctx context.Context, client *services.KapuaClient) ([]models.Device, int, error) {
+	var devices []models.Device
+	total := 0
+	for {
+		result, err := client.ListDevices(ctx, map[string]string{
+			"limit":         strconv.Itoa(maxDevicePageSize),
+			"offset":        strconv.Itoa(len(devices)),
+			"askTotalCount": "true",
+		})
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to list devices: %w", err)
+		}
+		if total == 0 {
+			total = result.TotalCount
+		}
+		devices = append(devices, result.Items...)
+		if len(result.Items) < maxDevicePageSize {
+			break
+		}
+	}
+	return devices, max(total, len(devices)), nil
+}
+
+func newFleetSnapshot(updatedAt time.Time, total int, entries []*indexedDevice, warnings []string) *fleetSnapshot {
+	snapshot := &fleetSnapshot{
+		updatedAt:  updatedAt,
+		totalCount: total,
+		devices:    entries,
+		byID:       make(map[string]*indexedDevice, len(entries)),
+		byClientID: make(map[string]*indexedDevice, len(entries)),
+		byStatus:   map[models.ConnectionStatus][]*indexedDevice{},
+		byFirmware: map[string][]*indexedDevice{},
+		byTag:      map[string][]*indexedDevice{},
+		warnings:   warnings,
+	}
+	for _, entry := range entries {
+		if entry.device.ID != "" {
+			snapshot.byID[string(entry.device.ID)] = entry
+		}
+		if entry.device.ClientID != "" {
+			snapshot.byClientID[entry.device.ClientID] = entry
+		}
+		snapshot.byStatus[entry.status] = append(snapshot.byStatus[entry.status], entry)
+		if entry.device.FirmwareVersion != "" {
+			snapshot.byFirmware[entry.device.FirmwareVersion] = append(snapshot.byFirmware[entry.device.FirmwareVersion], entry)
+		}
+		for _, tag := range entry.device.TagIDs {
+			snapshot.byTag[string(tag)] = append(snapshot.byTag[string(tag)], entry)
+		}
+	}
+	return snapshot
+}
+
+// eventsSince returns the events received at or after since.
+func eventsSince(events []models.DeviceEvent, since time.Time) []models.DeviceEvent {
+	var recent []models.DeviceEvent
+	for _, event := range events {
+		at := event.ReceivedOn
+		if at.IsZero() {
+			at = event.SentOn
+		}
+		if !at.Before(since) {
+			recent = append(recent, event)
+		}
+	}
+	return recent
+}
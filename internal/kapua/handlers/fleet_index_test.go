@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"kapua-mcp-server/internal/kapua/models"
+)
+
+// fakeFleet serves a device list and per-device events, counting the requests.
+type fakeFleet struct {
+	mu       sync.Mutex
+	devices  []models.Device
+	events   map[string][]models.DeviceEvent
+	requests map[string]int
+}
+
+func (f *fakeFleet) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests[r.URL.Path]++
+	switch {
+	case r.URL.Path == "/v1/tenant/devices":
+		body, _ := json.Marshal(models.DeviceListResult{TotalCount: len(f.devices), Items: f.devices})
+		_, _ = w.Write(body)
+	case strings.HasSuffix(r.URL.Path, "/events"):
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/tenant/devices/"), "/events")
+		body, _ := json.Marshal(models.DeviceEventListResult{Items: f.events[id]})
+		_, _ = w.Write(body)
+	default:
+		id := strings.TrimPrefix(r.URL.Path, "/v1/tenant/devices/")
+		for _, device := range f.devices {
+			if string(device.ID) == id {
+				body, _ := json.Marshal(device)
+				_, _ = w.Write(body)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeFleet) count(path string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[path]
+}
+
+func newIndexedHandler(t *testing.T, now time.Time) (*KapuaHandler, *fakeFleet) {
+	t.Helper()
+	fleet := &fakeFleet{
+		devices: []models.Device{
+			{
+				KapuaEntity:     models.KapuaEntity{ID: "dev-1"},
+				ClientID:        "alpha",
+				DisplayName:     "Boiler room gateway",
+				FirmwareVersion: "1.2",
+				TagIDs:          []models.KapuaID{"tag-a"},
+				LastEventID:     "ev-1",
+				Connection:      &models.DeviceConnection{Status: models.ConnectionStatusConnected},
+				LastEvent:       &models.DeviceEvent{ReceivedOn: now.Add(-5 * time.Minute)},
+			},
+			{
+				KapuaEntity:     models.KapuaEntity{ID: "dev-2"},
+				ClientID:        "bravo",
+				FirmwareVersion: "1.2",
+				LastEventID:     "ev-2",
+				Connection:      &models.DeviceConnection{Status: models.ConnectionStatusDisconnected},
+				LastEvent:       &models.DeviceEvent{ReceivedOn: now.Add(-3 * time.Hour)},
+			},
+			{
+				KapuaEntity:     models.KapuaEntity{ID: "dev-3"},
+				ClientID:        "charlie",
+				FirmwareVersion: "2.0",
+				TagIDs:          []models.KapuaID{"tag-a"},
+				Connection:      &models.DeviceConnection{Status: models.ConnectionStatusConnected},
+			},
+		},
+		events: map[string][]models.DeviceEvent{
+			"dev-1": {{ReceivedOn: now.Add(-10 * time.Minute), ResponseCode: "INTERNAL_ERROR", Action: "EXECUTE"}},
+		},
+		requests: map[string]int{},
+	}
+	handler := newHandlerWithServer(t, fleet.serve)
+	handler.SetFleetIndex(NewFleetIndex(handler.client, time.Minute))
+	return handler, fleet
+}
+
+func stubTimeNow(t *testing.T, now time.Time) {
+	t.Helper()
+	original := timeNow
+	timeNow = func() time.Time { return now }
+	t.Cleanup(func() { timeNow = original })
+}
+
+func TestFleetIndexRefresh(t *testing.T) {
+	now := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	stubTimeNow(t, now)
+	handler, fleet := newIndexedHandler(t, now)
+	index := handler.fleetIndex
+
+	if index.current() != nil {
+		t.Fatal("expected no snapshot before the first refresh")
+	}
+	if err := index.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh returned error: %v", err)
+	}
+	snapshot := index.current()
+	if snapshot.totalCount != 3 || !snapshot.updatedAt.Equal(now) {
+		t.Fatalf("unexpected snapshot header: %d devices at %v", snapshot.totalCount, snapshot.updatedAt)
+	}
+	if snapshot.byClientID["bravo"].device.ID != "dev-2" || len(snapshot.byStatus[models.ConnectionStatusConnected]) != 2 ||
+		len(snapshot.byFirmware["1.2"]) != 2 || len(snapshot.byTag["tag-a"]) != 2 {
+		t.Fatalf("unexpected indexes: %+v", snapshot)
+	}
+	if events := snapshot.byID["dev-1"].criticalEvents; len(events) != 1 || events[0].ResponseCode != "INTERNAL_ERROR" {
+		t.Fatalf("unexpected critical events: %+v", events)
+	}
+
+	// Devices whose last event did not change keep their events without a request.
+	fleet.mu.Lock()
+	fleet.devices[1].LastEventID = "ev-2b"
+	fleet.mu.Unlock()
+	if err := index.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh returned error: %v", err)
+	}
+	for path, want := range map[string]int{
+		"/v1/tenant/devices":              2,
+		"/v1/tenant/devices/dev-1/events": 1,
+		"/v1/tenant/devices/dev-2/events": 2,
+		"/v1/tenant/devices/dev-3/events": 2,
+	} {
+		if got := fleet.count(path); got != want {
+			t.Errorf("%s: got %d requests, want %d", path, got, want)
+		}
+	}
+	if len(index.current().byID["dev-1"].criticalEvents) != 1 {
+		t.Fatal("expected carried-over events for dev-1")
+	}
+}
+
+func TestSearchDevicesFromIndex(t *testing.T) {
+	now := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	stubTimeNow(t, now)
+	handler, fleet := newIndexedHandler(t, now)
+
+	if _, _, err := handler.HandleSearchDevices(context.Background(), nil, &SearchDevicesParams{}); err != errFleetIndexLoading {
+		t.Fatalf("expected loading error before the first refresh, got %v", err)
+	}
+	if err := handler.fleetIndex.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh returned error: %v", err)
+	}
+	requests := fleet.count("/v1/tenant/devices")
+
+	cases := []struct {
+		name   string
+		params SearchDevicesParams
+		want   []string
+	}{
+		{"all", SearchDevicesParams{}, []string{"dev-1", "dev-2", "dev-3"}},
+		{"query", SearchDevicesParams{Query: "boiler"}, []string{"dev-1"}},
+		{"client", SearchDevicesParams{ClientID: "charlie"}, []string{"dev-3"}},
+		{"status and firmware", SearchDevicesParams{ConnectionStatus: models.ConnectionStatusConnected, FirmwareVersion: "1.2"}, []string{"dev-1"}},
+		{"tag", SearchDevicesParams{TagID: "tag-a"}, []string{"dev-1", "dev-3"}},
+		{"seen within", SearchDevicesParams{SeenWithinMinutes: 30}, []string{"dev-1"}},
+		{"not seen for", SearchDevicesParams{NotSeenForMinutes: 60}, []string{"dev-2", "dev-3"}},
+		{"limit", SearchDevicesParams{Limit: 1}, []string{"dev-1"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, _, err := handler.HandleSearchDevices(context.Background(), nil, &tc.params)
+			if err != nil {
+				t.Fatalf("HandleSearchDevices returned error: %v", err)
+			}
+			var out DeviceSearchResult
+			if err := json.Unmarshal([]byte(textContent(t, result.Content[1])), &out); err != nil {
+				t.Fatalf("invalid JSON: %v", err)
+			}
+			var ids []string
+			for _, device := range out.Devices {
+				ids = append(ids, device.ID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tc.want, ",") || out.AsOf != "2024-08-01T12:00:00Z" {
+				t.Fatalf("got %v as of %s, want %v", ids, out.AsOf, tc.want)
+			}
+		})
+	}
+	if fleet.count("/v1/tenant/devices") != requests {
+		t.Fatal("searches must not call Kapua")
+	}
+}
+
+func TestResourcesAnswerFromIndex(t *testing.T) {
+	now := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	stubTimeNow(t, now)
+	handler, fleet := newIndexedHandler(t, now)
+	if err := handler.fleetIndex.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh returned error: %v", err)
+	}
+
+	result, err := handler.ReadResource(context.Background(), "kapua://devices?limit=2")
+	if err != nil {
+		t.Fatalf("ReadResource returned error: %v", err)
+	}
+	var devices map[string]any
+	_ = json.Unmarshal([]byte(result.Contents[0].Text), &devices)
+	if devices["source"] != "fleet-index" || devices["data_as_of"] != "2024-08-01T12:00:00Z" || devices["processed_count"] != float64(2) || devices["total_count"] != float64(3) {
+		t.Fatalf("unexpected devices resource: %v", devices)
+	}
+
+	result, err = handler.ReadResource(context.Background(), "kapua://fleet-health?staleMinutes=60")
+	if err != nil {
+		t.Fatalf("ReadResource returned error: %v", err)
+	}
+	var report fleetHealthReport
+	_ = json.Unmarshal([]byte(result.Contents[0].Text), &report)
+	if report.Online != 2 || report.Offline != 1 || len(report.StaleDevices) != 1 || report.StaleDevices[0].ID != "dev-2" ||
+		len(report.DevicesWithCriticalEvents) != 1 || report.DataAsOf != "2024-08-01T12:00:00Z" {
+		t.Fatalf("unexpected fleet health report: %+v", report)
+	}
+	if fleet.count("/v1/tenant/devices") != 1 {
+		t.Fatalf("expected index reads without Kapua calls, got %d list requests", fleet.count("/v1/tenant/devices"))
+	}
+
+	// Fresh data on request, and a lookback the index does not cover, go to Kapua.
+	if _, err := handler.ReadResource(context.Background(), "kapua://devices?noCache=true"); err != nil {
+		t.Fatalf("ReadResource returned error: %v", err)
+	}
+	if _, err := handler.ReadResource(context.Background(), "kapua://fleet-health?criticalMinutes=240"); err != nil {
+		t.Fatalf("ReadResource returned error: %v", err)
+	}
+	if fleet.count("/v1/tenant/devices") != 3 {
+		t.Fatalf("expected two live reads, got %d list requests", fleet.count("/v1/tenant/devices"))
+	}
+}
+
+func TestFleetIndexStartStop(t *testing.T) {
+	handler, _ := newIndexedHandler(t, time.Now())
+	index := handler.fleetIndex
+	index.Start()
+	deadline := time.Now().Add(5 * time.Second)
+	for index.current() == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("index was not refreshed after Start")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	index.Stop()
+
+	var nilIndex *FleetIndex
+	nilIndex.Stop()
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"kapua-mcp-server/internal/kapua/models"
+	"kapua-mcp-server/internal/kapua/services"
+	"kapua-mcp-server/pkg/utils"
+)
+
+// Kinds of DeviceChange, in the order of precedence when a device changed in
+// several ways between two polls.
+const (
+	DeviceAdded         = "added"
+	DeviceRemoved       = "removed"
+	DeviceConnected     = "connected"
+	DeviceDisconnected  = "disconnected"
+	DeviceStatusChanged = "status"
+	DeviceNewEvents     = "events"
+)
+
+// DeviceChange describes how a device changed between two polls of a FleetWatcher.
+type DeviceChange struct {
+	DeviceID string                  `json:"deviceId"`
+	ClientID string                  `json:"clientId,omitempty"`
+	Change   string                  `json:"change"`
+	Status   models.ConnectionStatus `json:"status,omitempty"`
+}
+
+// FleetWatcher polls the connection state and last event of every device and
+// reports the devices that changed. When a fleet index is set it diffs the
+// index's refreshes instead of calling Kapua.
+type FleetWatcher struct {
+	client   *services.KapuaClient
+	index    *FleetIndex
+	interval time.Duration
+	onChange func(context.Context, []DeviceChange)
+	logger   *utils.Logger
+
+	mu   sync.Mutex
+	stop context.CancelFunc
+	done chan struct{}
+}
+
+// watchedDevice is what a FleetWatcher compares between polls.
+type watchedDevice struct {
+	clientID    string
+	status      models.ConnectionStatus
+	lastEventID models.KapuaID
+}
+
+// NewFleetWatcher returns a watcher that polls every interval once started and
+// calls onChange with the devices that changed. index may be nil.
+func NewFleetWatcher(client *services.KapuaClient, index *FleetIndex, interval time.Duration, onChange func(context.Context, []DeviceChange)) *FleetWatcher {
+	return &FleetWatcher{
+		client:   client,
+		index:    index,
+		interval: interval,
+		onChange: onChange,
+		logger:   utils.NewDefaultLogger("FleetWatcher"),
+	}
+}
+
+// Start begins polling unless the watcher is running. The first poll records the
+// fleet without reporting changes.
+func (w *FleetWatcher) Start() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stop != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	w.stop, w.done = cancel, make(chan struct{})
+	go w.run(ctx, w.done)
+}
+
+// Stop ends polling and waits for a running poll to return.
+func (w *FleetWatcher) Stop() {
+	w.mu.Lock()
+	stop, done := w.stop, w.done
+	w.stop, w.done = nil, nil
+	w.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	stop()
+	<-done
+}
+
+func (w *FleetWatcher) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	var previous map[string]watchedDevice
+	var lastSnapshot *fleetSnapshot
+	for {
+		var current map[string]watchedDevice
+		var err error
+		if snapshot := w.index.current(); snapshot != nil {
+			if snapshot != lastSnapshot {
+				current, lastSnapshot = watchedFromSnapshot(snapshot), snapshot
+			}
+		} else {
+			current, err = w.poll(ctx)
+		}
+		switch {
+		case err != nil && ctx.Err() == nil:
+			w.logger.Warn("Device poll failed, retrying in %v: %v", w.interval, err)
+		case current != nil:
+			if previous != nil {
+				if changes := diffWatchedDevices(previous, current); len(changes) > 0 {
+					w.report(ctx, changes)
+				}
+			}
+			previous = current
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll pages through every device in the scope, past the response cache.
+func (w *FleetWatcher) poll(ctx context.Context) (map[string]watchedDevice, error) {
+	ctx = services.WithPriority(services.WithoutCache(ctx), services.PriorityBulk)
+	devices, _, err := listAllDevices(ctx, w.client)
+	if err != nil {
+		return nil, err
+	}
+	watched := make(map[string]watchedDevice, len(devices))
+	for _, device := range devices {
+		if device.ID != "" {
+			watched[string(device.ID)] = watchedDevice{clientID: device.ClientID, status: connectionStatus(device), lastEventID: device.LastEventID}
+		}
+	}
+	return watched, nil
+}
+
+func (w *FleetWatcher) report(ctx context.Context, changes []DeviceChange) {
+	if w.index.current() == nil {
+		// Answers are read from Kapua; make sure the next read is not a stale cache hit.
+		for _, change := range changes {
+			w.client.ForgetDevice(change.DeviceID)
+		}
+	}
+	w.logger.Info("Detected changes on %d devices", len(changes))
+	w.onChange(ctx, changes)
+}
+
+func watchedFromSnapshot(snapshot *fleetSnapshot) map[string]watchedDevice {
+	watched := make(map[string]watchedDevice, len(snapshot.byID))
+	for id, entry := range snapshot.byID {
+		watched[id] = watchedDevice{clientID: entry.device.ClientID, status: entry.status, lastEventID: entry.device.LastEventID}
+	}
+	return watched
+}
+
+// diffWatchedDevices returns one change per device that differs between previous
+// and current, sorted by device ID.
+func diffWatchedDevices(previous, current map[string]watchedDevice) []DeviceChange {
+	var changes []DeviceChange
+	for id, now := range current {
+		change := DeviceChange{DeviceID: id, ClientID: now.clientID, Status: now.status}
+		before, existed := previous[id]
+		switch {
+		case !existed:
+			change.Change = DeviceAdded
+		case before.status != now.status && now.status == models.ConnectionStatusConnected:
+			change.Change = DeviceConnected
+		case before.status != now.status && before.status == models.ConnectionStatusConnected:
+			change.Change = DeviceDisconnected
+		case before.status != now.status:
+			change.Change = DeviceStatusChanged
+		case before.lastEventID != now.lastEventID:
+			change.Change = DeviceNewEvents
+		default:
+			continue
+		}
+		changes = append(changes, change)
+	}
+	for id, before := range previous {
+		if _, ok := current[id]; !ok {
+			changes = append(changes, DeviceChange{DeviceID: id, ClientID: before.clientID, Change: DeviceRemoved})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].DeviceID < changes[j].DeviceID })
+	return changes
+}
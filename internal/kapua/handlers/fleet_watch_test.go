@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"kapua-mcp-server/internal/kapua/models"
+)
+
+func TestDiffWatchedDevices(t *testing.T) {
+	previous := map[string]watchedDevice{
+		"a": {clientID: "alpha", status: models.ConnectionStatusConnected, lastEventID: "e1"},
+		"b": {clientID: "bravo", status: models.ConnectionStatusDisconnected, lastEventID: "e2"},
+		"c": {clientID: "charlie", status: models.ConnectionStatusDisconnected},
+		"d": {clientID: "delta", status: models.ConnectionStatusConnected, lastEventID: "e4"},
+		"e": {clientID: "echo", status: models.ConnectionStatusConnected, lastEventID: "e5"},
+		"f": {clientID: "foxtrot", status: models.ConnectionStatusConnected, lastEventID: "e6"},
+	}
+	current := map[string]watchedDevice{
+		"a": {clientID: "alpha", status: models.ConnectionStatusDisconnected, lastEventID: "e1b"},
+		"b": {clientID: "bravo", status: models.ConnectionStatusConnected, lastEventID: "e2"},
+		"c": {clientID: "charlie", status: models.ConnectionStatusMissing},
+		"d": {clientID: "delta", status: models.ConnectionStatusConnected, lastEventID: "e4b"},
+		"f": {clientID: "foxtrot", status: models.ConnectionStatusConnected, lastEventID: "e6"},
+		"g": {clientID: "golf", status: models.ConnectionStatusConnected},
+	}
+
+	want := []DeviceChange{
+		{DeviceID: "a", ClientID: "alpha", Change: DeviceDisconnected, Status: models.ConnectionStatusDisconnected},
+		{DeviceID: "b", ClientID: "bravo", Change: DeviceConnected, Status: models.ConnectionStatusConnected},
+		{DeviceID: "c", ClientID: "charlie", Change: DeviceStatusChanged, Status: models.ConnectionStatusMissing},
+		{DeviceID: "d", ClientID: "delta", Change: DeviceNewEvents, Status: models.ConnectionStatusConnected},
+		{DeviceID: "e", ClientID: "echo", Change: DeviceRemoved},
+		{DeviceID: "g", ClientID: "golf", Change: DeviceAdded, Status: models.ConnectionStatusConnected},
+	}
+	if got := diffWatchedDevices(previous, current); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected changes:\n got %+v\nwant %+v", got, want)
+	}
+	if got := diffWatchedDevices(current, current); len(got) != 0 {
+		t.Fatalf("expected no changes, got %+v", got)
+	}
+}
+
+func TestFleetWatcherReportsChanges(t *testing.T) {
+	handler, fleet := newIndexedHandler(t, time.Now())
+	changes := make(chan []DeviceChange, 10)
+	watcher := NewFleetWatcher(handler.client, nil, 10*time.Millisecond, func(_ context.Context, c []DeviceChange) {
+		changes <- c
+	})
+	watcher.Start()
+	defer watcher.Stop()
+	watcher.Start() // Already running
+
+	// Let the first poll record the fleet, then disconnect a device.
+	deadline := time.Now().Add(5 * time.Second)
+	for fleet.count("/v1/tenant/devices") < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("watcher did not poll")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	fleet.mu.Lock()
+	fleet.devices[0].Connection = &models.DeviceConnection{Status: models.ConnectionStatusDisconnected}
+	fleet.mu.Unlock()
+
+	select {
+	case got := <-changes:
+		if len(got) != 1 || got[0].DeviceID != "dev-1" || got[0].Change != DeviceDisconnected {
+			t.Fatalf("unexpected changes: %+v", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change reported")
+	}
+
+	watcher.Stop()
+	polls := fleet.count("/v1/tenant/devices")
+	time.Sleep(30 * time.Millisecond)
+	if fleet.count("/v1/tenant/devices") != polls {
+		t.Fatal("watcher kept polling after Stop")
+	}
+}
+
+func TestFleetWatcherFollowsFleetIndex(t *testing.T) {
+	handler, fleet := newIndexedHandler(t, time.Now())
+	if err := handler.fleetIndex.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh returned error: %v", err)
+	}
+	changes := make(chan []DeviceChange, 10)
+	watcher := NewFleetWatcher(handler.client, handler.fleetIndex, 10*time.Millisecond, func(_ context.Context, c []DeviceChange) {
+		changes <- c
+	})
+	watcher.Start()
+	defer watcher.Stop()
+	time.Sleep(30 * time.Millisecond)
+
+	fleet.mu.Lock()
+	fleet.devices[2].LastEventID = "ev-3"
+	fleet.mu.Unlock()
+	if err := handler.fleetIndex.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh returned error: %v", err)
+	}
+
+	select {
+	case got := <-changes:
+		if len(got) != 1 || got[0].DeviceID != "dev-3" || got[0].Change != DeviceNewEvents {
+			t.Fatalf("unexpected changes: %+v", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change reported")
+	}
+	if fleet.count("/v1/tenant/devices") != 2 {
+		t.Fatalf("expected the watcher to read the index only, got %d list requests", fleet.count("/v1/tenant/devices"))
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"strconv"
+	"strings"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 
@@ -13,8 +15,56 @@ import (
 
 // KapuaHandler provides MCP tool handlers for Kapua operations
 type KapuaHandler struct {
-	client *services.KapuaClient
-	logger *utils.Logger
+	client     *services.KapuaClient
+	logger     *utils.Logger
+	fleetIndex *FleetIndex // Optional; fleet-level reads answer from it when set
+
+	completions completionCache
+	deviceRefs  deviceRefCache
+}
+
+// CacheControl is embedded in the parameters of read tools whose data the Kapua
+// client caches.
+type CacheControl struct {
+	NoCache bool `json:"noCache,omitempty" jsonschema:"Fetch fresh data from Kapua instead of a cached answer from the last few seconds"`
+}
+
+// cacheContext marks ctx to skip the response cache when NoCache is set.
+func (c CacheControl) cacheContext(ctx context.Context) context.Context {
+	if c.NoCache {
+		return services.WithoutCache(ctx)
+	}
+	return ctx
+}
+
+// Operation statuses reported in OperationResult.
+const (
+	OperationRequested = "requested" // Kapua accepted an asynchronous device operation
+	OperationUpdated   = "updated"   // A configuration was written to the device
+)
+
+// OperationResult is the structured output of tools that change a device.
+type OperationResult struct {
+	Status   string `json:"status" jsonschema:"requested when Kapua accepted an asynchronous device operation, updated when a configuration was written"`
+	DeviceID string `json:"deviceId,omitempty" jsonschema:"Kapua ID of the device the operation applies to"`
+}
+
+// noCacheRequested reports whether the resource URI has noCache=true.
+func noCacheRequested(uri *url.URL) bool {
+	if uri == nil {
+		return false
+	}
+	noCache, err := strconv.ParseBool(uri.Query().Get("noCache"))
+	return err == nil && noCache
+}
+
+// resourceCacheContext marks ctx to skip the response cache when the resource URI
+// has noCache=true.
+func resourceCacheContext(ctx context.Context, uri *url.URL) context.Context {
+	if noCacheRequested(uri) {
+		return services.WithoutCache(ctx)
+	}
+	return ctx
 }
 
 // NewKapuaHandler creates a new Kapua handler
@@ -25,46 +75,71 @@ func NewKapuaHandler(client *services.KapuaClient) *KapuaHandler {
 	}
 }
 
+// SetFleetIndex makes fleet-level reads answer from index, and enables
+// kapua-devices-search.
+func (h *KapuaHandler) SetFleetIndex(index *FleetIndex) {
+	h.fleetIndex = index
+}
+
+// HasFleetIndex reports whether a fleet index is set.
+func (h *KapuaHandler) HasFleetIndex() bool {
+	return h.fleetIndex != nil
+}
+
 // MCP Resource Handlers
 
 // ListResources returns a list of available Kapua resources
 func (h *KapuaHandler) ListResources(ctx context.Context) ([]mcp.Resource, error) {
-	h.logger.Debug("Listing available Kapua resources")
-
-	resources := []mcp.Resource{
-		{
-			URI:         "kapua://devices",
-			Name:        "Kapua Devices",
-			Description: "Live list of all Kapua IoT devices with current status and metadata",
-			MIMEType:    "application/json",
-		},
-		{
-			URI:         "kapua://fleet-health",
-			Name:        "Kapua Fleet Health",
-			Description: "Aggregated fleet health snapshot including connection status, stale devices, and recent critical events",
-			MIMEType:    "application/json",
-		},
+	h.logger.WithContext(ctx).Debug("Listing available Kapua resources")
+
+	var resources []mcp.Resource
+	for _, route := range resourceRoutes {
+		if path := route.path(); !strings.Contains(path, "{") {
+			resources = append(resources, mcp.Resource{
+				URI:         path,
+				Name:        route.name,
+				Description: route.description,
+				MIMEType:    "application/json",
+			})
+		}
 	}
 
 	return resources, nil
 }
 
+// ListResourceTemplates returns the URI templates of Kapua resources that take
+// path variables or query parameters.
+func (h *KapuaHandler) ListResourceTemplates(ctx context.Context) ([]mcp.ResourceTemplate, error) {
+	h.logger.WithContext(ctx).Debug("Listing available Kapua resource templates")
+
+	var templates []mcp.ResourceTemplate
+	for _, route := range resourceRoutes {
+		if strings.Contains(route.template, "{") {
+			templates = append(templates, mcp.ResourceTemplate{
+				URITemplate: route.template,
+				Name:        route.name,
+				Description: route.description,
+				MIMEType:    "application/json",
+			})
+		}
+	}
+
+	return templates, nil
+}
+
 // ReadResource returns the content of a specific Kapua resource
 func (h *KapuaHandler) ReadResource(ctx context.Context, uri string) (*mcp.ReadResourceResult, error) {
-	h.logger.Debug("Reading Kapua resource: %s", uri)
+	h.logger.WithContext(ctx).Debug("Reading Kapua resource: %s", uri)
 
 	parsed, err := url.Parse(uri)
 	if err != nil {
 		return nil, fmt.Errorf("invalid resource URI: %w", err)
 	}
-	resourceURI := fmt.Sprintf("%s://%s%s", parsed.Scheme, parsed.Host, parsed.Path)
-
-	switch resourceURI {
-	case "kapua://devices":
-		return h.readDevicesResource(ctx, parsed)
-	case "kapua://fleet-health":
-		return h.readFleetHealthResource(ctx, parsed)
-	default:
-		return nil, fmt.Errorf("unknown resource URI: %s", uri)
+
+	for _, route := range resourceRoutes {
+		if vars, ok := route.match(parsed); ok {
+			return route.read(h, ctx, parsed, vars)
+		}
 	}
+	return nil, fmt.Errorf("unknown resource URI: %s", uri)
 }
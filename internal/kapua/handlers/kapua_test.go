@@ -24,8 +24,8 @@ func TestNewKapuaHandlerListResources(t *testing.T) {
 	if err != nil {
 		t.Fatalf("ListResources returned error: %v", err)
 	}
-	if len(resources) != 2 {
-		t.Fatalf("expected two resources, got %d", len(resources))
+	if len(resources) != 3 {
+		t.Fatalf("expected three resources, got %d", len(resources))
 	}
 	uris := map[string]bool{}
 	for _, res := range resources {
@@ -37,6 +37,9 @@ func TestNewKapuaHandlerListResources(t *testing.T) {
 	if !uris["kapua://fleet-health"] {
 		t.Fatalf("fleet-health resource missing: %+v", resources)
 	}
+	if !uris["kapua://server-info"] {
+		t.Fatalf("server-info resource missing: %+v", resources)
+	}
 }
 
 func TestReadResourceDevicesSuccess(t *testing.T) {
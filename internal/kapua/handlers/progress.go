@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// ProgressFunc receives the progress of a long-running read: done of total items
+// are processed. It is called from several goroutines at once, so reports may
+// arrive out of order.
+type ProgressFunc func(done, total int, message string)
+
+type progressKey struct{}
+
+// WithProgress returns a context whose bulk reads, such as kapua://fleet-health,
+// report their progress to report.
+func WithProgress(ctx context.Context, report ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, report)
+}
+
+// reportProgress passes done of total to the ProgressFunc of ctx, if any, with
+// a message formatted from format and done and total.
+func reportProgress(ctx context.Context, done, total int, format string) {
+	if report, ok := ctx.Value(progressKey{}).(ProgressFunc); ok && report != nil {
+		report(done, total, fmt.Sprintf(format, done, total))
+	}
+}
+
+// processConcurrently calls fn for every item on at most workers goroutines and
+// reports progress as items finish. Once ctx is done no further item is started;
+// the calls in flight return promptly since their Kapua requests share ctx.
+func processConcurrently[T any](ctx context.Context, items []T, workers int, format string, fn func(T)) {
+	if len(items) == 0 {
+		return
+	}
+	workers = max(1, min(workers, len(items)))
+
+	var mu sync.Mutex
+	done := 0
+	reportProgress(ctx, 0, len(items), format)
+
+	queue := make(chan T)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range queue {
+				if ctx.Err() != nil {
+					continue
+				}
+				fn(item)
+				mu.Lock()
+				done++
+				finished := done
+				mu.Unlock()
+				reportProgress(ctx, finished, len(items), format)
+			}
+		}()
+	}
+
+feed:
+	for _, item := range items {
+		select {
+		case queue <- item:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(queue)
+	wg.Wait()
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type progressRecorder struct {
+	mu      sync.Mutex
+	reports [][2]int
+	last    string
+}
+
+func (r *progressRecorder) record(done, total int, message string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reports = append(r.reports, [2]int{done, total})
+	r.last = message
+}
+
+func TestProcessConcurrentlyReportsProgress(t *testing.T) {
+	var recorder progressRecorder
+	ctx := WithProgress(context.Background(), recorder.record)
+
+	var processed atomic.Int32
+	processConcurrently(ctx, []int{1, 2, 3, 4}, 2, "Checked %d of %d", func(int) { processed.Add(1) })
+
+	if processed.Load() != 4 {
+		t.Fatalf("expected 4 items processed, got %d", processed.Load())
+	}
+	// Reports are made outside the lock, so they may arrive out of order.
+	if len(recorder.reports) != 5 || recorder.reports[0] != [2]int{0, 4} {
+		t.Fatalf("unexpected progress %v", recorder.reports)
+	}
+	seen := map[int]bool{}
+	for _, report := range recorder.reports {
+		seen[report[0]] = report[1] == 4
+	}
+	for done := range 5 {
+		if !seen[done] {
+			t.Fatalf("missing progress %d of 4 in %v", done, recorder.reports)
+		}
+	}
+	// Without a ProgressFunc nothing is reported, and nothing breaks.
+	processConcurrently(context.Background(), []int{1}, 2, "Checked %d of %d", func(int) {})
+}
+
+func TestProcessConcurrentlyStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	items := make([]int, 100)
+	var started atomic.Int32
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		processConcurrently(ctx, items, 5, "%d of %d", func(int) {
+			if started.Add(1) == 5 {
+				cancel()
+			}
+			<-ctx.Done()
+		})
+	}()
+
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("processConcurrently did not return after cancellation")
+	}
+	if started.Load() > 5 {
+		t.Fatalf("expected no items started after cancellation, got %d", started.Load())
+	}
+}
+
+func TestReadDevicesResourceReportsProgress(t *testing.T) {
+	handler := newHandlerWithServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"totalCount":2,"items":[{"id":"dev-1"},{"id":"dev-2"}]}`))
+	})
+	var recorder progressRecorder
+	ctx := WithProgress(context.Background(), recorder.record)
+	if _, err := handler.ReadResource(ctx, "kapua://devices"); err != nil {
+		t.Fatalf("ReadResource returned error: %v", err)
+	}
+	if len(recorder.reports) != 1 || recorder.reports[0] != [2]int{2, 2} || recorder.last != "Listed 2 of 2 devices" {
+		t.Fatalf("unexpected progress %v %q", recorder.reports, recorder.last)
+	}
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// PromptTemplate is an MCP prompt whose text is a Go text/template over the
+// prompt arguments, e.g. {{.clientId}}. Missing optional arguments render empty.
+// Users add their own prompts as JSON files of this shape in KAPUA_PROMPTS_DIR.
+type PromptTemplate struct {
+	Name        string                `json:"name"`
+	Title       string                `json:"title,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Arguments   []*mcp.PromptArgument `json:"arguments,omitempty"`
+	Template    string                `json:"template"`
+
+	parsed *template.Template
+}
+
+var builtinPrompts = []*PromptTemplate{
+	{
+		Name:        "kapua-diagnose-offline-device",
+		Title:       "Diagnose an offline device",
+		Description: "Find out why a device is disconnected or missing and what to do about it",
+		Arguments: []*mcp.PromptArgument{
+			{Name: "clientId", Description: "Client ID of the device, e.g. gateway-07", Required: true},
+		},
+		Template: `Diagnose why the Kapua device {{.clientId}} is offline.
+
+1. Read the resource kapua://devices/{{.clientId}} to get its connection status, last event and firmware. If the device does not exist, say so and stop.
+2. Call kapua-device-events-list with clientId "{{.clientId}}", sortParam "receivedOn", sortDir "DESCENDING" and limit 50. Find the last connect and disconnect events and any errors before them.
+3. If kapua-device-logs-list is available, call it with clientId "{{.clientId}}" for the hours before the disconnect and look for errors or restarts.
+4. Call kapua-data-messages-list with clientIds ["{{.clientId}}"], sortDir "DESC" and limit 10. Telemetry after the disconnect means the device runs but its MQTT session is down.
+5. If the device is connected again, check kapua-device-inventory-bundles-list for bundles that are not ACTIVE.
+
+Report the current status, when the device was last seen, the most likely cause with the evidence for it, and the next steps. Do not roll back snapshots or change configurations without asking first.`,
+	},
+	{
+		Name:        "kapua-rollout-readiness",
+		Title:       "Pre-rollout readiness check",
+		Description: "Check whether the devices carrying a tag are ready for a software or configuration rollout",
+		Arguments: []*mcp.PromptArgument{
+			{Name: "tag", Description: "ID of the Kapua tag that selects the rollout targets", Required: true},
+			{Name: "targetVersion", Description: "Firmware or package version being rolled out, if any"},
+		},
+		Template: `Check whether the devices tagged {{.tag}} are ready for a rollout{{with .targetVersion}} of version {{.}}{{end}}.
+
+1. Find the devices with kapua-devices-search and tagId "{{.tag}}". If that tool is not available, page through kapua-devices-list and keep the devices whose tagIds contain "{{.tag}}".
+2. Read kapua://fleet-health for recent critical events on those devices.
+3. For every target device:
+   - It must be CONNECTED and seen in the last hour.
+   - Call kapua-device-snapshots-list: at least one snapshot must exist so the rollout can be rolled back.
+   - Call kapua-device-inventory-deployment-packages-list and kapua-device-inventory-system-packages-list to record the installed versions{{with .targetVersion}} and spot devices already on {{.}}{{end}}.
+   - Call kapua-device-events-list for the last 24 hours and note failed operations.
+
+Answer with a table of devices marked ready or not ready, the reason for each device that is not ready, and an overall go or no-go. Only read data; do not start or stop anything.`,
+	},
+	{
+		Name:        "kapua-weekly-fleet-report",
+		Title:       "Weekly fleet report",
+		Description: "Summarise fleet health, connectivity and notable events over the last days",
+		Arguments: []*mcp.PromptArgument{
+			{Name: "days", Description: "Number of days the report covers (default 7)"},
+		},
+		Template: `Write a fleet report for the last {{with .days}}{{.}}{{else}}7{{end}} days.
+
+1. Read kapua://fleet-health?staleMinutes=1440 for connection counts, stale devices and recent critical events.
+2. Read kapua://devices for the total number of devices and their firmware versions.
+3. For each stale device and each device with critical events, call kapua-device-events-list with a startDate at the beginning of the period to find when and why it went offline.
+4. Group the devices by firmware version and note versions running on only a few devices.
+
+Structure the report as: headline numbers, devices needing attention (client ID, problem, since when), firmware spread, and recommendations. Keep it short enough to paste into an email.`,
+	},
+	{
+		Name:        "kapua-investigate-metric-anomaly",
+		Title:       "Investigate a metric anomaly",
+		Description: "Look into unusual values of a telemetry metric reported by a device",
+		Arguments: []*mcp.PromptArgument{
+			{Name: "clientId", Description: "Client ID of the device reporting the metric", Required: true},
+			{Name: "metric", Description: "Name of the metric, e.g. temperature", Required: true},
+			{Name: "channel", Description: "Channel the metric is published on, if known"},
+			{Name: "hours", Description: "How many hours of telemetry to look at (default 24)"},
+		},
+		Template: `Investigate unusual values of the metric {{.metric}} reported by the device {{.clientId}}.
+
+1. Call kapua-data-messages-list with clientIds ["{{.clientId}}"]{{with .channel}}, channel "{{.}}"{{end}}, a startDate {{with .hours}}{{.}}{{else}}24{{end}} hours ago and sortDir "ASC". Page with offset until all messages are read.
+2. Extract {{.metric}} from the message metrics. Work out its typical range and list the values and times that fall outside it, as well as gaps in reporting.
+3. Call kapua-device-events-list with clientId "{{.clientId}}" for the same period and match connection changes, restarts or command executions to the anomalies.
+4. Call kapua-device-snapshots-list and compare the snapshot times with the anomalies to spot configuration changes.
+
+Report whether the anomaly looks real, like a sensor fault or like a side effect of a device event, with the supporting timestamps and values, and suggest what to check next.`,
+	},
+}
+
+// LoadPrompts returns the built-in prompts and those defined by the *.json files
+// in dir, which may be empty. A file prompt replaces the built-in prompt with the
+// same name.
+func LoadPrompts(dir string) ([]*PromptTemplate, error) {
+	byName := map[string]*PromptTemplate{}
+	for _, builtin := range builtinPrompts {
+		prompt := *builtin
+		if err := prompt.parse(); err != nil {
+			return nil, fmt.Errorf("invalid built-in prompt %s: %w", prompt.Name, err)
+		}
+		byName[prompt.Name] = &prompt
+	}
+
+	if dir != "" {
+		if _, err := os.Stat(dir); err != nil {
+			return nil, fmt.Errorf("failed to read prompts directory: %w", err)
+		}
+		files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list prompts in %s: %w", dir, err)
+		}
+		for _, file := range files {
+			prompt, err := loadPromptFile(file)
+			if err != nil {
+				return nil, err
+			}
+			byName[prompt.Name] = prompt
+		}
+	}
+
+	prompts := make([]*PromptTemplate, 0, len(byName))
+	for _, prompt := range byName {
+		prompts = append(prompts, prompt)
+	}
+	sort.Slice(prompts, func(i, j int) bool { return prompts[i].Name < prompts[j].Name })
+	return prompts, nil
+}
+
+func loadPromptFile(file string) (*PromptTemplate, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompt file: %w", err)
+	}
+	var prompt PromptTemplate
+	if err := json.Unmarshal(data, &prompt); err != nil {
+		return nil, fmt.Errorf("invalid prompt file %s: %w", file, err)
+	}
+	if err := prompt.parse(); err != nil {
+		return nil, fmt.Errorf("invalid prompt file %s: %w", file, err)
+	}
+	return &prompt, nil
+}
+
+func (p *PromptTemplate) parse() error {
+	if p.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if strings.TrimSpace(p.Template) == "" {
+		return fmt.Errorf("template is required")
+	}
+	for _, arg := range p.Arguments {
+		if arg == nil || arg.Name == "" {
+			return fmt.Errorf("every argument needs a name")
+		}
+	}
+	parsed, err := template.New(p.Name).Option("missingkey=zero").Parse(p.Template)
+	if err != nil {
+		return err
+	}
+	p.parsed = parsed
+	return nil
+}
+
+// Prompt returns the MCP description of the prompt.
+func (p *PromptTemplate) Prompt() *mcp.Prompt {
+	return &mcp.Prompt{
+		Name:        p.Name,
+		Title:       p.Title,
+		Description: p.Description,
+		Arguments:   p.Arguments,
+	}
+}
+
+// Render fills in the template with args and returns it as a single user message.
+func (p *PromptTemplate) Render(args map[string]string) (*mcp.GetPromptResult, error) {
+	values := map[string]string{}
+	for _, arg := range p.Arguments {
+		value := strings.TrimSpace(args[arg.Name])
+		if value == "" && arg.Required {
+			return nil, fmt.Errorf("prompt %s requires the %s argument", p.Name, arg.Name)
+		}
+		values[arg.Name] = value
+	}
+
+	var text strings.Builder
+	if err := p.parsed.Execute(&text, values); err != nil {
+		return nil, fmt.Errorf("failed to render prompt %s: %w", p.Name, err)
+	}
+	return &mcp.GetPromptResult{
+		Description: p.Description,
+		Messages: []*mcp.PromptMessage{
+			{Role: "user", Content: &mcp.TextContent{Text: text.String()}},
+		},
+	}, nil
+}
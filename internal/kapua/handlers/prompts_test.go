@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestBuiltinPromptsRender(t *testing.T) {
+	prompts, err := LoadPrompts("")
+	if err != nil {
+		t.Fatalf("LoadPrompts returned error: %v", err)
+	}
+	byName := map[string]*PromptTemplate{}
+	for _, prompt := range prompts {
+		byName[prompt.Name] = prompt
+	}
+	if len(byName) != len(builtinPrompts) {
+		t.Fatalf("expected %d built-in prompts, got %d", len(builtinPrompts), len(byName))
+	}
+
+	cases := []struct {
+		name     string
+		args     map[string]string
+		contains []string
+		excludes []string
+	}{
+		{"kapua-diagnose-offline-device", map[string]string{"clientId": "gateway-07"}, []string{"kapua://devices/gateway-07", `clientId "gateway-07"`}, nil},
+		{"kapua-rollout-readiness", map[string]string{"tag": "pilot"}, []string{`tagId "pilot"`, "kapua-device-snapshots-list"}, []string{"of version"}},
+		{"kapua-rollout-readiness", map[string]string{"tag": "pilot", "targetVersion": "5.4.0"}, []string{"rollout of version 5.4.0", "already on 5.4.0"}, nil},
+		{"kapua-weekly-fleet-report", nil, []string{"last 7 days"}, nil},
+		{"kapua-weekly-fleet-report", map[string]string{"days": "30"}, []string{"last 30 days"}, nil},
+		{"kapua-investigate-metric-anomaly", map[string]string{"clientId": "gw-1", "metric": "temperature"}, []string{"metric temperature", "24 hours ago"}, []string{"channel"}},
+		{"kapua-investigate-metric-anomaly", map[string]string{"clientId": "gw-1", "metric": "temperature", "channel": "sensors/room", "hours": "6"}, []string{`channel "sensors/room"`, "6 hours ago"}, nil},
+	}
+	for _, tc := range cases {
+		result, err := byName[tc.name].Render(tc.args)
+		if err != nil {
+			t.Fatalf("%s: Render returned error: %v", tc.name, err)
+		}
+		if len(result.Messages) != 1 || result.Messages[0].Role != "user" {
+			t.Fatalf("%s: unexpected messages %+v", tc.name, result.Messages)
+		}
+		text := result.Messages[0].Content.(*mcp.TextContent).Text
+		for _, want := range tc.contains {
+			if !strings.Contains(text, want) {
+				t.Errorf("%s %v: %q missing from:\n%s", tc.name, tc.args, want, text)
+			}
+		}
+		for _, unwanted := range tc.excludes {
+			if strings.Contains(text, unwanted) {
+				t.Errorf("%s %v: unexpected %q in:\n%s", tc.name, tc.args, unwanted, text)
+			}
+		}
+	}
+
+	if _, err := byName["kapua-investigate-metric-anomaly"].Render(map[string]string{"clientId": "gw-1", "metric": " "}); err == nil || !strings.Contains(err.Error(), "requires the metric argument") {
+		t.Fatalf("expected missing argument error, got %v", err)
+	}
+}
+
+func TestLoadPromptsFromDirectory(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("restart.json", `{"name":"site-restart-check","description":"Check devices after a site power cut","arguments":[{"name":"site","required":true}],"template":"List the devices of {{.site}} with kapua-devices-list."}`)
+	write("report.json", `{"name":"kapua-weekly-fleet-report","template":"Our own weekly report"}`)
+	write("notes.txt", "not a prompt")
+
+	prompts, err := LoadPrompts(dir)
+	if err != nil {
+		t.Fatalf("LoadPrompts returned error: %v", err)
+	}
+	byName := map[string]*PromptTemplate{}
+	for _, prompt := range prompts {
+		byName[prompt.Name] = prompt
+	}
+	if len(prompts) != len(builtinPrompts)+1 || byName["site-restart-check"] == nil {
+		t.Fatalf("unexpected prompts: %v", byName)
+	}
+	result, err := byName["site-restart-check"].Render(map[string]string{"site": "plant-3"})
+	if err != nil || result.Messages[0].Content.(*mcp.TextContent).Text != "List the devices of plant-3 with kapua-devices-list." {
+		t.Fatalf("unexpected custom prompt result %+v: %v", result, err)
+	}
+	if result, _ := byName["kapua-weekly-fleet-report"].Render(nil); result.Messages[0].Content.(*mcp.TextContent).Text != "Our own weekly report" {
+		t.Fatal("expected the file prompt to replace the built-in one")
+	}
+
+	write("broken.json", `{"name":"broken","template":"{{.site"}`)
+	if _, err := LoadPrompts(dir); err == nil || !strings.Contains(err.Error(), "broken.json") {
+		t.Fatalf("expected an error naming the broken file, got %v", err)
+	}
+	if _, err := LoadPrompts(filepath.Join(dir, "missing")); err == nil {
+		t.Fatal("expected an error for a missing directory")
+	}
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const deviceEventsResourceDefaultLimit = 50
+
+// resourceRoute maps a resource URI template to its reader. Path variables such
+// as {clientId} match one path segment; the query part ({?...}) only documents
+// the accepted parameters.
+type resourceRoute struct {
+	template    string
+	name        string
+	description string
+	read        func(h *KapuaHandler, ctx context.Context, uri *url.URL, vars map[string]string) (*mcp.ReadResourceResult, error)
+}
+
+var resourceRoutes = []resourceRoute{
+	{
+		template:    "kapua://devices{?limit,noCache}",
+		name:        "Kapua Devices",
+		description: "Live list of all Kapua IoT devices with current status and metadata",
+		read: func(h *KapuaHandler, ctx context.Context, uri *url.URL, _ map[string]string) (*mcp.ReadResourceResult, error) {
+			return h.readDevicesResource(ctx, uri)
+		},
+	},
+	{
+		template:    "kapua://fleet-health{?staleMinutes,criticalMinutes,limit,noCache}",
+		name:        "Kapua Fleet Health",
+		description: "Aggregated fleet health snapshot including connection status, stale devices, and recent critical events",
+		read: func(h *KapuaHandler, ctx context.Context, uri *url.URL, _ map[string]string) (*mcp.ReadResourceResult, error) {
+			return h.readFleetHealthResource(ctx, uri)
+		},
+	},
+	{
+		template:    "kapua://server-info",
+		name:        "Kapua Server Info",
+		description: "Kapua version, build and detected flavour (Eclipse Kapua or Everyware Cloud) with optional API capabilities",
+		read: func(h *KapuaHandler, ctx context.Context, _ *url.URL, _ map[string]string) (*mcp.ReadResourceResult, error) {
+			return h.readServerInfoResource(ctx)
+		},
+	},
+	{
+		template:    "kapua://devices/{clientId}{?noCache}",
+		name:        "Kapua Device",
+		description: "A device's metadata and connection state, by client ID",
+		read: func(h *KapuaHandler, ctx context.Context, uri *url.URL, vars map[string]string) (*mcp.ReadResourceResult, error) {
+			return h.readDeviceResource(ctx, uri, vars["clientId"], "device", func(ctx context.Context, deviceID string) (any, error) {
+				return h.client.GetDevice(ctx, deviceID)
+			})
+		},
+	},
+	{
+		template:    "kapua://devices/{clientId}/configuration{?noCache}",
+		name:        "Kapua Device Configuration",
+		description: "All component configurations of a device, by client ID",
+		read: func(h *KapuaHandler, ctx context.Context, uri *url.URL, vars map[string]string) (*mcp.ReadResourceResult, error) {
+			return h.readDeviceResource(ctx, uri, vars["clientId"], "device configuration", func(ctx context.Context, deviceID string) (any, error) {
+				return h.client.ReadDeviceConfigurations(ctx, deviceID)
+			})
+		},
+	},
+	{
+		template:    "kapua://devices/{clientId}/inventory{?noCache}",
+		name:        "Kapua Device Inventory",
+		description: "Inventory summary of a device, by client ID",
+		read: func(h *KapuaHandler, ctx context.Context, uri *url.URL, vars map[string]string) (*mcp.ReadResourceResult, error) {
+			return h.readDeviceResource(ctx, uri, vars["clientId"], "device inventory", func(ctx context.Context, deviceID string) (any, error) {
+				return h.client.ReadDeviceInventory(ctx, deviceID)
+			})
+		},
+	},
+	{
+		template:    "kapua://devices/{clientId}/snapshots{?noCache}",
+		name:        "Kapua Device Snapshots",
+		description: "Configuration snapshots available on a device, by client ID",
+		read: func(h *KapuaHandler, ctx context.Context, uri *url.URL, vars map[string]string) (*mcp.ReadResourceResult, error) {
+			return h.readDeviceResource(ctx, uri, vars["clientId"], "device snapshots", func(ctx context.Context, deviceID string) (any, error) {
+				return h.client.ListDeviceSnapshots(ctx, deviceID)
+			})
+		},
+	},
+	{
+		template:    "kapua://devices/{clientId}/snapshots/{snapshotId}{?noCache}",
+		name:        "Kapua Device Snapshot",
+		description: "The configuration stored in a device snapshot, by client ID and snapshot ID",
+		read: func(h *KapuaHandler, ctx context.Context, uri *url.URL, vars map[string]string) (*mcp.ReadResourceResult, error) {
+			return h.readDeviceResource(ctx, uri, vars["clientId"], "device snapshot", func(ctx context.Context, deviceID string) (any, error) {
+				return h.client.ReadDeviceSnapshotConfigurations(ctx, deviceID, vars["snapshotId"])
+			})
+		},
+	},
+	{
+		template:    "kapua://devices/{clientId}/events{?limit}",
+		name:        "Kapua Device Events",
+		description: "Most recent lifecycle events of a device, newest first, by client ID (default limit: 50)",
+		read: func(h *KapuaHandler, ctx context.Context, uri *url.URL, vars map[string]string) (*mcp.ReadResourceResult, error) {
+			limit := deviceEventsResourceDefaultLimit
+			if parsed, err := strconv.Atoi(uri.Query().Get("limit")); err == nil && parsed > 0 {
+				limit = parsed
+			}
+			return h.readDeviceResource(ctx, uri, vars["clientId"], "device events", func(ctx context.Context, deviceID string) (any, error) {
+				return h.client.ListDeviceEvents(ctx, deviceID, map[string]string{
+					"limit":     strconv.Itoa(limit),
+					"sortParam": "receivedOn",
+					"sortDir":   "DESCENDING",
+				})
+			})
+		},
+	},
+}
+
+// path returns the template without its query part.
+func (r resourceRoute) path() string {
+	path, _, _ := strings.Cut(r.template, "{?")
+	return path
+}
+
+// match reports whether uri has the route's scheme, host and path, and returns
+// the values of its path variables.
+func (r resourceRoute) match(uri *url.URL) (map[string]string, bool) {
+	scheme, pattern, _ := strings.Cut(r.path(), "://")
+	if uri.Scheme != scheme {
+		return nil, false
+	}
+	want := strings.Split(pattern, "/")
+	got := []string{uri.Host}
+	if path := strings.TrimPrefix(uri.EscapedPath(), "/"); path != "" {
+		got = append(got, strings.Split(path, "/")...)
+	}
+	if len(got) != len(want) {
+		return nil, false
+	}
+
+	vars := map[string]string{}
+	for i, segment := range want {
+		value, err := url.PathUnescape(got[i])
+		if err != nil {
+			return nil, false
+		}
+		if name, ok := strings.CutPrefix(segment, "{"); ok {
+			if value == "" {
+				return nil, false
+			}
+			vars[strings.TrimSuffix(name, "}")] = value
+			continue
+		}
+		if value != segment {
+			return nil, false
+		}
+	}
+	return vars, true
+}
+
+// readDeviceResource resolves clientID to a device and returns what fetch reads
+// for it as JSON. what names the data in log and error messages.
+func (h *KapuaHandler) readDeviceResource(ctx context.Context, uri *url.URL, clientID, what string, fetch func(context.Context, string) (any, error)) (*mcp.ReadResourceResult, error) {
+	ctx = resourceCacheContext(ctx, uri)
+	deviceID, err := h.deviceIDForClientID(ctx, clientID)
+	if err == nil {
+		var data any
+		if data, err = fetch(ctx, deviceID); err == nil {
+			return jsonResource(uri.String(), data)
+		}
+	}
+	h.logger.WithContext(ctx).Error("Failed to read %s resource for %s: %v", what, clientID, err)
+	return nil, fmt.Errorf("failed to read %s resource: %w", what, err)
+}
+
+func jsonResource(uri string, data any) (*mcp.ReadResourceResult, error) {
+	jsonData, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal resource: %w", err)
+	}
+	return &mcp.ReadResourceResult{
+		Contents: []*mcp.ResourceContents{
+			{
+				URI:      uri,
+				MIMEType: "application/json",
+				Text:     string(jsonData),
+			},
+		},
+	}, nil
+}
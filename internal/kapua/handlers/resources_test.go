@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"kapua-mcp-server/internal/kapua/models"
+)
+
+func TestResourceRouteMatch(t *testing.T) {
+	route := resourceRoute{template: "kapua://devices/{clientId}/snapshots/{snapshotId}{?noCache}"}
+	cases := []struct {
+		uri  string
+		want map[string]string
+	}{
+		{"kapua://devices/gw-1/snapshots/42", map[string]string{"clientId": "gw-1", "snapshotId": "42"}},
+		{"kapua://devices/gw-1/snapshots/42?noCache=true", map[string]string{"clientId": "gw-1", "snapshotId": "42"}},
+		{"kapua://devices/site%2Fgw-1/snapshots/42", map[string]string{"clientId": "site/gw-1", "snapshotId": "42"}},
+		{"kapua://devices/gw-1/snapshots", nil},
+		{"kapua://devices/gw-1/snapshots/42/extra", nil},
+		{"kapua://devices//snapshots/42", nil},
+		{"kapua://devices/gw-1/events/42", nil},
+		{"other://devices/gw-1/snapshots/42", nil},
+	}
+	for _, tc := range cases {
+		uri, err := url.Parse(tc.uri)
+		if err != nil {
+			t.Fatalf("invalid URI %s: %v", tc.uri, err)
+		}
+		vars, ok := route.match(uri)
+		if ok != (tc.want != nil) || (ok && !reflect.DeepEqual(vars, tc.want)) {
+			t.Errorf("%s: got %v, %v; want %v", tc.uri, vars, ok, tc.want)
+		}
+	}
+}
+
+func TestListResourceTemplates(t *testing.T) {
+	handler := newHandlerWithServer(t, func(w http.ResponseWriter, r *http.Request) {})
+	templates, err := handler.ListResourceTemplates(context.Background())
+	if err != nil {
+		t.Fatalf("ListResourceTemplates returned error: %v", err)
+	}
+	uris := map[string]bool{}
+	for _, template := range templates {
+		uris[template.URITemplate] = true
+	}
+	for _, want := range []string{
+		"kapua://devices{?limit,noCache}",
+		"kapua://devices/{clientId}{?noCache}",
+		"kapua://devices/{clientId}/configuration{?noCache}",
+		"kapua://devices/{clientId}/inventory{?noCache}",
+		"kapua://devices/{clientId}/snapshots/{snapshotId}{?noCache}",
+		"kapua://devices/{clientId}/events{?limit}",
+	} {
+		if !uris[want] {
+			t.Errorf("template %s missing from %v", want, uris)
+		}
+	}
+	if uris["kapua://server-info"] {
+		t.Error("kapua://server-info takes no parameters and must not be a template")
+	}
+}
+
+func TestReadDeviceResources(t *testing.T) {
+	var paths []string
+	handler := newHandlerWithServer(t, func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path+"?"+r.URL.RawQuery)
+		switch r.URL.Path {
+		case "/v1/tenant/devices":
+			if r.URL.Query().Get("clientId") == "gw-1" {
+				_, _ = w.Write([]byte(`{"items":[{"id":"dev-1","clientId":"gw-1"}]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"items":[]}`))
+		case "/v1/tenant/devices/dev-1":
+			_, _ = w.Write([]byte(`{"id":"dev-1","clientId":"gw-1","displayName":"Gateway 1"}`))
+		case "/v1/tenant/devices/dev-1/configurations":
+			_, _ = w.Write([]byte(`{"configuration":[{"id":"org.eclipse.kura.clock.ClockService"}]}`))
+		case "/v1/tenant/devices/dev-1/inventory":
+			_, _ = w.Write([]byte(`{"inventoryItems":[{"name":"kura","itemType":"BUNDLE"}]}`))
+		case "/v1/tenant/devices/dev-1/snapshots":
+			_, _ = w.Write([]byte(`{"snapshotId":[{"id":"42"}]}`))
+		case "/v1/tenant/devices/dev-1/snapshots/42":
+			_, _ = w.Write([]byte(`{"configuration":[{"id":"snap"}]}`))
+		case "/v1/tenant/devices/dev-1/events":
+			_, _ = w.Write([]byte(`{"items":[{"resource":"command"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	cases := []struct {
+		uri      string
+		contains string
+		request  string
+	}{
+		{"kapua://devices/gw-1", `"displayName": "Gateway 1"`, "/v1/tenant/devices/dev-1?"},
+		{"kapua://devices/gw-1/configuration", "ClockService", "/v1/tenant/devices/dev-1/configurations?"},
+		{"kapua://devices/gw-1/inventory", `"kura"`, "/v1/tenant/devices/dev-1/inventory?"},
+		{"kapua://devices/gw-1/snapshots", `"42"`, "/v1/tenant/devices/dev-1/snapshots?"},
+		{"kapua://devices/gw-1/snapshots/42", `"snap"`, "/v1/tenant/devices/dev-1/snapshots/42?"},
+		{"kapua://devices/gw-1/events?limit=5", `"command"`, "/v1/tenant/devices/dev-1/events?limit=5&sortDir=DESCENDING&sortParam=receivedOn"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.uri, func(t *testing.T) {
+			paths = nil
+			result, err := handler.ReadResource(context.Background(), tc.uri)
+			if err != nil {
+				t.Fatalf("ReadResource returned error: %v", err)
+			}
+			content := result.Contents[0]
+			if content.URI != tc.uri || content.MIMEType != "application/json" || !strings.Contains(content.Text, tc.contains) {
+				t.Fatalf("unexpected content: %+v", content)
+			}
+			if !json.Valid([]byte(content.Text)) {
+				t.Fatalf("invalid JSON: %s", content.Text)
+			}
+			if len(paths) != 2 || !strings.HasPrefix(paths[0], "/v1/tenant/devices?") || paths[1] != tc.request {
+				t.Fatalf("unexpected requests: %v", paths)
+			}
+		})
+	}
+
+	if _, err := handler.ReadResource(context.Background(), "kapua://devices/unknown/inventory"); err == nil || !strings.Contains(err.Error(), `no device with client ID "unknown"`) {
+		t.Fatalf("expected unknown client ID error, got %v", err)
+	}
+	if _, err := handler.ReadResource(context.Background(), "kapua://devices/gw-1/logs"); err == nil || !strings.Contains(err.Error(), "unknown resource URI") {
+		t.Fatalf("expected unknown resource error, got %v", err)
+	}
+}
+
+func TestDeviceResourcesResolveFromFleetIndex(t *testing.T) {
+	handler, fleet := newIndexedHandler(t, timeNow())
+	if err := handler.fleetIndex.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh returned error: %v", err)
+	}
+	lists := fleet.count("/v1/tenant/devices")
+
+	result, err := handler.ReadResource(context.Background(), "kapua://devices/bravo")
+	if err != nil {
+		t.Fatalf("ReadResource returned error: %v", err)
+	}
+	var device models.Device
+	if err := json.Unmarshal([]byte(result.Contents[0].Text), &device); err != nil || device.ID != "dev-2" {
+		t.Fatalf("unexpected device %+v: %v", device, err)
+	}
+	if fleet.count("/v1/tenant/devices") != lists || fleet.count("/v1/tenant/devices/dev-2") != 1 {
+		t.Fatal("expected the client ID to be resolved from the index")
+	}
+}
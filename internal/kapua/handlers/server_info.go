@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// readServerInfoResource returns the Kapua version, build and detected flavour.
+// Detection normally runs at startup; it is retried here if it has not succeeded yet.
+func (h *KapuaHandler) readServerInfoResource(ctx context.Context) (*mcp.ReadResourceResult, error) {
+	info := h.client.ServerInfo()
+	if info == nil {
+		detected, err := h.client.DetectServerInfo(ctx)
+		if err != nil {
+			h.logger.WithContext(ctx).Error("Failed to read server info resource: %v", err)
+			return nil, fmt.Errorf("failed to read server info resource: %w", err)
+		}
+		info = detected
+	}
+
+	jsonData, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal server info resource: %w", err)
+	}
+
+	return &mcp.ReadResourceResult{
+		Contents: []*mcp.ResourceContents{
+			{
+				URI:      "kapua://server-info",
+				MIMEType: "application/json",
+				Text:     string(jsonData),
+			},
+		},
+	}, nil
+}
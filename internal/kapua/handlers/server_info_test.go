@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"kapua-mcp-server/internal/kapua/models"
+)
+
+func TestReadServerInfoResourceDetectsFlavour(t *testing.T) {
+	handler := newHandlerWithServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/sys-info":
+			_, _ = w.Write([]byte(`{"version":"2.0.0","buildNumber":"142","revision":"e53c7b7"}`))
+		case "/v1/tenant/deviceLogs":
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{"items":[]}`))
+		default:
+			t.Fatalf("unexpected path %s", r.URL.Path)
+		}
+	})
+
+	result, err := handler.ReadResource(context.Background(), "kapua://server-info")
+	if err != nil {
+		t.Fatalf("ReadResource returned error: %v", err)
+	}
+	if len(result.Contents) != 1 || result.Contents[0].URI != "kapua://server-info" {
+		t.Fatalf("unexpected resource contents: %+v", result.Contents)
+	}
+
+	var info models.ServerInfo
+	if err := json.Unmarshal([]byte(result.Contents[0].Text), &info); err != nil {
+		t.Fatalf("failed to unmarshal server info: %v", err)
+	}
+	if info.SystemInfo.Version != "2.0.0" || info.SystemInfo.BuildNumber != "142" {
+		t.Fatalf("unexpected system info: %+v", info.SystemInfo)
+	}
+	if info.Flavour != models.ServerFlavourEverywareCloud {
+		t.Fatalf("expected everyware-cloud flavour, got %s", info.Flavour)
+	}
+	if !info.Capabilities.DeviceLogs {
+		t.Fatal("expected device logs capability")
+	}
+}
+
+func TestReadServerInfoResourceError(t *testing.T) {
+	handler := newHandlerWithServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		_, _ = w.Write([]byte("bad gateway"))
+	})
+
+	if _, err := handler.ReadResource(context.Background(), "kapua://server-info"); err == nil {
+		t.Fatal("expected error when sys-info is unreachable")
+	}
+}
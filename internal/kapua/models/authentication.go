@@ -100,3 +100,12 @@ const (
 	CredentialStatusEnabled  CredentialStatus = "ENABLED"
 	CredentialStatusDisabled CredentialStatus = "DISABLED"
 )
+
+// MfaOption describes the multi-factor authentication settings of the current user (GET /user/mfa).
+type MfaOption struct {
+	KapuaEntity
+	UserID              KapuaID   `json:"userId,omitempty"`
+	TrustKey            string    `json:"trustKey,omitempty"`
+	HasTrustMe          bool      `json:"hasTrustMe,omitempty"`
+	TrustExpirationDate time.Time `json:"trustExpirationDate,omitempty"`
+}
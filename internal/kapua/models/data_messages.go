@@ -46,3 +46,22 @@ type DataMessageListResult struct {
 	TotalCount    int           `json:"totalCount,omitempty"`
 	Items         []DataMessage `json:"items,omitempty"`
 }
+
+// ChannelInfo describes a channel that data messages were published on.
+type ChannelInfo struct {
+	ID             string    `json:"id,omitempty"`
+	ScopeID        KapuaID   `json:"scopeId,omitempty"`
+	ClientID       string    `json:"clientId,omitempty"`
+	Name           string    `json:"name,omitempty"`
+	FirstMessageOn time.Time `json:"firstMessageOn,omitempty"`
+	LastMessageOn  time.Time `json:"lastMessageOn,omitempty"`
+}
+
+// ChannelInfoListResult represents a paginated list of channels.
+type ChannelInfoListResult struct {
+	Type          string        `json:"type,omitempty"`
+	LimitExceeded bool          `json:"limitExceeded,omitempty"`
+	Size          int           `json:"size,omitempty"`
+	TotalCount    int           `json:"totalCount,omitempty"`
+	Items         []ChannelInfo `json:"items,omitempty"`
+}
@@ -36,9 +36,18 @@ type KapuaQuery struct {
 
 // KapuaError represents a standard Kapua error response
 type KapuaError struct {
-	Code    string `json:"code,omitempty"`
-	Message string `json:"message,omitempty"`
-	Details string `json:"details,omitempty"`
+	Code           string `json:"code,omitempty"`
+	KapuaErrorCode string `json:"kapuaErrorCode,omitempty"`
+	Message        string `json:"message,omitempty"`
+	Details        string `json:"details,omitempty"`
+}
+
+// ErrorCode returns the Kapua error code, preferring kapuaErrorCode when both are set.
+func (e KapuaError) ErrorCode() string {
+	if e.KapuaErrorCode != "" {
+		return e.KapuaErrorCode
+	}
+	return e.Code
 }
 
 func (e KapuaError) Error() string {
@@ -25,6 +25,20 @@ func TestKapuaErrorErrorMessage(t *testing.T) {
 	}
 }
 
+func TestKapuaErrorErrorCode(t *testing.T) {
+	var err KapuaError
+	if jsonErr := json.Unmarshal([]byte(`{"type":"kapuaErrorMessage","httpErrorCode":401,"message":"MFA required","kapuaErrorCode":"REQUIRE_MFA_CREDENTIALS"}`), &err); jsonErr != nil {
+		t.Fatalf("unexpected unmarshal error: %v", jsonErr)
+	}
+	if err.ErrorCode() != "REQUIRE_MFA_CREDENTIALS" {
+		t.Fatalf("expected kapuaErrorCode to be preferred, got %q", err.ErrorCode())
+	}
+
+	if code := (KapuaError{Code: "ERR"}).ErrorCode(); code != "ERR" {
+		t.Fatalf("expected code fallback, got %q", code)
+	}
+}
+
 func TestAccessTokenJSONMarshalling(t *testing.T) {
 	payload := `{"tokenId":"new-token","refreshToken":"refresh-token","expiresOn":"2025-01-02T15:04:05Z","refreshExpiresOn":"2025-01-03T15:04:05Z","scopeId":"tenant","userId":"user-1"}`
 
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// SystemInfo mirrors the Kapua systemInfo schema returned by GET /sys-info.
+type SystemInfo struct {
+	Version     string `json:"version,omitempty"`
+	Revision    string `json:"revision,omitempty"`
+	BuildDate   string `json:"buildDate,omitempty"`
+	BuildNumber string `json:"buildNumber,omitempty"`
+	BuildBranch string `json:"buildBranch,omitempty"`
+}
+
+// ServerFlavour identifies which Kapua distribution is serving the REST API.
+type ServerFlavour string
+
+const (
+	ServerFlavourEclipseKapua   ServerFlavour = "eclipse-kapua"
+	ServerFlavourEverywareCloud ServerFlavour = "everyware-cloud"
+	ServerFlavourUnknown        ServerFlavour = "unknown"
+)
+
+// ServerCapabilities lists optional APIs whose availability depends on the flavour.
+type ServerCapabilities struct {
+	DeviceLogs bool `json:"deviceLogs"`
+}
+
+// ServerInfo combines the reported system info with the detected flavour and capabilities.
+type ServerInfo struct {
+	SystemInfo   SystemInfo         `json:"systemInfo"`
+	Flavour      ServerFlavour      `json:"flavour"`
+	Capabilities ServerCapabilities `json:"capabilities"`
+	DetectedAt   time.Time          `json:"detectedAt"`
+}
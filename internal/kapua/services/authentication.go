@@ -25,10 +25,27 @@ func (c *KapuaClient) SetTokenInfo(accessToken *models.AccessToken) {
 	c.refreshToken = accessToken.RefreshToken
 	c.refreshExpiry = accessToken.RefreshExpiresOn
 	c.scopeId = accessToken.ScopeID.String()
+	c.authPending = nil
 	c.logger.Debug("Token information updated - expires: %v, refresh expires: %v",
 		c.tokenExpiry.Format(time.RFC3339), c.refreshExpiry.Format(time.RFC3339))
 }
 
+// SetAuthenticationPending records that the client cannot authenticate until an
+// external step completes (for example an MFA code supplied by the MCP client).
+// Requests fail fast with err until a token is obtained.
+func (c *KapuaClient) SetAuthenticationPending(err error) {
+	c.tokenMutex.Lock()
+	defer c.tokenMutex.Unlock()
+	c.authPending = err
+}
+
+// pendingAuthentication returns the error set by SetAuthenticationPending, if any.
+func (c *KapuaClient) pendingAuthentication() error {
+	c.tokenMutex.RLock()
+	defer c.tokenMutex.RUnlock()
+	return c.authPending
+}
+
 // getToken safely retrieves the current token
 func (c *KapuaClient) getToken() string {
 	c.tokenMutex.RLock()
@@ -36,9 +53,41 @@ func (c *KapuaClient) getToken() string {
 	return c.token
 }
 
+// IsAuthenticated reports whether the client currently holds a Kapua token.
+func (c *KapuaClient) IsAuthenticated() bool {
+	return c.getToken() != ""
+}
+
+// TokenStatus describes the access token held by a client.
+type TokenStatus struct {
+	Authenticated bool       `json:"authenticated"`
+	ExpiresAt     *time.Time `json:"expiresAt,omitempty"`
+	Pending       string     `json:"pending,omitempty"` // Why authentication waits on an external step
+}
+
+// Valid reports whether the token can be used at now.
+func (s TokenStatus) Valid(now time.Time) bool {
+	return s.Authenticated && s.Pending == "" && (s.ExpiresAt == nil || now.Before(*s.ExpiresAt))
+}
+
+// TokenStatus reports whether the client holds a token and when it expires.
+func (c *KapuaClient) TokenStatus() TokenStatus {
+	c.tokenMutex.RLock()
+	defer c.tokenMutex.RUnlock()
+	status := TokenStatus{Authenticated: c.token != ""}
+	if !c.tokenExpiry.IsZero() {
+		expiresAt := c.tokenExpiry
+		status.ExpiresAt = &expiresAt
+	}
+	if c.authPending != nil {
+		status.Pending = c.authPending.Error()
+	}
+	return status
+}
+
 // refreshTokenIfNeeded refreshes an access token when expiring soon or already expired.
 // If already expired and the refresh token is also expired, it falls back to QuickAuthenticate.
-func (c *KapuaClient) refreshTokenIfNeeded(ctx context.Context) error {
+func (c *KapuaClient) refreshTokenIfNeeded(ctx context.Context) (err error) {
 	if !c.autoRefresh {
 		return nil
 	}
@@ -63,6 +112,14 @@ func (c *KapuaClient) refreshTokenIfNeeded(ctx context.Context) error {
 		return nil
 	}
 
+	defer func() {
+		if err != nil {
+			kapuaTokenRefreshes.Inc("failure")
+		} else {
+			kapuaTokenRefreshes.Inc("success")
+		}
+	}()
+
 	// If already expired, ensure refresh token is still valid; otherwise re-authenticate
 	if expired {
 		if refreshToken == "" {
@@ -86,8 +143,7 @@ func (c *KapuaClient) refreshTokenIfNeeded(ctx context.Context) error {
 		RefreshToken: refreshToken,
 		TokenID:      tokenID,
 	}
-	_, err := c.RefreshToken(ctx, request)
-	if err != nil {
+	if _, err := c.RefreshToken(ctx, request); err != nil {
 		c.logger.Error("Automatic token refresh failed: %v", err)
 		return err
 	}
@@ -205,7 +261,9 @@ func (c *KapuaClient) Logout(ctx context.Context) error {
 // QuickAuthenticate performs a quick authentication using configured credentials.
 // It selects the authentication method based on the configured AuthMethod:
 //   - "apikey": authenticates with the configured API key
-//   - "password" or "" (default): authenticates with username and password
+//   - "jwt": exchanges the configured JWT (inline or read from a file) for a Kapua token
+//   - "password" or "" (default): authenticates with username and password, adding
+//     a stored trust key or an MFA code (static or TOTP) when configured
 //
 // An explicit error is returned for unrecognised AuthMethod values.
 func (c *KapuaClient) QuickAuthenticate(ctx context.Context) (*models.AccessToken, error) {
@@ -215,15 +273,18 @@ func (c *KapuaClient) QuickAuthenticate(ctx context.Context) (*models.AccessToke
 			return nil, fmt.Errorf("AuthMethod is \"apikey\" but no APIKey is configured")
 		}
 		return c.AuthenticateAPIKey(ctx, models.APIKeyCredentials{APIKey: c.config.APIKey})
+	case "jwt":
+		jwt, err := c.configuredJWT()
+		if err != nil {
+			return nil, err
+		}
+		return c.AuthenticateJWT(ctx, models.JWTCredentials{JWT: jwt})
 	case "password", "":
 		if c.config.Username == "" || c.config.Password == "" {
 			return nil, fmt.Errorf("AuthMethod is \"password\" but Username or Password is not configured")
 		}
-		return c.AuthenticateUser(ctx, models.UsernamePasswordCredentials{
-			Username: c.config.Username,
-			Password: c.config.Password,
-		})
+		return c.authenticatePassword(ctx, "")
 	default:
-		return nil, fmt.Errorf("unsupported AuthMethod %q: must be \"password\" or \"apikey\"", c.config.AuthMethod)
+		return nil, fmt.Errorf("unsupported AuthMethod %q: must be \"password\", \"apikey\" or \"jwt\"", c.config.AuthMethod)
 	}
 }
@@ -138,9 +138,13 @@ func TestRefreshTokenIfNeededExpiringSoonRefreshSuccess(t *testing.T) {
 	client.refreshExpiry = now.Add(time.Hour)
 	client.tokenMutex.Unlock()
 
+	successes := kapuaTokenRefreshes.Value("success")
 	if err := client.refreshTokenIfNeeded(context.Background()); err != nil {
 		t.Fatalf("expected nil error, got %v", err)
 	}
+	if got := kapuaTokenRefreshes.Value("success") - successes; got != 1 {
+		t.Fatalf("expected one successful refresh to be counted, got %v", got)
+	}
 	if refreshCalled != 1 {
 		t.Fatalf("expected RefreshToken to be called once, got %d", refreshCalled)
 	}
@@ -239,10 +243,14 @@ func TestRefreshTokenIfNeededRefreshFails(t *testing.T) {
 	client.refreshExpiry = time.Now().Add(time.Hour)
 	client.tokenMutex.Unlock()
 
+	failures := kapuaTokenRefreshes.Value("failure")
 	err := client.refreshTokenIfNeeded(context.Background())
 	if err == nil {
 		t.Fatal("expected error from refresh failure")
 	}
+	if got := kapuaTokenRefreshes.Value("failure") - failures; got != 1 {
+		t.Fatalf("expected one failed refresh to be counted, got %v", got)
+	}
 	if !strings.Contains(err.Error(), "bad") {
 		t.Fatalf("expected error to include response body, got %v", err)
 	}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"kapua-mcp-server/internal/kapua/config"
+	"kapua-mcp-server/pkg/metrics"
+)
+
+// Circuit breaker states as reported by BreakerStatus.
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
+
+var kapuaCircuitState = metrics.NewGauge(
+	"kapua_circuit_state",
+	"State of the Kapua circuit breaker: 0 closed, 1 half-open, 2 open.")
+
+// UnavailableError is returned without contacting Kapua while the circuit is open.
+type UnavailableError struct {
+	Since     time.Time // First failure of the outage
+	Failures  int       // Consecutive failed calls
+	RetryAt   time.Time // When the next probe is allowed
+	LastError string
+}
+
+func (e *UnavailableError) Error() string {
+	msg := fmt.Sprintf("Kapua unavailable since %s (%d consecutive failures); next attempt after %s",
+		e.Since.UTC().Format(time.RFC3339), e.Failures, e.RetryAt.UTC().Format(time.RFC3339))
+	if e.LastError != "" {
+		msg += ": last error: " + e.LastError
+	}
+	return msg
+}
+
+// BreakerStatus is a snapshot of the circuit breaker for health reporting.
+type BreakerStatus struct {
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutiveFailures,omitempty"`
+	UnavailableSince    *time.Time `json:"unavailableSince,omitempty"`
+	RetryAt             *time.Time `json:"retryAt,omitempty"`
+	LastError           string     `json:"lastError,omitempty"`
+}
+
+// circuitBreaker stops calling Kapua after threshold consecutive failed calls. While
+// open, calls fail immediately; after cooldown a single probe call is let through
+// (half-open) and its outcome closes or re-opens the circuit. A nil breaker lets
+// every call through.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	state     string
+	failures  int
+	since     time.Time // First failure in the current run
+	openedAt  time.Time
+	probing   bool
+	lastError string
+}
+
+// newCircuitBreaker returns nil when cfg disables the breaker.
+func newCircuitBreaker(cfg *config.KapuaConfig) *circuitBreaker {
+	if cfg.BreakerThreshold <= 0 {
+		return nil
+	}
+	return &circuitBreaker{threshold: cfg.BreakerThreshold, cooldown: cfg.BreakerCooldown, now: time.Now, state: CircuitClosed}
+}
+
+// allow reports whether a call may proceed. probe is true for the single call
+// admitted in the half-open state; its outcome must be reported.
+func (b *circuitBreaker) allow() (probe bool, err error) {
+	if b == nil {
+		return false, nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case CircuitClosed:
+		return false, nil
+	case CircuitOpen:
+		if b.now().Before(b.openedAt.Add(b.cooldown)) {
+			return false, b.unavailable()
+		}
+		b.setState(CircuitHalfOpen)
+	}
+	if b.probing {
+		return false, b.unavailable()
+	}
+	b.probing = true
+	return true, nil
+}
+
+func (b *circuitBreaker) unavailable() error {
+	return &UnavailableError{Since: b.since, Failures: b.failures, RetryAt: b.openedAt.Add(b.cooldown), LastError: b.lastError}
+}
+
+// success records a call that reached Kapua and got a usable answer.
+func (b *circuitBreaker) success(probe bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if probe {
+		b.probing = false
+	}
+	b.failures, b.lastError, b.since = 0, "", time.Time{}
+	b.setState(CircuitClosed)
+}
+
+// failure records a call that could not reach Kapua or got a gateway error.
+func (b *circuitBreaker) failure(probe bool, cause string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if probe {
+		b.probing = false
+	}
+	now := b.now()
+	if b.failures == 0 {
+		b.since = now
+	}
+	b.failures++
+	b.lastError = cause
+	if probe || b.state == CircuitHalfOpen || b.failures >= b.threshold {
+		b.openedAt = now
+		b.setState(CircuitOpen)
+	}
+}
+
+// abandon releases a probe whose outcome says nothing about Kapua, for example
+// because the caller cancelled it.
+func (b *circuitBreaker) abandon(probe bool) {
+	if b == nil || !probe {
+		return
+	}
+	b.mu.Lock()
+	b.probing = false
+	b.mu.Unlock()
+}
+
+// record classifies the final result of a request for the breaker.
+func (b *circuitBreaker) record(ctx context.Context, probe bool, resp *http.Response, err error) {
+	switch {
+	case err != nil && ctx.Err() != nil:
+		b.abandon(probe)
+	case err != nil:
+		b.failure(probe, err.Error())
+	case resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusServiceUnavailable || resp.StatusCode == http.StatusGatewayTimeout:
+		b.failure(probe, fmt.Sprintf("HTTP %d", resp.StatusCode))
+	default:
+		b.success(probe)
+	}
+}
+
+func (b *circuitBreaker) setState(state string) {
+	b.state = state
+	switch state {
+	case CircuitOpen:
+		kapuaCircuitState.Set(2)
+	case CircuitHalfOpen:
+		kapuaCircuitState.Set(1)
+	default:
+		kapuaCircuitState.Set(0)
+	}
+}
+
+func (b *circuitBreaker) status() BreakerStatus {
+	if b == nil {
+		return BreakerStatus{State: CircuitClosed}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	status := BreakerStatus{State: b.state, ConsecutiveFailures: b.failures, LastError: b.lastError}
+	if b.state == CircuitOpen && !b.now().Before(b.openedAt.Add(b.cooldown)) {
+		status.State = CircuitHalfOpen
+	}
+	if b.state != CircuitClosed {
+		since, retryAt := b.since, b.openedAt.Add(b.cooldown)
+		status.UnavailableSince, status.RetryAt = &since, &retryAt
+	}
+	return status
+}
+
+// BreakerStatus reports the state of the circuit breaker guarding Kapua calls.
+func (c *KapuaClient) BreakerStatus() BreakerStatus {
+	return c.breaker.status()
+}
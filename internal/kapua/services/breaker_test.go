@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"kapua-mcp-server/internal/kapua/config"
+)
+
+func newTestBreaker(threshold int, cooldown time.Duration) (*circuitBreaker, *time.Time) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	b := newCircuitBreaker(&config.KapuaConfig{BreakerThreshold: threshold, BreakerCooldown: cooldown})
+	b.now = func() time.Time { return now }
+	return b, &now
+}
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	b, now := newTestBreaker(3, time.Minute)
+	start := *now
+
+	for i := 0; i < 2; i++ {
+		if _, err := b.allow(); err != nil {
+			t.Fatalf("closed circuit must allow calls: %v", err)
+		}
+		b.failure(false, "dial tcp: connection refused")
+		*now = now.Add(time.Second)
+	}
+	b.success(false)
+	if status := b.status(); status.State != CircuitClosed || status.ConsecutiveFailures != 0 {
+		t.Fatalf("success must reset failures: %+v", status)
+	}
+
+	for i := 0; i < 3; i++ {
+		b.failure(false, "dial tcp: connection refused")
+	}
+	_, err := b.allow()
+	var unavailable *UnavailableError
+	if !errors.As(err, &unavailable) {
+		t.Fatalf("expected UnavailableError, got %v", err)
+	}
+	if !unavailable.Since.Equal(start.Add(2*time.Second)) || unavailable.Failures != 3 {
+		t.Fatalf("unexpected error details: %+v", unavailable)
+	}
+	if !strings.Contains(err.Error(), "Kapua unavailable since 2024-05-01T12:00:02Z") || !strings.Contains(err.Error(), "connection refused") {
+		t.Fatalf("unexpected message: %v", err)
+	}
+
+	*now = now.Add(time.Minute)
+	if status := b.status(); status.State != CircuitHalfOpen {
+		t.Fatalf("expected half-open after the cooldown, got %s", status.State)
+	}
+	probe, err := b.allow()
+	if err != nil || !probe {
+		t.Fatalf("expected a probe after the cooldown, got %v %v", probe, err)
+	}
+	if _, err := b.allow(); err == nil {
+		t.Fatal("only one probe may run at a time")
+	}
+
+	b.failure(true, "HTTP 503")
+	if status := b.status(); status.State != CircuitOpen || status.UnavailableSince == nil || !status.UnavailableSince.Equal(start.Add(2*time.Second)) {
+		t.Fatalf("failed probe must re-open the circuit and keep the outage start: %+v", status)
+	}
+
+	*now = now.Add(time.Minute)
+	probe, _ = b.allow()
+	b.abandon(probe)
+	probe, err = b.allow()
+	if err != nil || !probe {
+		t.Fatalf("abandoned probe must let another probe through: %v", err)
+	}
+	b.success(probe)
+	if status := b.status(); status.State != CircuitClosed || status.UnavailableSince != nil {
+		t.Fatalf("successful probe must close the circuit: %+v", status)
+	}
+}
+
+func TestMakeRequestFailsFastWhenCircuitOpen(t *testing.T) {
+	var calls atomic.Int32
+	healthy := atomic.Bool{}
+	client := newTestKapuaClient()
+	client.breaker, _ = newTestBreaker(2, time.Minute)
+	client.breaker.now = time.Now
+	client.httpClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls.Add(1)
+		if healthy.Load() {
+			return &http.Response{StatusCode: http.StatusNotFound, Header: make(http.Header), Body: io.NopCloser(strings.NewReader(`{"message":"not found"}`))}, nil
+		}
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Header: make(http.Header), Body: io.NopCloser(strings.NewReader("down"))}, nil
+	})}
+
+	for i := 0; i < 2; i++ {
+		_, _ = client.GetDevice(context.Background(), "dev-1")
+	}
+	_, err := client.GetDevice(context.Background(), "dev-1")
+	var unavailable *UnavailableError
+	if !errors.As(err, &unavailable) {
+		t.Fatalf("expected fail-fast error, got %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("open circuit must not contact Kapua, got %d calls", calls.Load())
+	}
+	if client.BreakerStatus().State != CircuitOpen {
+		t.Fatalf("expected open circuit, got %+v", client.BreakerStatus())
+	}
+
+	// A 4xx answer proves Kapua is reachable and closes the circuit.
+	client.breaker.openedAt = time.Now().Add(-time.Hour)
+	healthy.Store(true)
+	if _, err := client.GetDevice(context.Background(), "dev-1"); err == nil || errors.As(err, &unavailable) {
+		t.Fatalf("expected the probe to reach Kapua, got %v", err)
+	}
+	if client.BreakerStatus().State != CircuitClosed {
+		t.Fatalf("expected closed circuit after the probe, got %+v", client.BreakerStatus())
+	}
+}
+
+func TestMakeRequestCancelledCallDoesNotTripCircuit(t *testing.T) {
+	client := newTestKapuaClient()
+	client.breaker, _ = newTestBreaker(1, time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	client.httpClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		cancel()
+		return nil, context.Canceled
+	})}
+
+	_, _ = client.GetDevice(ctx, "dev-1")
+	if status := client.BreakerStatus(); status.State != CircuitClosed || status.ConsecutiveFailures != 0 {
+		t.Fatalf("caller cancellation must not count as a Kapua failure: %+v", status)
+	}
+}
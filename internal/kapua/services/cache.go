@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"kapua-mcp-server/internal/kapua/config"
+)
+
+// Kinds of cached Kapua data, each with its own TTL.
+const (
+	cacheDevices        = "devices"
+	cacheInventory      = "inventory"
+	cacheSnapshots      = "snapshots"
+	cacheConfigurations = "configurations"
+)
+
+// maxCacheEntries bounds the memory used by a response cache.
+const maxCacheEntries = 1024
+
+type cacheBypassKey struct{}
+
+// WithoutCache returns a context whose Kapua reads skip the response cache. The
+// fresh answers still replace the cached ones.
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+func cacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(cacheBypassKey{}).(bool)
+	return bypass
+}
+
+type cacheEntry struct {
+	body    []byte
+	kind    string
+	device  string // Device the entry belongs to, empty for device lists
+	expires time.Time
+}
+
+// responseCache keeps successful GET responses for read-mostly data. Keys are
+// endpoints, which start with the scope ID. A client shares its cache with nobody:
+// session clients get their own, since Kapua may show other users different data.
+// A nil responseCache caches nothing.
+type responseCache struct {
+	mu         sync.Mutex
+	now        func() time.Time
+	ttls       map[string]time.Duration
+	entries    map[string]cacheEntry
+	generation uint64 // Incremented by every invalidation
+}
+
+// newResponseCache returns nil when cfg disables caching of every kind of data.
+func newResponseCache(cfg *config.KapuaConfig) *responseCache {
+	ttls := map[string]time.Duration{}
+	for kind, ttl := range map[string]time.Duration{
+		cacheDevices:        cfg.CacheTTLDevices,
+		cacheInventory:      cfg.CacheTTLInventory,
+		cacheSnapshots:      cfg.CacheTTLSnapshots,
+		cacheConfigurations: cfg.CacheTTLConfigurations,
+	} {
+		if ttl > 0 {
+			ttls[kind] = ttl
+		}
+	}
+	if len(ttls) == 0 {
+		return nil
+	}
+	return &responseCache{now: time.Now, ttls: ttls, entries: map[string]cacheEntry{}}
+}
+
+// newSession returns an empty cache with the same TTLs.
+func (rc *responseCache) newSession() *responseCache {
+	if rc == nil {
+		return nil
+	}
+	return &responseCache{now: rc.now, ttls: rc.ttls, entries: map[string]cacheEntry{}}
+}
+
+// classifyEndpoint returns the kind of data an endpoint serves, or "" when it is
+// not cached, and the device it belongs to.
+func classifyEndpoint(endpoint string) (kind, device string) {
+	path, _, _ := strings.Cut(endpoint, "?")
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	if len(segments) < 2 || segments[1] != "devices" {
+		return "", ""
+	}
+	if len(segments) == 2 {
+		return cacheDevices, ""
+	}
+	device = segments[2]
+	if device == "" || strings.HasPrefix(device, "_") {
+		return "", ""
+	}
+	if len(segments) == 3 {
+		return cacheDevices, device
+	}
+	switch segments[3] {
+	case cacheInventory, cacheSnapshots, cacheConfigurations:
+		kind = segments[3]
+	}
+	if strings.HasPrefix(segments[len(segments)-1], "_") {
+		// Operations such as _rollback or _start are never cached.
+		kind = ""
+	}
+	return kind, device
+}
+
+// get returns the cached body for endpoint, or nil. The generation must be passed
+// to put so that an answer fetched across an invalidation is not stored.
+func (rc *responseCache) get(ctx context.Context, endpoint string) ([]byte, uint64) {
+	if rc == nil {
+		return nil, 0
+	}
+	kind, _ := classifyEndpoint(endpoint)
+	if rc.ttls[kind] == 0 {
+		return nil, 0
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if cacheBypassed(ctx) {
+		kapuaCacheRequests.Inc(kind, "bypass")
+		return nil, rc.generation
+	}
+	entry, ok := rc.entries[endpoint]
+	if !ok || !rc.now().Before(entry.expires) {
+		kapuaCacheRequests.Inc(kind, "miss")
+		return nil, rc.generation
+	}
+	kapuaCacheRequests.Inc(kind, "hit")
+	return entry.body, rc.generation
+}
+
+// put stores the body of a successful GET.
+func (rc *responseCache) put(endpoint string, body []byte, generation uint64) {
+	if rc == nil {
+		return
+	}
+	kind, device := classifyEndpoint(endpoint)
+	ttl := rc.ttls[kind]
+	if ttl == 0 {
+		return
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if generation != rc.generation {
+		return
+	}
+	now := rc.now()
+	if _, ok := rc.entries[endpoint]; !ok && len(rc.entries) >= maxCacheEntries {
+		rc.evict(now)
+	}
+	rc.entries[endpoint] = cacheEntry{body: body, kind: kind, device: device, expires: now.Add(ttl)}
+}
+
+// evict drops expired entries, or the one closest to expiry if none has expired.
+func (rc *responseCache) evict(now time.Time) {
+	var oldestKey string
+	var oldest time.Time
+	for key, entry := range rc.entries {
+		if !now.Before(entry.expires) {
+			delete(rc.entries, key)
+			continue
+		}
+		if oldestKey == "" || entry.expires.Before(oldest) {
+			oldestKey, oldest = key, entry.expires
+		}
+	}
+	if len(rc.entries) >= maxCacheEntries {
+		delete(rc.entries, oldestKey)
+	}
+}
+
+// invalidate drops what a mutating request to endpoint may have changed: every
+// entry of the target device and all device lists.
+func (rc *responseCache) invalidate(endpoint string) {
+	if rc == nil {
+		return
+	}
+	path, _, _ := strings.Cut(endpoint, "?")
+	if strings.HasSuffix(path, "/_read") {
+		// Reads sent as POST, such as asset reads, change nothing.
+		return
+	}
+	_, device := classifyEndpoint(endpoint)
+	if device == "" {
+		return
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.generation++
+	for key, entry := range rc.entries {
+		if entry.device == device || (entry.kind == cacheDevices && entry.device == "") {
+			delete(rc.entries, key)
+		}
+	}
+}
+
+// ForgetDevice drops the cached data of a device, and all cached device lists,
+// after it was seen to change outside this client.
+func (c *KapuaClient) ForgetDevice(deviceID string) {
+	c.cache.invalidate(c.scopedEndpoint("/devices/%s", deviceID))
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"kapua-mcp-server/internal/kapua/config"
+)
+
+func TestClassifyEndpoint(t *testing.T) {
+	cases := []struct {
+		endpoint, kind, device string
+	}{
+		{"/tenant/devices?limit=5", cacheDevices, ""},
+		{"/tenant/devices/dev-1", cacheDevices, "dev-1"},
+		{"/tenant/devices/dev-1/inventory/bundles", cacheInventory, "dev-1"},
+		{"/tenant/devices/dev-1/snapshots/snap-1", cacheSnapshots, "dev-1"},
+		{"/tenant/devices/dev-1/configurations/comp", cacheConfigurations, "dev-1"},
+		{"/tenant/devices/dev-1/snapshots/snap-1/_rollback", "", "dev-1"},
+		{"/tenant/devices/dev-1/events?limit=1", "", "dev-1"},
+		{"/tenant/devices/_count", "", ""},
+		{"/sys-info", "", ""},
+	}
+	for _, tc := range cases {
+		kind, device := classifyEndpoint(tc.endpoint)
+		if kind != tc.kind || device != tc.device {
+			t.Errorf("classifyEndpoint(%q) = %q, %q; want %q, %q", tc.endpoint, kind, device, tc.kind, tc.device)
+		}
+	}
+}
+
+// newCachingTestClient returns a client whose Kapua counts the requests per path.
+func newCachingTestClient(t *testing.T, calls map[string]*atomic.Int32) (*KapuaClient, *time.Time) {
+	t.Helper()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	client := newTestKapuaClient()
+	client.cache = newResponseCache(&config.KapuaConfig{CacheTTLDevices: 10 * time.Second, CacheTTLSnapshots: time.Minute})
+	client.cache.now = func() time.Time { return now }
+	client.httpClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		key := req.Method + " " + req.URL.Path
+		if calls[key] == nil {
+			t.Fatalf("unexpected request %s", key)
+		}
+		calls[key].Add(1)
+		body := `{"items":[],"id":"dev-1","snapshotId":[]}`
+		if strings.HasSuffix(req.URL.Path, "/broken") {
+			return &http.Response{StatusCode: http.StatusNotFound, Header: make(http.Header), Body: io.NopCloser(strings.NewReader(`{"message":"missing"}`))}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Body: io.NopCloser(strings.NewReader(body))}, nil
+	})}
+	return client, &now
+}
+
+func TestResponseCacheServesFreshEntries(t *testing.T) {
+	calls := map[string]*atomic.Int32{
+		"GET /v1/tenant/devices":                 {},
+		"GET /v1/tenant/devices/dev-1":           {},
+		"GET /v1/tenant/devices/broken":          {},
+		"GET /v1/tenant/devices/dev-1/inventory": {},
+	}
+	client, now := newCachingTestClient(t, calls)
+	ctx := context.Background()
+
+	for range 3 {
+		if _, err := client.ListDevices(ctx, map[string]string{"limit": "5"}); err != nil {
+			t.Fatalf("ListDevices returned error: %v", err)
+		}
+		_, _ = client.GetDevice(ctx, "broken")
+		if _, err := client.ReadDeviceInventory(ctx, "dev-1"); err != nil {
+			t.Fatalf("ReadDeviceInventory returned error: %v", err)
+		}
+	}
+	if calls["GET /v1/tenant/devices"].Load() != 1 {
+		t.Fatalf("expected one device list request, got %d", calls["GET /v1/tenant/devices"].Load())
+	}
+	if calls["GET /v1/tenant/devices/broken"].Load() != 3 {
+		t.Fatalf("errors must not be cached, got %d requests", calls["GET /v1/tenant/devices/broken"].Load())
+	}
+	if calls["GET /v1/tenant/devices/dev-1/inventory"].Load() != 3 {
+		t.Fatalf("inventory has no TTL and must not be cached, got %d requests", calls["GET /v1/tenant/devices/dev-1/inventory"].Load())
+	}
+
+	// A different query is a different entry.
+	_, _ = client.ListDevices(ctx, map[string]string{"limit": "10"})
+	if calls["GET /v1/tenant/devices"].Load() != 2 {
+		t.Fatalf("expected a request for another query, got %d", calls["GET /v1/tenant/devices"].Load())
+	}
+
+	_, _ = client.ListDevices(WithoutCache(ctx), map[string]string{"limit": "5"})
+	if calls["GET /v1/tenant/devices"].Load() != 3 {
+		t.Fatalf("expected bypass to reach Kapua, got %d", calls["GET /v1/tenant/devices"].Load())
+	}
+
+	*now = now.Add(10 * time.Second)
+	_, _ = client.ListDevices(ctx, map[string]string{"limit": "5"})
+	if calls["GET /v1/tenant/devices"].Load() != 4 {
+		t.Fatalf("expected expired entry to be refetched, got %d", calls["GET /v1/tenant/devices"].Load())
+	}
+}
+
+func TestResponseCacheInvalidatedByMutations(t *testing.T) {
+	calls := map[string]*atomic.Int32{
+		"GET /v1/tenant/devices":                               {},
+		"GET /v1/tenant/devices/dev-1/snapshots":               {},
+		"GET /v1/tenant/devices/dev-2/snapshots":               {},
+		"POST /v1/tenant/devices/dev-1/snapshots/s1/_rollback": {},
+	}
+	client, _ := newCachingTestClient(t, calls)
+	ctx := context.Background()
+
+	read := func() {
+		t.Helper()
+		if _, err := client.ListDevices(ctx, nil); err != nil {
+			t.Fatalf("ListDevices returned error: %v", err)
+		}
+		for _, device := range []string{"dev-1", "dev-2"} {
+			if _, err := client.ListDeviceSnapshots(ctx, device); err != nil {
+				t.Fatalf("ListDeviceSnapshots returned error: %v", err)
+			}
+		}
+	}
+	read()
+	read()
+	if err := client.RollbackDeviceSnapshot(ctx, "dev-1", "s1"); err != nil {
+		t.Fatalf("RollbackDeviceSnapshot returned error: %v", err)
+	}
+	read()
+	client.ForgetDevice("dev-2")
+	read()
+
+	for key, want := range map[string]int32{
+		"GET /v1/tenant/devices":                 3,
+		"GET /v1/tenant/devices/dev-1/snapshots": 2,
+		"GET /v1/tenant/devices/dev-2/snapshots": 2,
+	} {
+		if got := calls[key].Load(); got != want {
+			t.Errorf("%s: got %d requests, want %d", key, got, want)
+		}
+	}
+}
+
+func TestResponseCacheSkipsAnswersFetchedAcrossInvalidation(t *testing.T) {
+	cache := newResponseCache(&config.KapuaConfig{CacheTTLDevices: time.Minute})
+	body, generation := cache.get(context.Background(), "/tenant/devices/dev-1")
+	if body != nil {
+		t.Fatal("expected a miss")
+	}
+	cache.invalidate("/tenant/devices/dev-1/bundles/b/_start")
+	cache.put("/tenant/devices/dev-1", []byte(`{}`), generation)
+	if body, _ := cache.get(context.Background(), "/tenant/devices/dev-1"); body != nil {
+		t.Fatal("an answer fetched before the invalidation must not be cached")
+	}
+}
+
+func TestResponseCacheBounded(t *testing.T) {
+	cache := newResponseCache(&config.KapuaConfig{CacheTTLDevices: time.Minute})
+	for i := range maxCacheEntries + 10 {
+		cache.put(fmt.Sprintf("/tenant/devices?offset=%d", i), []byte(`{}`), 0)
+	}
+	if len(cache.entries) > maxCacheEntries {
+		t.Fatalf("expected at most %d entries, got %d", maxCacheEntries, len(cache.entries))
+	}
+	if newResponseCache(&config.KapuaConfig{}) != nil {
+		t.Fatal("expected no cache when every TTL is 0")
+	}
+}
@@ -77,3 +77,30 @@ func (c *KapuaClient) ListDataMessages(ctx context.Context, query *DataMessagesQ
 	c.logger.Info("Listed %d data messages successfully", len(result.Items))
 	return &result, nil
 }
+
+// ListDataChannels queries the channels that data messages were published on,
+// optionally only those of clientID.
+func (c *KapuaClient) ListDataChannels(ctx context.Context, clientID string, limit int) (*models.ChannelInfoListResult, error) {
+	c.logger.Info("Listing data channels for scope: %s", c.scopeId)
+
+	params := url.Values{}
+	if clientID != "" {
+		params.Set("clientId", clientID)
+	}
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+
+	endpoint := c.scopedEndpoint("/data/channels")
+	if encoded := params.Encode(); encoded != "" {
+		endpoint += "?" + encoded
+	}
+
+	var result models.ChannelInfoListResult
+	if err := c.doKapuaRequest(ctx, http.MethodGet, endpoint, "list data channels", nil, &result); err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Listed %d data channels successfully", len(result.Items))
+	return &result, nil
+}
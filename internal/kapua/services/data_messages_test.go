@@ -171,3 +171,29 @@ func TestDataMessagesQueryNil(t *testing.T) {
 		t.Fatalf("expected empty values for nil query, got %v", values)
 	}
 }
+
+func TestListDataChannels(t *testing.T) {
+	client := newTestKapuaClient()
+
+	client.httpClient = &http.Client{Transport: dataMessageRoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path != "/v1/tenant/data/channels" {
+			t.Fatalf("unexpected path %s", req.URL.Path)
+		}
+		if got := req.URL.Query(); got.Get("clientId") != "gw-1" || got.Get("limit") != "100" {
+			t.Fatalf("unexpected query %q", req.URL.RawQuery)
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"type":"channelInfoListResult","totalCount":1,"items":[{"clientId":"gw-1","name":"sensors/room","lastMessageOn":"2023-09-12T09:35:04.383Z"}]}`)),
+			Header:     make(http.Header),
+		}, nil
+	})}
+
+	result, err := client.ListDataChannels(context.Background(), "gw-1", 100)
+	if err != nil {
+		t.Fatalf("ListDataChannels returned error: %v", err)
+	}
+	if len(result.Items) != 1 || result.Items[0].Name != "sensors/room" || result.Items[0].LastMessageOn.IsZero() {
+		t.Fatalf("unexpected channels: %+v", result)
+	}
+}
@@ -80,6 +80,10 @@ func (q *DeviceLogsQuery) toValues() url.Values {
 func (c *KapuaClient) ListDeviceLogs(ctx context.Context, query *DeviceLogsQuery) (*models.DeviceLogListResult, error) {
 	c.logger.Info("Listing device logs for scope: %s", c.scopeId)
 
+	if info := c.ServerInfo(); info != nil && !info.Capabilities.DeviceLogs {
+		return nil, ErrDeviceLogsNotSupported
+	}
+
 	params := query.toValues()
 
 	endpoint := c.scopedEndpoint("/deviceLogs")
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// jwtFileCache remembers the last token read from the JWT file together with the
+// file's modification time and size, so the file is only re-read after it rotates.
+// Kubernetes projected service-account tokens are swapped atomically through a
+// symlink, which changes both values.
+type jwtFileCache struct {
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	token   string
+}
+
+// configuredJWT returns the token used for KAPUA_AUTH_METHOD=jwt: the inline value
+// when set, otherwise the current content of the JWT file.
+func (c *KapuaClient) configuredJWT() (string, error) {
+	if c.config.JWT != "" {
+		return c.config.JWT, nil
+	}
+	if c.config.JWTFile == "" {
+		return "", fmt.Errorf("AuthMethod is \"jwt\" but neither JWT nor JWTFile is configured")
+	}
+	return c.jwtFile.read(c.config.JWTFile)
+}
+
+// read returns the token stored in path, re-reading the file when it has changed.
+func (f *jwtFileCache) read(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to stat JWT file: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.token != "" && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return f.token, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read JWT file: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("JWT file %s is empty", path)
+	}
+
+	f.token = token
+	f.modTime = info.ModTime()
+	f.size = info.Size()
+	return token, nil
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"kapua-mcp-server/internal/kapua/config"
+	"kapua-mcp-server/internal/kapua/models"
+)
+
+func TestQuickAuthenticateUsesInlineJWT(t *testing.T) {
+	client := newMFATestClient(&config.KapuaConfig{AuthMethod: "jwt", JWT: "inline-jwt"}, func(req *http.Request) (*http.Response, error) {
+		if !strings.HasSuffix(req.URL.Path, "/authentication/jwt") {
+			t.Fatalf("expected /authentication/jwt, got %s", req.URL.Path)
+		}
+		var creds models.JWTCredentials
+		_ = json.NewDecoder(req.Body).Decode(&creds)
+		if creds.JWT != "inline-jwt" {
+			t.Fatalf("unexpected JWT %q", creds.JWT)
+		}
+		return jsonResponse(http.StatusOK, mfaTokenJSON), nil
+	})
+
+	if _, err := client.QuickAuthenticate(context.Background()); err != nil {
+		t.Fatalf("QuickAuthenticate returned error: %v", err)
+	}
+}
+
+func TestQuickAuthenticateRereadsRotatedJWTFile(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("first-jwt\n"), 0o600); err != nil {
+		t.Fatalf("failed to write token: %v", err)
+	}
+
+	var sent []string
+	client := newMFATestClient(&config.KapuaConfig{AuthMethod: "jwt", JWTFile: tokenFile}, func(req *http.Request) (*http.Response, error) {
+		var creds models.JWTCredentials
+		_ = json.NewDecoder(req.Body).Decode(&creds)
+		sent = append(sent, creds.JWT)
+		return jsonResponse(http.StatusOK, mfaTokenJSON), nil
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.QuickAuthenticate(context.Background()); err != nil {
+			t.Fatalf("QuickAuthenticate returned error: %v", err)
+		}
+	}
+
+	if err := os.WriteFile(tokenFile, []byte("rotated-jwt-value\n"), 0o600); err != nil {
+		t.Fatalf("failed to rotate token: %v", err)
+	}
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(tokenFile, future, future); err != nil {
+		t.Fatalf("failed to touch token: %v", err)
+	}
+	if _, err := client.QuickAuthenticate(context.Background()); err != nil {
+		t.Fatalf("QuickAuthenticate returned error: %v", err)
+	}
+
+	expected := []string{"first-jwt", "first-jwt", "rotated-jwt-value"}
+	if strings.Join(sent, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected tokens %v, got %v", expected, sent)
+	}
+}
+
+func TestQuickAuthenticateMissingJWTFile(t *testing.T) {
+	client := newMFATestClient(&config.KapuaConfig{AuthMethod: "jwt", JWTFile: filepath.Join(t.TempDir(), "missing")}, func(req *http.Request) (*http.Response, error) {
+		t.Fatalf("unexpected request to %s", req.URL.Path)
+		return nil, nil
+	})
+
+	if _, err := client.QuickAuthenticate(context.Background()); err == nil || !strings.Contains(err.Error(), "JWT file") {
+		t.Fatalf("expected JWT file error, got %v", err)
+	}
+}
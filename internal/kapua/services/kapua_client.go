@@ -7,12 +7,19 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
 	"kapua-mcp-server/internal/kapua/config"
 	"kapua-mcp-server/internal/kapua/models"
+	"kapua-mcp-server/pkg/tracing"
 	"kapua-mcp-server/pkg/utils"
 )
 
@@ -22,13 +29,24 @@ type KapuaClient struct {
 	httpClient    *http.Client
 	logger        *utils.Logger
 	baseURL       string
-	token         string       // Current JWT token for authenticated requests
-	tokenExpiry   time.Time    // Token expiration time
-	refreshToken  string       // Refresh token
-	refreshExpiry time.Time    // Refresh token expiration time
-	tokenMutex    sync.RWMutex // Protects token-related fields
-	autoRefresh   bool         // Enable automatic token refresh
-	scopeId       string       // Default scope ID for account operations
+	token         string             // Current JWT token for authenticated requests
+	tokenExpiry   time.Time          // Token expiration time
+	refreshToken  string             // Refresh token
+	refreshExpiry time.Time          // Refresh token expiration time
+	tokenMutex    sync.RWMutex       // Protects token-related fields
+	autoRefresh   bool               // Enable automatic token refresh
+	scopeId       string             // Default scope ID for account operations
+	trustKey      string             // MFA trust key returned by Kapua, if any
+	authPending   error              // Set while authentication waits on an external step such as MFA
+	jwtFile       jwtFileCache       // Last token read from config.JWTFile
+	serverInfo    *models.ServerInfo // Result of DetectServerInfo, nil until detected
+	infoMutex     sync.RWMutex       // Protects serverInfo
+	retry         retryPolicy        // Retries of idempotent requests after transient failures
+	reauthMutex   sync.Mutex         // Serialises re-authentication after a rejected token
+	limiter       *requestLimiter    // Rate and in-flight limits, shared with session clients
+	breaker       *circuitBreaker    // Fails fast while Kapua is down, shared with session clients
+	upstream      *upstreamStats     // Recent request outcomes, shared with session clients
+	cache         *responseCache     // Cached GET responses, never shared
 }
 
 // NewKapuaClient creates a new Kapua API client
@@ -47,6 +65,30 @@ func NewKapuaClient(cfg *config.KapuaConfig) *KapuaClient {
 		logger:      utils.NewDefaultLogger("KapuaClient"),
 		baseURL:     baseURL,
 		autoRefresh: true, // Enable automatic token refresh by default
+		retry:       newRetryPolicy(cfg),
+		limiter:     newRequestLimiter(cfg),
+		breaker:     newCircuitBreaker(cfg),
+		upstream:    newUpstreamStats(),
+		cache:       newResponseCache(cfg),
+	}
+}
+
+// NewSessionClient returns a client for the same Kapua endpoint that authenticates with
+// cfg instead of the receiver's credentials. The HTTP client and detected server info
+// are shared; tokens are not.
+func (c *KapuaClient) NewSessionClient(cfg *config.KapuaConfig) *KapuaClient {
+	return &KapuaClient{
+		config:      cfg,
+		httpClient:  c.httpClient,
+		logger:      c.logger,
+		baseURL:     c.baseURL,
+		autoRefresh: c.autoRefresh,
+		retry:       c.retry,
+		limiter:     c.limiter,
+		breaker:     c.breaker,
+		upstream:    c.upstream,
+		cache:       c.cache.newSession(),
+		serverInfo:  c.ServerInfo(),
 	}
 }
 
@@ -58,79 +100,220 @@ func (c *KapuaClient) SetHTTPClient(client *http.Client) {
 }
 
 // makeRequest performs an HTTP request to the Kapua API
-func (c *KapuaClient) makeRequest(ctx context.Context, method, endpoint string, body interface{}) (*http.Response, error) {
+func (c *KapuaClient) makeRequest(ctx context.Context, method, endpoint string, body interface{}) (resp *http.Response, err error) {
+	template := endpointTemplate(endpoint)
+	ctx, span := tracing.Tracer().Start(ctx, method+" "+template,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", method),
+			attribute.String("url.template", template)))
+	defer func() {
+		if resp != nil {
+			span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+			if resp.StatusCode >= 400 {
+				span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+			}
+		}
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	reachedKapua := false
+	defer func() {
+		if reachedKapua {
+			c.breaker.record(ctx, probe, resp, err)
+			c.upstream.record(ctx, resp, err)
+		} else {
+			c.breaker.abandon(probe)
+		}
+	}()
+
 	url := c.baseURL + endpoint
-	c.logger.Debug("Making %s request to %s", method, url)
+	logger := c.logger.WithContext(ctx)
+	logger.Debug("Making %s request to %s", method, url)
 
-	var reqBody io.Reader
+	authEndpoint := isAuthenticationEndpoint(endpoint)
+	var jsonData []byte
 	if body != nil {
-		jsonData, err := json.Marshal(body)
+		jsonData, err = json.Marshal(body)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal request body: %w", err)
 		}
-		reqBody = bytes.NewBuffer(jsonData)
-		c.logger.Debug("Request body: %s", string(jsonData))
-	}
-
-	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		// Authentication bodies carry credentials; keep them out of the log entirely.
+		if !authEndpoint {
+			logger.Debug("Request body: %s", string(jsonData))
+		}
 	}
 
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-
 	// Skip refresh handling for authentication endpoints to avoid recursion
-	if !strings.HasPrefix(endpoint, "/authentication/") {
+	if !authEndpoint {
+		if err := c.pendingAuthentication(); err != nil {
+			return nil, err
+		}
 		if err := c.refreshTokenIfNeeded(ctx); err != nil {
-			c.logger.Warn("Token refresh failed, continuing with current token: %v", err)
+			logger.Warn("Token refresh failed, continuing with current token: %v", err)
 		}
 	}
 
-	token := c.getToken()
-	if token != "" {
-		req.Header.Set("Authorization", "Bearer "+token)
-	}
+	priority := priorityFromContext(ctx)
+	reauthenticated := false
+	for attempt := 1; ; {
+		var reqBody io.Reader
+		if jsonData != nil {
+			reqBody = bytes.NewReader(jsonData)
+		}
+		req, reqErr := http.NewRequestWithContext(ctx, method, url, reqBody)
+		if reqErr != nil {
+			return nil, fmt.Errorf("failed to create request: %w", reqErr)
+		}
+
+		// Set headers
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		token := c.getToken()
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		tracing.Propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
 
-	resp, err := c.httpClient.Do(req)
+		release, queued, limitErr := c.limiter.acquire(ctx, priority)
+		if c.limiter != nil {
+			observeQueueWait(priority, queued)
+		}
+		if queued > 0 {
+			if queued >= slowQueueWait {
+				logger.Info("%s %s waited %v for the Kapua request budget (%s)", method, url, queued.Round(time.Millisecond), priority)
+			} else {
+				logger.Debug("%s %s waited %v for the Kapua request budget (%s)", method, url, queued.Round(time.Millisecond), priority)
+			}
+			span.SetAttributes(attribute.Int64("kapua.queue_wait_ms", queued.Milliseconds()))
+		}
+		if limitErr != nil {
+			return nil, fmt.Errorf("request cancelled while queued: %w", limitErr)
+		}
+
+		start := time.Now()
+		resp, err = c.httpClient.Do(req)
+		status := 0
+		if err == nil {
+			status = resp.StatusCode
+			resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+		} else {
+			release()
+		}
+		observeKapuaRequest(method, endpoint, status, start)
+
+		// A rejected token is replaced once and the request replayed. Kapua refuses
+		// such requests before acting on them, so this is safe for POST as well.
+		if err == nil && !authEndpoint && !reauthenticated && c.canReauthenticate() && authenticationRejected(resp) {
+			reauthenticated = true
+			drainAndClose(resp)
+			logger.Warn("Kapua rejected the access token for %s %s; re-authenticating", method, url)
+			if reauthErr := c.reauthenticate(ctx, token); reauthErr != nil {
+				return nil, fmt.Errorf("access token rejected and re-authentication failed: %w", reauthErr)
+			}
+			resp = nil
+			continue
+		}
+
+		wait, retry := c.retry.next(ctx, method, attempt, resp, err)
+		if !retry {
+			reachedKapua = true
+			break
+		}
+		reason := http.StatusText(status)
+		if err != nil {
+			reason = err.Error()
+		} else {
+			drainAndClose(resp)
+		}
+		logger.Warn("%s %s failed (%s); retry %d of %d in %v", method, url, reason, attempt, c.retry.maxRetries, wait.Round(time.Millisecond))
+		kapuaRequestRetries.Inc(method, template)
+		span.SetAttributes(attribute.Int("http.request.resend_count", attempt))
+		resp = nil
+		if err := sleepContext(ctx, wait); err != nil {
+			return nil, fmt.Errorf("request cancelled while waiting to retry: %w", err)
+		}
+		attempt++
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
-	c.logger.Debug("Request info: %s %s", method, url)
-	c.logger.Debug("Request body: %v", body)
-	c.logger.Debug("Response status: %d", resp.StatusCode)
+	logger.Debug("Response status for %s %s: %d", method, url, resp.StatusCode)
 	return resp, nil
 }
 
+// drainAndClose discards a response that will not be handled, so its connection
+// can be reused.
+func drainAndClose(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+}
+
 // handleResponse processes the HTTP response and unmarshals the result
 func (c *KapuaClient) handleResponse(resp *http.Response, result interface{}) error {
+	body, err := c.readResponse(resp)
+	if err != nil {
+		return err
+	}
+	return decodeResponse(body, result)
+}
+
+// isAuthenticationEndpoint reports whether endpoint is one of the
+// /authentication/ endpoints, whose bodies carry credentials or tokens.
+func isAuthenticationEndpoint(endpoint string) bool {
+	return strings.HasPrefix(endpoint, "/authentication/")
+}
+
+// isAuthenticationResponse reports whether resp answers a request to an
+// /authentication/ endpoint.
+func (c *KapuaClient) isAuthenticationResponse(resp *http.Response) bool {
+	if resp.Request == nil || resp.Request.URL == nil {
+		return false
+	}
+	base, err := url.Parse(c.baseURL)
+	if err != nil {
+		return false
+	}
+	return isAuthenticationEndpoint(strings.TrimPrefix(resp.Request.URL.Path, base.Path))
+}
+
+// readResponse returns the body of a successful response, or the Kapua error it carries.
+func (c *KapuaClient) readResponse(resp *http.Response) ([]byte, error) {
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("failed to read response body: %w", err)
+		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	c.logger.Debug("Response body: %s", string(body))
+	if !c.isAuthenticationResponse(resp) {
+		c.logger.Debug("Response body: %s", string(body))
+	}
 
 	// Check for error responses
 	if resp.StatusCode >= 400 {
 		var kapuaErr models.KapuaError
 		if err := json.Unmarshal(body, &kapuaErr); err != nil {
 			// If we can't unmarshal as KapuaError, return a generic error
-			return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+			return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
 		}
-		return kapuaErr
+		return nil, kapuaErr
 	}
+	return body, nil
+}
 
-	// Unmarshal successful response
+// decodeResponse unmarshals a successful response body into result, if set.
+func decodeResponse(body []byte, result interface{}) error {
 	if result != nil {
 		if err := json.Unmarshal(body, result); err != nil {
 			return fmt.Errorf("failed to unmarshal response: %w", err)
 		}
 	}
-
 	return nil
 }
 
@@ -142,15 +325,39 @@ func (c *KapuaClient) scopedEndpoint(pathTemplate string, args ...interface{}) s
 
 // doKapuaRequest wraps makeRequest and handleResponse, applying consistent error wrapping.
 // action should describe the operation, e.g., "list devices" or "authenticate user".
+// GET answers for read-mostly data are served from the response cache while fresh;
+// other methods drop the cached data of the device they target.
 func (c *KapuaClient) doKapuaRequest(ctx context.Context, method, endpoint, action string, body interface{}, out interface{}) error {
+	var generation uint64
+	if method == http.MethodGet {
+		var cached []byte
+		if cached, generation = c.cache.get(ctx, endpoint); cached != nil {
+			c.logger.WithContext(ctx).Debug("Serving %s from cache", endpoint)
+			if err := decodeResponse(cached, out); err != nil {
+				return fmt.Errorf("failed to %s: %w", action, err)
+			}
+			return nil
+		}
+	} else {
+		// Also after a failure: the change may have been applied anyway.
+		defer c.cache.invalidate(endpoint)
+	}
+
 	resp, err := c.makeRequest(ctx, method, endpoint, body)
 	if err != nil {
 		return fmt.Errorf("%s request failed: %w", action, err)
 	}
 
-	if err := c.handleResponse(resp, out); err != nil {
+	data, err := c.readResponse(resp)
+	if err == nil {
+		err = decodeResponse(data, out)
+	}
+	if err != nil {
 		return fmt.Errorf("failed to %s: %w", action, err)
 	}
+	if method == http.MethodGet {
+		c.cache.put(endpoint, data, generation)
+	}
 
 	return nil
 }
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"kapua-mcp-server/internal/kapua/config"
+)
+
+// Priority orders queued Kapua requests when the client-side limits are reached.
+type Priority int
+
+const (
+	// PriorityInteractive is the default: single-device calls made for a tool.
+	PriorityInteractive Priority = iota
+	// PriorityBulk is for scans that issue many requests, such as fleet health.
+	// Bulk requests only proceed when no interactive request is waiting.
+	PriorityBulk
+)
+
+func (p Priority) String() string {
+	if p == PriorityBulk {
+		return "bulk"
+	}
+	return "interactive"
+}
+
+type priorityKey struct{}
+
+// WithPriority returns a context whose Kapua requests are queued with priority p.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+func priorityFromContext(ctx context.Context) Priority {
+	p, _ := ctx.Value(priorityKey{}).(Priority)
+	return p
+}
+
+// requestLimiter enforces a token-bucket rate and a maximum number of requests in
+// flight. It is shared by all clients derived from the same KapuaClient. A nil
+// limiter imposes no limits.
+type requestLimiter struct {
+	mu          sync.Mutex
+	rate        float64 // Tokens added per second; 0 disables rate limiting
+	burst       float64
+	tokens      float64
+	last        time.Time
+	maxInFlight int // 0 disables the concurrency limit
+	inFlight    int
+	queues      [2][]*limiterWaiter // Indexed by Priority
+	timer       *time.Timer         // Pending dispatch once a token becomes available
+}
+
+type limiterWaiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+// newRequestLimiter returns nil when cfg sets no limits.
+func newRequestLimiter(cfg *config.KapuaConfig) *requestLimiter {
+	if cfg.RateLimit <= 0 && cfg.MaxInFlight <= 0 {
+		return nil
+	}
+	burst := float64(max(cfg.RateBurst, 1))
+	return &requestLimiter{
+		rate:        cfg.RateLimit,
+		burst:       burst,
+		tokens:      burst,
+		last:        time.Now(),
+		maxInFlight: cfg.MaxInFlight,
+	}
+}
+
+// acquire waits for a rate token and an in-flight slot. It returns the time spent
+// queued and a release function that must be called once the request is done.
+func (l *requestLimiter) acquire(ctx context.Context, priority Priority) (release func(), wait time.Duration, err error) {
+	if l == nil {
+		return func() {}, 0, nil
+	}
+	start := time.Now()
+	l.mu.Lock()
+	if l.queuedAhead(priority) == 0 && l.available(start) {
+		l.take()
+		l.mu.Unlock()
+		return l.releaseOnce(), 0, nil
+	}
+	w := &limiterWaiter{ready: make(chan struct{})}
+	l.queues[priority] = append(l.queues[priority], w)
+	l.dispatch()
+	l.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return l.releaseOnce(), time.Since(start), nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if w.granted {
+			// Granted while cancelling; hand the slot back.
+			l.inFlight--
+		} else {
+			l.remove(priority, w)
+		}
+		l.dispatch()
+		return nil, time.Since(start), ctx.Err()
+	}
+}
+
+// queuedAhead counts waiters that must be served before a new request of priority.
+func (l *requestLimiter) queuedAhead(priority Priority) int {
+	n := 0
+	for p := PriorityInteractive; p <= priority; p++ {
+		n += len(l.queues[p])
+	}
+	return n
+}
+
+// available refills the bucket and reports whether a request may start now.
+func (l *requestLimiter) available(now time.Time) bool {
+	if l.rate > 0 {
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+		l.last = now
+		if l.tokens < 1 {
+			return false
+		}
+	}
+	return l.maxInFlight <= 0 || l.inFlight < l.maxInFlight
+}
+
+func (l *requestLimiter) take() {
+	if l.rate > 0 {
+		l.tokens--
+	}
+	l.inFlight++
+}
+
+// dispatch grants queued waiters, interactive first, while limits allow. When only
+// the rate blocks progress it arms a timer for the next token.
+func (l *requestLimiter) dispatch() {
+	for {
+		var w *limiterWaiter
+		var p Priority
+		for p = PriorityInteractive; p <= PriorityBulk; p++ {
+			if len(l.queues[p]) > 0 {
+				w = l.queues[p][0]
+				break
+			}
+		}
+		if w == nil {
+			return
+		}
+		if !l.available(time.Now()) {
+			if l.rate > 0 && l.tokens < 1 && l.timer == nil && (l.maxInFlight <= 0 || l.inFlight < l.maxInFlight) {
+				delay := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+				l.timer = time.AfterFunc(delay, func() {
+					l.mu.Lock()
+					l.timer = nil
+					l.dispatch()
+					l.mu.Unlock()
+				})
+			}
+			return
+		}
+		l.queues[p] = l.queues[p][1:]
+		l.take()
+		w.granted = true
+		close(w.ready)
+	}
+}
+
+func (l *requestLimiter) remove(priority Priority, w *limiterWaiter) {
+	queue := l.queues[priority]
+	for i, queued := range queue {
+		if queued == w {
+			l.queues[priority] = append(queue[:i:i], queue[i+1:]...)
+			return
+		}
+	}
+}
+
+func (l *requestLimiter) releaseOnce() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			l.inFlight--
+			l.dispatch()
+			l.mu.Unlock()
+		})
+	}
+}
+
+// releasingBody frees the in-flight slot when the response body is closed.
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
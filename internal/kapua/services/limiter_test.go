@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"kapua-mcp-server/internal/kapua/config"
+)
+
+func TestNewRequestLimiterDisabled(t *testing.T) {
+	limiter := newRequestLimiter(&config.KapuaConfig{})
+	if limiter != nil {
+		t.Fatal("expected no limiter without limits")
+	}
+	release, wait, err := limiter.acquire(context.Background(), PriorityBulk)
+	if err != nil || wait != 0 {
+		t.Fatalf("nil limiter must not wait: %v %v", wait, err)
+	}
+	release()
+}
+
+func TestRequestLimiterRate(t *testing.T) {
+	limiter := newRequestLimiter(&config.KapuaConfig{RateLimit: 50, RateBurst: 1})
+
+	start := time.Now()
+	for range 5 {
+		release, _, err := limiter.acquire(context.Background(), PriorityInteractive)
+		if err != nil {
+			t.Fatalf("acquire: %v", err)
+		}
+		release()
+	}
+	// One request from the burst, then four at 20ms intervals.
+	if elapsed := time.Since(start); elapsed < 70*time.Millisecond {
+		t.Fatalf("expected rate limiting to space requests, took %v", elapsed)
+	}
+}
+
+func TestRequestLimiterMaxInFlight(t *testing.T) {
+	limiter := newRequestLimiter(&config.KapuaConfig{MaxInFlight: 2})
+
+	first, _, _ := limiter.acquire(context.Background(), PriorityInteractive)
+	second, _, _ := limiter.acquire(context.Background(), PriorityInteractive)
+
+	acquired := make(chan time.Duration)
+	go func() {
+		release, wait, _ := limiter.acquire(context.Background(), PriorityInteractive)
+		release()
+		acquired <- wait
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("third request must wait for a free slot")
+	case <-time.After(30 * time.Millisecond):
+	}
+	first()
+	first() // Releasing twice must not free a second slot.
+	if wait := <-acquired; wait < 30*time.Millisecond {
+		t.Fatalf("expected the queue wait to be reported, got %v", wait)
+	}
+	second()
+	if limiter.inFlight != 0 {
+		t.Fatalf("expected no requests in flight, got %d", limiter.inFlight)
+	}
+}
+
+func TestRequestLimiterPrefersInteractive(t *testing.T) {
+	limiter := newRequestLimiter(&config.KapuaConfig{MaxInFlight: 1})
+	hold, _, _ := limiter.acquire(context.Background(), PriorityInteractive)
+
+	order := make(chan Priority, 2)
+	wait := func(p Priority) {
+		release, _, err := limiter.acquire(context.Background(), p)
+		if err != nil {
+			t.Errorf("acquire: %v", err)
+			return
+		}
+		order <- p
+		release()
+	}
+	go wait(PriorityBulk)
+	waitForQueued(t, limiter, PriorityBulk)
+	go wait(PriorityInteractive)
+	waitForQueued(t, limiter, PriorityInteractive)
+
+	hold()
+	if first := <-order; first != PriorityInteractive {
+		t.Fatalf("expected the interactive request first, got %s", first)
+	}
+	if second := <-order; second != PriorityBulk {
+		t.Fatalf("expected the bulk request second, got %s", second)
+	}
+}
+
+func TestRequestLimiterCancelWhileQueued(t *testing.T) {
+	limiter := newRequestLimiter(&config.KapuaConfig{MaxInFlight: 1})
+	hold, _, _ := limiter.acquire(context.Background(), PriorityInteractive)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, _, err := limiter.acquire(ctx, PriorityBulk); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline error, got %v", err)
+	}
+	hold()
+
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	if limiter.inFlight != 0 || len(limiter.queues[PriorityBulk]) != 0 {
+		t.Fatalf("cancelled waiter leaked: inFlight %d, queued %d", limiter.inFlight, len(limiter.queues[PriorityBulk]))
+	}
+}
+
+func TestMakeRequestReleasesLimiterSlot(t *testing.T) {
+	client := newTestKapuaClient()
+	client.limiter = newRequestLimiter(&config.KapuaConfig{MaxInFlight: 1})
+	client.httpClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Body: io.NopCloser(strings.NewReader(`{"id":"dev-1"}`))}, nil
+	})}
+
+	before := kapuaRequestQueueWait.Count("bulk")
+	ctx, cancel := context.WithTimeout(WithPriority(context.Background(), PriorityBulk), time.Second)
+	defer cancel()
+	for range 3 {
+		if _, err := client.GetDevice(ctx, "dev-1"); err != nil {
+			t.Fatalf("request failed, slot probably not released: %v", err)
+		}
+	}
+	if got := kapuaRequestQueueWait.Count("bulk") - before; got != 3 {
+		t.Fatalf("expected 3 queue wait observations, got %d", got)
+	}
+}
+
+func waitForQueued(t *testing.T, limiter *requestLimiter, p Priority) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		limiter.mu.Lock()
+		n := len(limiter.queues[p])
+		limiter.mu.Unlock()
+		if n > 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("no %s request queued", p)
+}
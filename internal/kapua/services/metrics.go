@@ -0,0 +1,73 @@
+package services
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"kapua-mcp-server/pkg/metrics"
+)
+
+var (
+	kapuaRequestDuration = metrics.NewHistogramVec(
+		"kapua_request_duration_seconds",
+		"Latency of Kapua REST API requests by method, endpoint template and status code (\"error\" when no response was received).",
+		nil, "method", "endpoint", "status")
+	kapuaRequestRetries = metrics.NewCounterVec(
+		"kapua_request_retries_total",
+		"Kapua REST API requests sent again after a transient failure, by method and endpoint template.",
+		"method", "endpoint")
+	kapuaRequestQueueWait = metrics.NewHistogramVec(
+		"kapua_request_queue_wait_seconds",
+		"Time Kapua requests waited for the client-side rate limit and in-flight budget, by priority (interactive or bulk).",
+		nil, "priority")
+	kapuaCacheRequests = metrics.NewCounterVec(
+		"kapua_cache_requests_total",
+		"Lookups in the Kapua response cache by kind of data and result (hit, miss or bypass).",
+		"kind", "result")
+	kapuaTokenRefreshes = metrics.NewCounterVec(
+		"kapua_token_refreshes_total",
+		"Automatic Kapua token refreshes and re-authentications by result (success or failure).",
+		"result")
+)
+
+// slowQueueWait is the queue wait above which a request is logged at INFO.
+const slowQueueWait = time.Second
+
+func observeQueueWait(priority Priority, wait time.Duration) {
+	kapuaRequestQueueWait.Observe(wait.Seconds(), priority.String())
+}
+
+// collectionSegments are path segments followed by an entity ID in Kapua endpoints.
+var collectionSegments = map[string]bool{
+	"devices":        true,
+	"bundles":        true,
+	"configurations": true,
+	"snapshots":      true,
+}
+
+// endpointTemplate reduces an endpoint to a low-cardinality label by replacing the
+// scope and entity IDs, e.g. "/AQ/devices/Ag/events?limit=5" becomes
+// "/{scopeId}/devices/{id}/events".
+func endpointTemplate(endpoint string) string {
+	path, _, _ := strings.Cut(endpoint, "?")
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	if len(segments) > 1 && segments[0] != "authentication" && segments[0] != "user" {
+		segments[0] = "{scopeId}"
+	}
+	for i := 1; i < len(segments); i++ {
+		if collectionSegments[segments[i-1]] && segments[i] != "" && !strings.HasPrefix(segments[i], "_") {
+			segments[i] = "{id}"
+		}
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// observeKapuaRequest records a request started at start; status 0 means no response.
+func observeKapuaRequest(method, endpoint string, status int, start time.Time) {
+	statusLabel := "error"
+	if status != 0 {
+		statusLabel = strconv.Itoa(status)
+	}
+	kapuaRequestDuration.Observe(time.Since(start).Seconds(), method, endpointTemplate(endpoint), statusLabel)
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestEndpointTemplate(t *testing.T) {
+	cases := map[string]string{
+		"/authentication/user":                        "/authentication/user",
+		"/sys-info":                                   "/sys-info",
+		"/user/mfa":                                   "/user/mfa",
+		"/AQ/devices":                                 "/{scopeId}/devices",
+		"/AQ/devices/Ag/events":                       "/{scopeId}/devices/{id}/events",
+		"/AQ/devices/Ag/bundles/12/_start":            "/{scopeId}/devices/{id}/bundles/{id}/_start",
+		"/AQ/devices/Ag/inventory/bundles/_stop":      "/{scopeId}/devices/{id}/inventory/bundles/_stop",
+		"/AQ/devices/Ag/snapshots/3/_rollback":        "/{scopeId}/devices/{id}/snapshots/{id}/_rollback",
+		"/AQ/devices/Ag/configurations/org.acme.Comp": "/{scopeId}/devices/{id}/configurations/{id}",
+		"/AQ/deviceLogs?limit=1":                      "/{scopeId}/deviceLogs",
+	}
+	for endpoint, want := range cases {
+		if got := endpointTemplate(endpoint); got != want {
+			t.Errorf("endpointTemplate(%q) = %q, want %q", endpoint, got, want)
+		}
+	}
+}
+
+func TestMakeRequestRecordsLatency(t *testing.T) {
+	client := newTestKapuaClient()
+	client.httpClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(strings.NewReader(`{}`)), Header: make(http.Header)}, nil
+	})}
+
+	before := kapuaRequestDuration.Count(http.MethodGet, "/{scopeId}/devices/{id}", "404")
+	_, _ = client.GetDevice(context.Background(), "dev-1")
+	if got := kapuaRequestDuration.Count(http.MethodGet, "/{scopeId}/devices/{id}", "404") - before; got != 1 {
+		t.Fatalf("expected one observation, got %d", got)
+	}
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"kapua-mcp-server/internal/kapua/models"
+)
+
+var (
+	// ErrMFARequired is returned when Kapua asks for a second factor and none is configured.
+	ErrMFARequired = errors.New("kapua account requires a multi-factor authentication code")
+	// ErrMFACodeRejected is returned when Kapua refuses the provided MFA code.
+	ErrMFACodeRejected = errors.New("kapua rejected the multi-factor authentication code")
+)
+
+const (
+	totpPeriod = 30 * time.Second
+	totpDigits = 6
+)
+
+// GenerateTOTP computes the RFC 6238 time-based one-time password for a base32
+// encoded secret, using the SHA-1, 6 digit, 30 second parameters Kapua expects.
+func GenerateTOTP(secret string, at time.Time) (string, error) {
+	normalized := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(secret), " ", ""))
+	normalized = strings.TrimRight(normalized, "=")
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(normalized)
+	if err != nil {
+		return "", fmt.Errorf("invalid TOTP secret: %w", err)
+	}
+
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(at.Unix()/int64(totpPeriod/time.Second)))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000), nil
+}
+
+// AuthenticateWithMFACode performs a username/password login with the given MFA code,
+// for example one obtained from the MCP client through elicitation.
+func (c *KapuaClient) AuthenticateWithMFACode(ctx context.Context, code string) (*models.AccessToken, error) {
+	if code == "" {
+		return nil, ErrMFARequired
+	}
+	return c.authenticatePassword(ctx, code)
+}
+
+// GetMFAOption retrieves the MFA settings of the authenticated user.
+func (c *KapuaClient) GetMFAOption(ctx context.Context) (*models.MfaOption, error) {
+	var option models.MfaOption
+	if err := c.doKapuaRequest(ctx, http.MethodGet, "/user/mfa", "get MFA option", nil, &option); err != nil {
+		return nil, err
+	}
+	return &option, nil
+}
+
+// authenticatePassword logs in with username and password, adding the second factor
+// when one is available. A stored trust key is tried first so that restarts skip MFA;
+// otherwise code (or the configured MFA code / TOTP secret) is sent.
+func (c *KapuaClient) authenticatePassword(ctx context.Context, code string) (*models.AccessToken, error) {
+	credentials := models.UsernamePasswordCredentials{
+		Username: c.config.Username,
+		Password: c.config.Password,
+	}
+
+	if trustKey := c.loadTrustKey(); trustKey != "" && code == "" {
+		withTrust := credentials
+		withTrust.TrustKey = trustKey
+		token, err := c.AuthenticateUser(ctx, withTrust)
+		if err == nil {
+			return token, nil
+		}
+		if !hasKapuaErrorCode(err, trustKeyRejectedCodes) {
+			return nil, err
+		}
+		c.logger.Warn("Stored MFA trust key was rejected; falling back to an MFA code")
+		c.forgetTrustKey()
+	}
+
+	if code == "" {
+		var err error
+		if code, err = c.configuredMFACode(); err != nil {
+			return nil, err
+		}
+	}
+
+	if code == "" {
+		token, err := c.AuthenticateUser(ctx, credentials)
+		if err != nil && isMFARequired(err) {
+			return nil, fmt.Errorf("%w: %v", ErrMFARequired, err)
+		}
+		return token, err
+	}
+
+	credentials.AuthenticationCode = code
+	credentials.TrustMe = c.config.TrustKeyFile != ""
+	token, err := c.AuthenticateUser(ctx, credentials)
+	if err != nil {
+		if hasKapuaErrorCode(err, mfaCodeRejectedCodes) {
+			return nil, fmt.Errorf("%w: %v", ErrMFACodeRejected, err)
+		}
+		return nil, err
+	}
+
+	if token.TrustKey != "" {
+		c.storeTrustKey(token.TrustKey)
+	}
+	return token, nil
+}
+
+// configuredMFACode returns the static MFA code or a fresh TOTP code, if configured.
+// The static code is consumed on first use since Kapua will not accept it twice.
+func (c *KapuaClient) configuredMFACode() (string, error) {
+	if c.config.TOTPSecret != "" {
+		return GenerateTOTP(c.config.TOTPSecret, time.Now())
+	}
+	c.tokenMutex.Lock()
+	defer c.tokenMutex.Unlock()
+	code := c.config.MFACode
+	c.config.MFACode = ""
+	return code, nil
+}
+
+// loadTrustKey returns the trust key kept in memory or, failing that, the persisted one.
+func (c *KapuaClient) loadTrustKey() string {
+	c.tokenMutex.RLock()
+	trustKey := c.trustKey
+	c.tokenMutex.RUnlock()
+	if trustKey != "" || c.config.TrustKeyFile == "" {
+		return trustKey
+	}
+
+	data, err := os.ReadFile(c.config.TrustKeyFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			c.logger.Warn("Unable to read MFA trust key file: %v", err)
+		}
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// storeTrustKey keeps the trust key in memory and persists it when a file is configured.
+func (c *KapuaClient) storeTrustKey(trustKey string) {
+	c.tokenMutex.Lock()
+	c.trustKey = trustKey
+	c.tokenMutex.Unlock()
+
+	if c.config.TrustKeyFile == "" {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(c.config.TrustKeyFile), 0o700); err != nil {
+		c.logger.Warn("Unable to create MFA trust key directory: %v", err)
+		return
+	}
+	if err := os.WriteFile(c.config.TrustKeyFile, []byte(trustKey+"\n"), 0o600); err != nil {
+		c.logger.Warn("Unable to persist MFA trust key: %v", err)
+		return
+	}
+	c.logger.Info("MFA trust key stored; later logins will skip the second factor")
+}
+
+// forgetTrustKey drops a trust key that Kapua no longer accepts.
+func (c *KapuaClient) forgetTrustKey() {
+	c.tokenMutex.Lock()
+	c.trustKey = ""
+	c.tokenMutex.Unlock()
+
+	if c.config.TrustKeyFile == "" {
+		return
+	}
+	if err := os.Remove(c.config.TrustKeyFile); err != nil && !os.IsNotExist(err) {
+		c.logger.Warn("Unable to remove stale MFA trust key: %v", err)
+	}
+}
+
+// trustKeyRejectedCodes are Kapua error codes meaning a stored trust key is no longer
+// accepted. Other login failures, such as a wrong password or a locked account, leave
+// the trust key in place.
+var trustKeyRejectedCodes = map[string]bool{
+	"REQUIRE_MFA_CREDENTIALS": true,
+	"INVALID_TRUST_KEY":       true,
+}
+
+// mfaCodeRejectedCodes are Kapua error codes meaning the MFA code itself was refused.
+var mfaCodeRejectedCodes = map[string]bool{
+	"INVALID_MFA_CODE": true,
+}
+
+// isMFARequired reports whether a login error means Kapua expects a second factor.
+func isMFARequired(err error) bool {
+	var kapuaErr models.KapuaError
+	return errors.As(err, &kapuaErr) && kapuaErr.ErrorCode() == "REQUIRE_MFA_CREDENTIALS"
+}
+
+// hasKapuaErrorCode reports whether err is a Kapua error response carrying one of codes.
+func hasKapuaErrorCode(err error, codes map[string]bool) bool {
+	var kapuaErr models.KapuaError
+	return errors.As(err, &kapuaErr) && codes[kapuaErr.ErrorCode()]
+}
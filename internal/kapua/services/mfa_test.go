@@ -0,0 +1,216 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"kapua-mcp-server/internal/kapua/config"
+	"kapua-mcp-server/internal/kapua/models"
+	"kapua-mcp-server/pkg/utils"
+)
+
+const mfaTokenJSON = `{"tokenId":"mfa-token","refreshToken":"refresh","expiresOn":"2030-01-02T15:04:05Z","refreshExpiresOn":"2030-01-03T15:04:05Z","trustKey":"trust-123"}`
+
+func TestGenerateTOTP(t *testing.T) {
+	// RFC 6238 SHA-1 test vectors, secret "12345678901234567890" in base32.
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	cases := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+	}
+	for unix, want := range cases {
+		got, err := GenerateTOTP(secret, time.Unix(unix, 0))
+		if err != nil {
+			t.Fatalf("GenerateTOTP returned error: %v", err)
+		}
+		if got != want {
+			t.Fatalf("at %d expected %s, got %s", unix, want, got)
+		}
+	}
+
+	if _, err := GenerateTOTP("not base32!", time.Now()); err == nil {
+		t.Fatal("expected error for invalid secret")
+	}
+}
+
+func newMFATestClient(cfg *config.KapuaConfig, transport roundTripFunc) *KapuaClient {
+	return &KapuaClient{
+		config:     cfg,
+		logger:     utils.NewDefaultLogger("test"),
+		baseURL:    "http://example.com/v1",
+		httpClient: &http.Client{Transport: transport},
+	}
+}
+
+func decodeCredentials(t *testing.T, req *http.Request) models.UsernamePasswordCredentials {
+	t.Helper()
+	var creds models.UsernamePasswordCredentials
+	if err := json.NewDecoder(req.Body).Decode(&creds); err != nil {
+		t.Fatalf("failed to decode credentials: %v", err)
+	}
+	return creds
+}
+
+func jsonResponse(status int, body string) *http.Response {
+	return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header)}
+}
+
+func TestQuickAuthenticateMFARequired(t *testing.T) {
+	client := newMFATestClient(&config.KapuaConfig{Username: "user", Password: "pass", AuthMethod: "password"},
+		func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(http.StatusUnauthorized, `{"kapuaErrorCode":"REQUIRE_MFA_CREDENTIALS","message":"MFA credentials required"}`), nil
+		})
+
+	_, err := client.QuickAuthenticate(context.Background())
+	if !errors.Is(err, ErrMFARequired) {
+		t.Fatalf("expected ErrMFARequired, got %v", err)
+	}
+}
+
+func TestQuickAuthenticateSendsTOTPAndStoresTrustKey(t *testing.T) {
+	trustFile := filepath.Join(t.TempDir(), "state", "trust-key")
+	cfg := &config.KapuaConfig{
+		Username:     "user",
+		Password:     "pass",
+		AuthMethod:   "password",
+		TOTPSecret:   "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+		TrustKeyFile: trustFile,
+	}
+	client := newMFATestClient(cfg, func(req *http.Request) (*http.Response, error) {
+		creds := decodeCredentials(t, req)
+		if len(creds.AuthenticationCode) != 6 || !creds.TrustMe || creds.TrustKey != "" {
+			t.Fatalf("unexpected credentials: %+v", creds)
+		}
+		return jsonResponse(http.StatusOK, mfaTokenJSON), nil
+	})
+
+	if _, err := client.QuickAuthenticate(context.Background()); err != nil {
+		t.Fatalf("QuickAuthenticate returned error: %v", err)
+	}
+
+	data, err := os.ReadFile(trustFile)
+	if err != nil {
+		t.Fatalf("trust key not persisted: %v", err)
+	}
+	if strings.TrimSpace(string(data)) != "trust-123" {
+		t.Fatalf("unexpected trust key file content %q", data)
+	}
+	if info, err := os.Stat(trustFile); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected 0600 trust key file, got %v (%v)", info.Mode().Perm(), err)
+	}
+}
+
+func TestQuickAuthenticateUsesPersistedTrustKey(t *testing.T) {
+	trustFile := filepath.Join(t.TempDir(), "trust-key")
+	if err := os.WriteFile(trustFile, []byte("trust-123\n"), 0o600); err != nil {
+		t.Fatalf("failed to write trust key: %v", err)
+	}
+	cfg := &config.KapuaConfig{Username: "user", Password: "pass", AuthMethod: "password", TrustKeyFile: trustFile}
+	client := newMFATestClient(cfg, func(req *http.Request) (*http.Response, error) {
+		creds := decodeCredentials(t, req)
+		if creds.TrustKey != "trust-123" || creds.AuthenticationCode != "" {
+			t.Fatalf("expected trust key login, got %+v", creds)
+		}
+		return jsonResponse(http.StatusOK, mfaTokenJSON), nil
+	})
+
+	if _, err := client.QuickAuthenticate(context.Background()); err != nil {
+		t.Fatalf("QuickAuthenticate returned error: %v", err)
+	}
+}
+
+func TestQuickAuthenticateDropsRejectedTrustKey(t *testing.T) {
+	trustFile := filepath.Join(t.TempDir(), "trust-key")
+	if err := os.WriteFile(trustFile, []byte("expired"), 0o600); err != nil {
+		t.Fatalf("failed to write trust key: %v", err)
+	}
+	cfg := &config.KapuaConfig{Username: "user", Password: "pass", AuthMethod: "password", TrustKeyFile: trustFile, MFACode: "123456"}
+	var calls int
+	client := newMFATestClient(cfg, func(req *http.Request) (*http.Response, error) {
+		calls++
+		creds := decodeCredentials(t, req)
+		if creds.TrustKey != "" {
+			return jsonResponse(http.StatusUnauthorized, `{"kapuaErrorCode":"INVALID_TRUST_KEY","message":"invalid trust key"}`), nil
+		}
+		if creds.AuthenticationCode != "123456" {
+			t.Fatalf("expected configured MFA code, got %+v", creds)
+		}
+		return jsonResponse(http.StatusOK, mfaTokenJSON), nil
+	})
+
+	if _, err := client.QuickAuthenticate(context.Background()); err != nil {
+		t.Fatalf("QuickAuthenticate returned error: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected trust key attempt then code attempt, got %d calls", calls)
+	}
+	if data, _ := os.ReadFile(trustFile); strings.TrimSpace(string(data)) != "trust-123" {
+		t.Fatalf("expected new trust key to replace the rejected one, got %q", data)
+	}
+}
+
+func TestQuickAuthenticateKeepsTrustKeyOnWrongPassword(t *testing.T) {
+	trustFile := filepath.Join(t.TempDir(), "trust-key")
+	if err := os.WriteFile(trustFile, []byte("trust-123\n"), 0o600); err != nil {
+		t.Fatalf("failed to write trust key: %v", err)
+	}
+	cfg := &config.KapuaConfig{Username: "user", Password: "wrong", AuthMethod: "password", TrustKeyFile: trustFile, MFACode: "123456"}
+	var calls int
+	client := newMFATestClient(cfg, func(req *http.Request) (*http.Response, error) {
+		calls++
+		return jsonResponse(http.StatusUnauthorized, `{"kapuaErrorCode":"INVALID_CREDENTIALS","message":"wrong password"}`), nil
+	})
+
+	_, err := client.QuickAuthenticate(context.Background())
+	var kapuaErr models.KapuaError
+	if !errors.As(err, &kapuaErr) || kapuaErr.ErrorCode() != "INVALID_CREDENTIALS" {
+		t.Fatalf("expected the Kapua error unchanged, got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected a single trust key attempt, got %d calls", calls)
+	}
+	if data, err := os.ReadFile(trustFile); err != nil || strings.TrimSpace(string(data)) != "trust-123" {
+		t.Fatalf("expected trust key file to be kept, got %q (%v)", data, err)
+	}
+}
+
+func TestAuthenticateWithMFACodeRejected(t *testing.T) {
+	client := newMFATestClient(&config.KapuaConfig{Username: "user", Password: "pass", AuthMethod: "password"},
+		func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(http.StatusUnauthorized, `{"kapuaErrorCode":"INVALID_MFA_CODE","message":"wrong code"}`), nil
+		})
+
+	if _, err := client.AuthenticateWithMFACode(context.Background(), "000000"); !errors.Is(err, ErrMFACodeRejected) {
+		t.Fatalf("expected ErrMFACodeRejected, got %v", err)
+	}
+	if _, err := client.AuthenticateWithMFACode(context.Background(), ""); !errors.Is(err, ErrMFARequired) {
+		t.Fatalf("expected ErrMFARequired for empty code, got %v", err)
+	}
+}
+
+func TestAuthenticationPendingBlocksRequests(t *testing.T) {
+	pending := errors.New("waiting for MFA")
+	client := newMFATestClient(&config.KapuaConfig{}, func(req *http.Request) (*http.Response, error) {
+		t.Fatalf("unexpected request to %s", req.URL.Path)
+		return nil, nil
+	})
+	client.SetAuthenticationPending(pending)
+
+	if _, err := client.GetSystemInfo(context.Background()); !errors.Is(err, pending) {
+		t.Fatalf("expected pending error, got %v", err)
+	}
+
+	client.SetTokenInfo(&models.AccessToken{TokenID: "token"})
+	if err := client.pendingAuthentication(); err != nil {
+		t.Fatalf("expected pending state cleared after login, got %v", err)
+	}
+}
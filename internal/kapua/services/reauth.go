@@ -0,0 +1,91 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"kapua-mcp-server/internal/kapua/models"
+)
+
+// maxErrorBodyPeek bounds how much of an error response is read to classify it.
+const maxErrorBodyPeek = 64 << 10
+
+// tokenRejectedCodes are Kapua error codes meaning the access token itself is no
+// longer accepted (revoked, expired or unknown after a restart). Login failures such
+// as INVALID_LOGIN_CREDENTIALS are excluded: logging in again cannot fix them.
+var tokenRejectedCodes = map[string]bool{
+	"UNAUTHENTICATED":                    true,
+	"INVALID_SESSION_CREDENTIALS":        true,
+	"EXPIRED_SESSION_CREDENTIALS":        true,
+	"INVALID_CREDENTIALS_TOKEN_PROVIDED": true,
+}
+
+// authenticationRejected reports whether resp says the access token was refused:
+// a 401, or an error body carrying one of tokenRejectedCodes. The body is restored
+// so the response can still be handled normally.
+func authenticationRejected(resp *http.Response) bool {
+	if resp.StatusCode == http.StatusUnauthorized {
+		return true
+	}
+	if resp.StatusCode < 400 || resp.Body == nil {
+		return false
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyPeek))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+	if err != nil {
+		return false
+	}
+	var kapuaErr models.KapuaError
+	if json.Unmarshal(body, &kapuaErr) != nil {
+		return false
+	}
+	return tokenRejectedCodes[kapuaErr.ErrorCode()]
+}
+
+// canReauthenticate reports whether the client has credentials to log in again.
+func (c *KapuaClient) canReauthenticate() bool {
+	return c.autoRefresh && c.config != nil
+}
+
+// reauthenticate replaces the rejected token. Calls are serialised so concurrent
+// requests that failed with the same token (e.g. fleet-health goroutines) trigger a
+// single login; the others find a new token in place and simply replay. The refresh
+// token is tried first and a full QuickAuthenticate is the fallback.
+func (c *KapuaClient) reauthenticate(ctx context.Context, rejected string) (err error) {
+	c.reauthMutex.Lock()
+	defer c.reauthMutex.Unlock()
+
+	c.tokenMutex.RLock()
+	current := c.token
+	refreshToken := c.refreshToken
+	refreshExpiry := c.refreshExpiry
+	c.tokenMutex.RUnlock()
+	if current != "" && current != rejected {
+		return nil
+	}
+
+	defer func() {
+		if err != nil {
+			kapuaTokenRefreshes.Inc("failure")
+		} else {
+			kapuaTokenRefreshes.Inc("success")
+		}
+	}()
+
+	if refreshToken != "" && time.Now().Before(refreshExpiry) {
+		_, refreshErr := c.RefreshToken(ctx, models.RefreshTokenRequest{RefreshToken: refreshToken, TokenID: rejected})
+		if refreshErr == nil {
+			return nil
+		}
+		c.logger.Info("Token refresh after rejection failed, logging in again: %v", refreshErr)
+	}
+	_, err = c.QuickAuthenticate(ctx)
+	return err
+}
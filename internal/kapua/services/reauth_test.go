@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"kapua-mcp-server/internal/kapua/config"
+	"kapua-mcp-server/pkg/utils"
+)
+
+// revokingKapua simulates a Kapua that no longer accepts the "old" token.
+type revokingKapua struct {
+	logins    atomic.Int32
+	refreshes atomic.Int32
+	mu        sync.Mutex
+	seen      []string // Authorization headers of device requests
+
+	rejection  func() *http.Response
+	loginReply func() *http.Response
+}
+
+func tokenResponse(token string) *http.Response {
+	expires := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	body := fmt.Sprintf(`{"tokenId":%q,"refreshToken":"refresh-%s","expiresOn":%q,"refreshExpiresOn":%q,"scopeId":"tenant"}`, token, token, expires, expires)
+	return &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Body: io.NopCloser(strings.NewReader(body))}
+}
+
+func (k *revokingKapua) client() *KapuaClient {
+	client := &KapuaClient{
+		config:      &config.KapuaConfig{Username: "kapua-sys", Password: "kapua-password"},
+		logger:      utils.NewDefaultLogger("test"),
+		baseURL:     "http://example.com/v1",
+		scopeId:     "tenant",
+		autoRefresh: true,
+		token:       "old",
+	}
+	client.httpClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		switch req.URL.Path {
+		case "/v1/authentication/user":
+			k.logins.Add(1)
+			time.Sleep(20 * time.Millisecond)
+			if k.loginReply != nil {
+				return k.loginReply(), nil
+			}
+			return tokenResponse("new"), nil
+		case "/v1/authentication/refresh":
+			k.refreshes.Add(1)
+			return tokenResponse("refreshed"), nil
+		}
+		auth := req.Header.Get("Authorization")
+		k.mu.Lock()
+		k.seen = append(k.seen, auth)
+		k.mu.Unlock()
+		if auth == "Bearer old" {
+			if k.rejection != nil {
+				return k.rejection(), nil
+			}
+			return &http.Response{StatusCode: http.StatusUnauthorized, Header: make(http.Header), Body: io.NopCloser(strings.NewReader(`{"kapuaErrorCode":"UNAUTHENTICATED","message":"Unauthenticated"}`))}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Body: io.NopCloser(strings.NewReader(`{"id":"dev-1","clientId":"gw-1"}`))}, nil
+	})}
+	return client
+}
+
+func TestMakeRequestReauthenticatesOn401(t *testing.T) {
+	kapua := &revokingKapua{}
+	client := kapua.client()
+
+	device, err := client.GetDevice(context.Background(), "dev-1")
+	if err != nil {
+		t.Fatalf("expected replayed request to succeed, got %v", err)
+	}
+	if device.ClientID != "gw-1" {
+		t.Fatalf("unexpected device %+v", device)
+	}
+	if kapua.logins.Load() != 1 || client.getToken() != "new" {
+		t.Fatalf("expected one login and the new token, got %d logins and %q", kapua.logins.Load(), client.getToken())
+	}
+	if len(kapua.seen) != 2 || kapua.seen[1] != "Bearer new" {
+		t.Fatalf("expected the request to be replayed with the new token, got %v", kapua.seen)
+	}
+}
+
+func TestMakeRequestReauthenticatesOnKapuaErrorCode(t *testing.T) {
+	kapua := &revokingKapua{rejection: func() *http.Response {
+		return &http.Response{StatusCode: http.StatusInternalServerError, Header: make(http.Header), Body: io.NopCloser(strings.NewReader(`{"kapuaErrorCode":"EXPIRED_SESSION_CREDENTIALS","message":"expired"}`))}
+	}}
+	client := kapua.client()
+
+	if _, err := client.GetDevice(context.Background(), "dev-1"); err != nil {
+		t.Fatalf("expected replayed request to succeed, got %v", err)
+	}
+	if kapua.logins.Load() != 1 {
+		t.Fatalf("expected one login, got %d", kapua.logins.Load())
+	}
+}
+
+func TestMakeRequestKeepsOtherErrorBodies(t *testing.T) {
+	kapua := &revokingKapua{rejection: func() *http.Response {
+		return &http.Response{StatusCode: http.StatusForbidden, Header: make(http.Header), Body: io.NopCloser(strings.NewReader(`{"kapuaErrorCode":"SUBJECT_UNAUTHORIZED","message":"not allowed"}`))}
+	}}
+	client := kapua.client()
+
+	_, err := client.GetDevice(context.Background(), "dev-1")
+	if err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Fatalf("expected the Kapua error to be returned intact, got %v", err)
+	}
+	if kapua.logins.Load() != 0 {
+		t.Fatalf("authorization errors must not trigger a login, got %d", kapua.logins.Load())
+	}
+}
+
+func TestMakeRequestReauthenticatesOnceForConcurrentRequests(t *testing.T) {
+	kapua := &revokingKapua{}
+	client := kapua.client()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.GetDevice(context.Background(), "dev-1")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if kapua.logins.Load() != 1 {
+		t.Fatalf("expected a single login for concurrent rejections, got %d", kapua.logins.Load())
+	}
+}
+
+func TestMakeRequestPrefersRefreshToken(t *testing.T) {
+	kapua := &revokingKapua{}
+	client := kapua.client()
+	client.refreshToken = "refresh-old"
+	client.refreshExpiry = time.Now().Add(time.Hour)
+
+	if _, err := client.GetDevice(context.Background(), "dev-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if kapua.refreshes.Load() != 1 || kapua.logins.Load() != 0 || client.getToken() != "refreshed" {
+		t.Fatalf("expected a refresh instead of a login, got %d refreshes, %d logins, token %q", kapua.refreshes.Load(), kapua.logins.Load(), client.getToken())
+	}
+}
+
+func TestMakeRequestReauthenticationFailure(t *testing.T) {
+	kapua := &revokingKapua{loginReply: func() *http.Response {
+		return &http.Response{StatusCode: http.StatusUnauthorized, Header: make(http.Header), Body: io.NopCloser(strings.NewReader(`{"kapuaErrorCode":"INVALID_LOGIN_CREDENTIALS","message":"bad credentials"}`))}
+	}}
+	client := kapua.client()
+
+	before := kapuaTokenRefreshes.Value("failure")
+	_, err := client.GetDevice(context.Background(), "dev-1")
+	if err == nil || !strings.Contains(err.Error(), "re-authentication failed") || !strings.Contains(err.Error(), "bad credentials") {
+		t.Fatalf("expected re-authentication error, got %v", err)
+	}
+	if kapua.logins.Load() != 1 || len(kapua.seen) != 1 {
+		t.Fatalf("expected one login and no replay, got %d logins and %d requests", kapua.logins.Load(), len(kapua.seen))
+	}
+	if kapuaTokenRefreshes.Value("failure")-before != 1 {
+		t.Fatal("expected the failed re-authentication to be counted")
+	}
+}
+
+func TestMakeRequestWithoutCredentialsDoesNotReauthenticate(t *testing.T) {
+	client := newTestKapuaClient()
+	client.httpClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if strings.Contains(req.URL.Path, "/authentication/") {
+			t.Fatalf("unexpected login %s", req.URL.Path)
+		}
+		return &http.Response{StatusCode: http.StatusUnauthorized, Header: make(http.Header), Body: io.NopCloser(strings.NewReader(`{"message":"Unauthenticated"}`))}, nil
+	})}
+
+	if _, err := client.GetDevice(context.Background(), "dev-1"); err == nil {
+		t.Fatal("expected 401 error")
+	}
+}
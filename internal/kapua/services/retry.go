@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"kapua-mcp-server/internal/kapua/config"
+)
+
+// retryPolicy decides whether and when a failed Kapua request is attempted again.
+// The zero value never retries.
+type retryPolicy struct {
+	maxRetries int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+}
+
+func newRetryPolicy(cfg *config.KapuaConfig) retryPolicy {
+	return retryPolicy{maxRetries: cfg.MaxRetries, baseDelay: cfg.RetryBaseDelay, maxDelay: cfg.RetryMaxDelay}
+}
+
+// next reports whether the request should be sent again after attempt (counting
+// from 1) ended with resp or err, and how long to wait first. Retry-After is
+// honoured on 429 and 503; when it asks for more than maxDelay, or any wait would
+// outlive ctx, the current result is returned instead.
+func (p retryPolicy) next(ctx context.Context, method string, attempt int, resp *http.Response, err error) (time.Duration, bool) {
+	if attempt > p.maxRetries || !isIdempotent(method) {
+		return 0, false
+	}
+	var wait time.Duration
+	switch {
+	case err != nil:
+		if !retryableError(ctx, err) {
+			return 0, false
+		}
+		wait = p.backoff(attempt)
+	case retryableStatus(resp.StatusCode):
+		if after, ok := retryAfter(resp, time.Now()); ok {
+			if after > p.maxDelay {
+				return 0, false
+			}
+			wait = after
+		} else {
+			wait = p.backoff(attempt)
+		}
+	default:
+		return 0, false
+	}
+	if exceedsDeadline(ctx, wait) {
+		return 0, false
+	}
+	return wait, true
+}
+
+// isIdempotent reports whether repeating method cannot cause a second side effect.
+// POST covers device commands, bundle start/stop, snapshot rollback and logins,
+// so it is never retried.
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// retryableStatus reports whether status signals a transient gateway or throttling failure.
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// retryableError reports whether a transport error may succeed on another attempt.
+// Cancellation and deadline errors of the caller's context are final.
+func retryableError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	return true
+}
+
+// backoff returns the exponential delay before retry number attempt (starting at 1),
+// with "equal jitter": half the delay is fixed, the other half random.
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	delay := p.baseDelay
+	for i := 1; i < attempt && delay < p.maxDelay; i++ {
+		delay *= 2
+	}
+	if p.maxDelay > 0 && delay > p.maxDelay {
+		delay = p.maxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// retryAfter parses the Retry-After header of a 429 or 503 response, given either
+// in seconds or as an HTTP date. It returns false when absent or unparsable.
+func retryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+	value := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := at.Sub(now); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// sleepContext waits for d or until ctx ends, whichever comes first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// exceedsDeadline reports whether waiting d would outlive ctx, in which case a
+// retry cannot finish and the current result is returned instead.
+func exceedsDeadline(ctx context.Context, d time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	return ok && time.Until(deadline) < d
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newRetryingTestClient(responses ...func(*http.Request) (*http.Response, error)) (*KapuaClient, *atomic.Int32) {
+	var calls atomic.Int32
+	client := newTestKapuaClient()
+	client.retry = retryPolicy{maxRetries: 3, baseDelay: time.Millisecond, maxDelay: 50 * time.Millisecond}
+	client.httpClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		n := int(calls.Add(1)) - 1
+		if n >= len(responses) {
+			n = len(responses) - 1
+		}
+		return responses[n](req)
+	})}
+	return client, &calls
+}
+
+func statusResponse(status int, header http.Header, body string) func(*http.Request) (*http.Response, error) {
+	return func(*http.Request) (*http.Response, error) {
+		if header == nil {
+			header = make(http.Header)
+		}
+		return &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(strings.NewReader(body))}, nil
+	}
+}
+
+func TestMakeRequestRetriesIdempotentRequests(t *testing.T) {
+	client, calls := newRetryingTestClient(
+		statusResponse(http.StatusBadGateway, nil, "bad gateway"),
+		func(*http.Request) (*http.Response, error) { return nil, errors.New("dial tcp: connection refused") },
+		statusResponse(http.StatusOK, nil, `{"id":"dev-1","clientId":"gw-1"}`),
+	)
+
+	before := kapuaRequestRetries.Value(http.MethodGet, "/{scopeId}/devices/{id}")
+	device, err := client.GetDevice(context.Background(), "dev-1")
+	if err != nil {
+		t.Fatalf("expected success after retries, got %v", err)
+	}
+	if device.ClientID != "gw-1" || calls.Load() != 3 {
+		t.Fatalf("unexpected result %+v after %d attempts", device, calls.Load())
+	}
+	if got := kapuaRequestRetries.Value(http.MethodGet, "/{scopeId}/devices/{id}") - before; got != 2 {
+		t.Fatalf("expected 2 retries counted, got %v", got)
+	}
+}
+
+func TestMakeRequestResendsBody(t *testing.T) {
+	var bodies []string
+	record := func(req *http.Request) (*http.Response, error) {
+		data, _ := io.ReadAll(req.Body)
+		bodies = append(bodies, string(data))
+		return statusResponse(http.StatusServiceUnavailable, nil, "")(req)
+	}
+	client, _ := newRetryingTestClient(record, record, func(req *http.Request) (*http.Response, error) {
+		data, _ := io.ReadAll(req.Body)
+		bodies = append(bodies, string(data))
+		return statusResponse(http.StatusNoContent, nil, "")(req)
+	})
+
+	resp, err := client.makeRequest(context.Background(), http.MethodPut, "/tenant/devices/dev-1", map[string]string{"displayName": "gw"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if len(bodies) != 3 || bodies[0] != bodies[2] || bodies[2] != `{"displayName":"gw"}` {
+		t.Fatalf("expected identical bodies on each attempt, got %q", bodies)
+	}
+}
+
+func TestMakeRequestNeverRetriesPost(t *testing.T) {
+	client, calls := newRetryingTestClient(statusResponse(http.StatusServiceUnavailable, nil, "unavailable"))
+
+	if err := client.RollbackDeviceSnapshot(context.Background(), "dev-1", "snap-1"); err == nil {
+		t.Fatal("expected rollback to fail")
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected a single attempt for POST, got %d", calls.Load())
+	}
+}
+
+func TestMakeRequestDoesNotRetryClientErrors(t *testing.T) {
+	client, calls := newRetryingTestClient(statusResponse(http.StatusNotFound, nil, `{}`))
+
+	if _, err := client.GetDevice(context.Background(), "dev-1"); err == nil {
+		t.Fatal("expected not found error")
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected a single attempt for 404, got %d", calls.Load())
+	}
+}
+
+func TestMakeRequestHonoursRetryAfter(t *testing.T) {
+	t.Run("short wait is retried", func(t *testing.T) {
+		client, calls := newRetryingTestClient(
+			statusResponse(http.StatusTooManyRequests, http.Header{"Retry-After": {"0"}}, ""),
+			statusResponse(http.StatusOK, nil, `{"id":"dev-1"}`),
+		)
+		if _, err := client.GetDevice(context.Background(), "dev-1"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if calls.Load() != 2 {
+			t.Fatalf("expected 2 attempts, got %d", calls.Load())
+		}
+	})
+
+	t.Run("wait beyond max delay is returned", func(t *testing.T) {
+		client, calls := newRetryingTestClient(statusResponse(http.StatusServiceUnavailable, http.Header{"Retry-After": {"120"}}, "maintenance"))
+		_, err := client.GetDevice(context.Background(), "dev-1")
+		if err == nil || !strings.Contains(err.Error(), "503") {
+			t.Fatalf("expected 503 error, got %v", err)
+		}
+		if calls.Load() != 1 {
+			t.Fatalf("expected no retry, got %d attempts", calls.Load())
+		}
+	})
+}
+
+func TestMakeRequestStopsAtContextDeadline(t *testing.T) {
+	client, calls := newRetryingTestClient(statusResponse(http.StatusServiceUnavailable, nil, ""))
+	client.retry.baseDelay, client.retry.maxDelay = time.Second, time.Second
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := client.GetDevice(ctx, "dev-1"); err == nil {
+		t.Fatal("expected error")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("retry outlived the context deadline: %v", elapsed)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected no retry that cannot finish before the deadline, got %d attempts", calls.Load())
+	}
+}
+
+func TestMakeRequestStopsRetryingWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	client, calls := newRetryingTestClient(func(req *http.Request) (*http.Response, error) {
+		cancel()
+		return nil, context.Canceled
+	})
+
+	if _, err := client.GetDevice(ctx, "dev-1"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation error, got %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected no retry after cancellation, got %d attempts", calls.Load())
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	resp := func(status int, value string) *http.Response {
+		return &http.Response{StatusCode: status, Header: http.Header{"Retry-After": {value}}}
+	}
+
+	if wait, ok := retryAfter(resp(http.StatusTooManyRequests, "7"), now); !ok || wait != 7*time.Second {
+		t.Fatalf("expected 7s, got %v %v", wait, ok)
+	}
+	if wait, ok := retryAfter(resp(http.StatusServiceUnavailable, "Wed, 01 May 2024 12:00:30 GMT"), now); !ok || wait != 30*time.Second {
+		t.Fatalf("expected 30s, got %v %v", wait, ok)
+	}
+	if _, ok := retryAfter(resp(http.StatusBadGateway, "7"), now); ok {
+		t.Fatal("Retry-After must only apply to 429 and 503")
+	}
+	if _, ok := retryAfter(resp(http.StatusTooManyRequests, "soon"), now); ok {
+		t.Fatal("expected unparsable Retry-After to be ignored")
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	policy := retryPolicy{maxRetries: 5, baseDelay: 100 * time.Millisecond, maxDelay: 300 * time.Millisecond}
+	bounds := []struct{ min, max time.Duration }{
+		{50 * time.Millisecond, 100 * time.Millisecond},
+		{100 * time.Millisecond, 200 * time.Millisecond},
+		{150 * time.Millisecond, 300 * time.Millisecond},
+		{150 * time.Millisecond, 300 * time.Millisecond},
+	}
+	for i, bound := range bounds {
+		for range 20 {
+			if d := policy.backoff(i + 1); d < bound.min || d > bound.max {
+				t.Fatalf("backoff(%d) = %v, want within [%v, %v]", i+1, d, bound.min, bound.max)
+			}
+		}
+	}
+	if (retryPolicy{}).backoff(1) != 0 {
+		t.Fatal("zero policy must not wait")
+	}
+}
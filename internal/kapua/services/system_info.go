@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"kapua-mcp-server/internal/kapua/models"
+)
+
+// GetSystemInfo retrieves the version and build information exposed by GET /sys-info.
+func (c *KapuaClient) GetSystemInfo(ctx context.Context) (*models.SystemInfo, error) {
+	var info models.SystemInfo
+	if err := c.doKapuaRequest(ctx, http.MethodGet, "/sys-info", "get system info", nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// DetectServerInfo queries /sys-info and probes flavour-specific APIs to work out
+// whether the endpoint is Eclipse Kapua or Everyware Cloud. The result is cached on
+// the client so later calls can skip APIs that are known to be unsupported.
+func (c *KapuaClient) DetectServerInfo(ctx context.Context) (*models.ServerInfo, error) {
+	sysInfo, err := c.GetSystemInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &models.ServerInfo{
+		SystemInfo: *sysInfo,
+		Flavour:    models.ServerFlavourUnknown,
+		DetectedAt: time.Now().UTC(),
+	}
+
+	// Device logs are an Everyware Cloud extension: Eclipse Kapua answers 404.
+	switch supported, err := c.probeDeviceLogs(ctx); {
+	case err != nil:
+		c.logger.Warn("Unable to probe device logs API, flavour left undetected: %v", err)
+		info.Capabilities.DeviceLogs = true
+	case supported:
+		info.Flavour = models.ServerFlavourEverywareCloud
+		info.Capabilities.DeviceLogs = true
+	default:
+		info.Flavour = models.ServerFlavourEclipseKapua
+	}
+
+	c.infoMutex.Lock()
+	c.serverInfo = info
+	c.infoMutex.Unlock()
+
+	c.logger.Info("Detected %s (version %s, build %s)", info.Flavour, sysInfo.Version, sysInfo.BuildNumber)
+	return info, nil
+}
+
+// ServerInfo returns the cached result of DetectServerInfo, or nil when detection has not run.
+func (c *KapuaClient) ServerInfo() *models.ServerInfo {
+	c.infoMutex.RLock()
+	defer c.infoMutex.RUnlock()
+	return c.serverInfo
+}
+
+// probeDeviceLogs reports whether the scoped /deviceLogs endpoint exists.
+func (c *KapuaClient) probeDeviceLogs(ctx context.Context) (bool, error) {
+	resp, err := c.makeRequest(ctx, http.MethodGet, c.scopedEndpoint("/deviceLogs?limit=1"), nil)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return false, nil
+	case resp.StatusCode < 300:
+		return true, nil
+	default:
+		return false, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+}
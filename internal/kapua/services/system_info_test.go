@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"kapua-mcp-server/internal/kapua/models"
+)
+
+func systemInfoTransport(t *testing.T, deviceLogsStatus int) roundTripFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		var status int
+		var body string
+		switch req.URL.Path {
+		case "/v1/sys-info":
+			status, body = http.StatusOK, `{"version":"2.0.0","revision":"e53c7b7","buildDate":"2023-03-02T08:50:59Z UTC","buildNumber":"142","buildBranch":"release/2.0.0"}`
+		case "/v1/tenant/deviceLogs":
+			if got := req.URL.Query().Get("limit"); got != "1" {
+				t.Fatalf("expected limit=1 probe, got %q", got)
+			}
+			status, body = deviceLogsStatus, `{}`
+		default:
+			t.Fatalf("unexpected path %s", req.URL.Path)
+		}
+		return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header)}, nil
+	}
+}
+
+func TestGetSystemInfo(t *testing.T) {
+	client := newTestKapuaClient()
+	client.httpClient = &http.Client{Transport: systemInfoTransport(t, http.StatusOK)}
+
+	info, err := client.GetSystemInfo(context.Background())
+	if err != nil {
+		t.Fatalf("GetSystemInfo returned error: %v", err)
+	}
+	if info.Version != "2.0.0" || info.BuildBranch != "release/2.0.0" || info.BuildNumber != "142" {
+		t.Fatalf("unexpected system info: %+v", info)
+	}
+}
+
+func TestDetectServerInfoFlavours(t *testing.T) {
+	cases := []struct {
+		name       string
+		status     int
+		flavour    models.ServerFlavour
+		deviceLogs bool
+	}{
+		{name: "everyware cloud", status: http.StatusOK, flavour: models.ServerFlavourEverywareCloud, deviceLogs: true},
+		{name: "eclipse kapua", status: http.StatusNotFound, flavour: models.ServerFlavourEclipseKapua, deviceLogs: false},
+		{name: "undetected", status: http.StatusForbidden, flavour: models.ServerFlavourUnknown, deviceLogs: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestKapuaClient()
+			client.httpClient = &http.Client{Transport: systemInfoTransport(t, tc.status)}
+
+			if client.ServerInfo() != nil {
+				t.Fatal("expected no cached server info before detection")
+			}
+			info, err := client.DetectServerInfo(context.Background())
+			if err != nil {
+				t.Fatalf("DetectServerInfo returned error: %v", err)
+			}
+			if info.Flavour != tc.flavour {
+				t.Fatalf("expected flavour %s, got %s", tc.flavour, info.Flavour)
+			}
+			if info.Capabilities.DeviceLogs != tc.deviceLogs {
+				t.Fatalf("expected deviceLogs=%v, got %v", tc.deviceLogs, info.Capabilities.DeviceLogs)
+			}
+			if client.ServerInfo() != info {
+				t.Fatal("expected detected info to be cached on the client")
+			}
+		})
+	}
+}
+
+func TestListDeviceLogsSkipsRequestWhenUnsupported(t *testing.T) {
+	client := newTestKapuaClient()
+	client.serverInfo = &models.ServerInfo{Flavour: models.ServerFlavourEclipseKapua}
+	client.httpClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		t.Fatalf("unexpected request to %s", req.URL.Path)
+		return nil, nil
+	})}
+
+	if _, err := client.ListDeviceLogs(context.Background(), nil); !errors.Is(err, ErrDeviceLogsNotSupported) {
+		t.Fatalf("expected ErrDeviceLogsNotSupported, got %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"kapua-mcp-server/pkg/tracing"
+)
+
+func TestMakeRequestTracesKapuaCall(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	var traceparent string
+	client := newTestKapuaClient()
+	client.httpClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		traceparent = req.Header.Get("traceparent")
+		return &http.Response{StatusCode: http.StatusInternalServerError, Body: io.NopCloser(strings.NewReader(`{}`)), Header: make(http.Header)}, nil
+	})}
+
+	ctx, parent := tracing.Tracer().Start(context.Background(), "tools/call kapua-device-get", trace.WithSpanKind(trace.SpanKindServer))
+	_, _ = client.GetDevice(ctx, "dev-1")
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected client and parent spans, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "GET /{scopeId}/devices/{id}" || span.SpanKind != trace.SpanKindClient || span.Status.Code != codes.Error {
+		t.Fatalf("unexpected client span: %+v", span)
+	}
+	if span.SpanContext.TraceID() != parent.SpanContext().TraceID() || span.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Fatalf("client span not parented by the tool span: %+v", span)
+	}
+	attrs := attribute.NewSet(span.Attributes...)
+	if value, _ := attrs.Value("url.template"); value.AsString() != "/{scopeId}/devices/{id}" {
+		t.Fatalf("unexpected attributes: %v", span.Attributes)
+	}
+	if value, _ := attrs.Value("http.response.status_code"); value.AsInt64() != 500 {
+		t.Fatalf("unexpected attributes: %v", span.Attributes)
+	}
+	if want := "00-" + span.SpanContext.TraceID().String() + "-" + span.SpanContext.SpanID().String() + "-01"; traceparent != want {
+		t.Fatalf("expected outgoing traceparent %s, got %s", want, traceparent)
+	}
+}
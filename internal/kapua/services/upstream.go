@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// upstreamWindow is the period over which the upstream error rate is computed.
+const upstreamWindow = 5 * time.Minute
+
+// maxUpstreamOutcomes bounds the outcomes kept for the error rate under heavy load.
+const maxUpstreamOutcomes = 4096
+
+// UpstreamStatus summarises recent Kapua requests for readiness reporting.
+type UpstreamStatus struct {
+	LastSuccess *time.Time    `json:"lastSuccess,omitempty"`
+	LastError   string        `json:"lastError,omitempty"`
+	Requests    int           `json:"requests"` // Requests in Window
+	Failures    int           `json:"failures"` // Connection errors and 5xx answers in Window
+	Window      time.Duration `json:"-"`
+}
+
+// ErrorRate returns the share of failed requests in the window, 0 without requests.
+func (s UpstreamStatus) ErrorRate() float64 {
+	if s.Requests == 0 {
+		return 0
+	}
+	return float64(s.Failures) / float64(s.Requests)
+}
+
+type upstreamOutcome struct {
+	at     time.Time
+	failed bool
+}
+
+// upstreamStats records the outcome of Kapua requests. It is shared by a client and
+// its session clients; a nil upstreamStats records nothing.
+type upstreamStats struct {
+	mu          sync.Mutex
+	now         func() time.Time
+	lastSuccess time.Time
+	lastError   string
+	outcomes    []upstreamOutcome // Oldest first
+}
+
+func newUpstreamStats() *upstreamStats {
+	return &upstreamStats{now: time.Now}
+}
+
+// record classifies the final result of a request. Requests cancelled by the
+// caller say nothing about Kapua and are ignored.
+func (s *upstreamStats) record(ctx context.Context, resp *http.Response, err error) {
+	if s == nil || (err != nil && ctx.Err() != nil) {
+		return
+	}
+	failed := err != nil || resp.StatusCode >= http.StatusInternalServerError
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	switch {
+	case err != nil:
+		s.lastError = err.Error()
+	case failed:
+		s.lastError = fmt.Sprintf("HTTP %d", resp.StatusCode)
+	default:
+		s.lastSuccess = now
+	}
+	s.prune(now)
+	if len(s.outcomes) == maxUpstreamOutcomes {
+		s.outcomes = s.outcomes[1:]
+	}
+	s.outcomes = append(s.outcomes, upstreamOutcome{at: now, failed: failed})
+}
+
+func (s *upstreamStats) prune(now time.Time) {
+	cutoff := now.Add(-upstreamWindow)
+	drop := 0
+	for drop < len(s.outcomes) && s.outcomes[drop].at.Before(cutoff) {
+		drop++
+	}
+	if drop > 0 {
+		s.outcomes = append(s.outcomes[:0], s.outcomes[drop:]...)
+	}
+}
+
+func (s *upstreamStats) status() UpstreamStatus {
+	status := UpstreamStatus{Window: upstreamWindow}
+	if s == nil {
+		return status
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(s.now())
+	if !s.lastSuccess.IsZero() {
+		lastSuccess := s.lastSuccess
+		status.LastSuccess = &lastSuccess
+	}
+	status.LastError = s.lastError
+	status.Requests = len(s.outcomes)
+	for _, outcome := range s.outcomes {
+		if outcome.failed {
+			status.Failures++
+		}
+	}
+	return status
+}
+
+// UpstreamStatus reports the last successful Kapua call and the recent error rate.
+func (c *KapuaClient) UpstreamStatus() UpstreamStatus {
+	return c.upstream.status()
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestUpstreamStats(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	stats := newUpstreamStats()
+	stats.now = func() time.Time { return now }
+
+	stats.record(context.Background(), &http.Response{StatusCode: http.StatusOK}, nil)
+	now = now.Add(time.Minute)
+	stats.record(context.Background(), &http.Response{StatusCode: http.StatusNotFound}, nil)
+	stats.record(context.Background(), &http.Response{StatusCode: http.StatusBadGateway}, nil)
+	stats.record(context.Background(), nil, errors.New("dial tcp: connection refused"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	stats.record(ctx, nil, context.Canceled)
+
+	status := stats.status()
+	if status.Requests != 4 || status.Failures != 2 || status.ErrorRate() != 0.5 {
+		t.Fatalf("unexpected counts: %+v", status)
+	}
+	// The 404 counts as a success: Kapua answered.
+	if status.LastSuccess == nil || !status.LastSuccess.Equal(now) || status.LastError != "dial tcp: connection refused" {
+		t.Fatalf("unexpected last outcomes: %+v", status)
+	}
+
+	now = now.Add(upstreamWindow)
+	status = stats.status()
+	if status.Requests != 3 || status.Failures != 2 {
+		t.Fatalf("expected the oldest request to leave the window: %+v", status)
+	}
+
+	now = now.Add(time.Minute)
+	if status := stats.status(); status.Requests != 0 || status.ErrorRate() != 0 || status.LastSuccess == nil {
+		t.Fatalf("expected an empty window that keeps the last success: %+v", status)
+	}
+}
@@ -0,0 +1,40 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"kapua-mcp-server/internal/kapua/services"
+)
+
+// healthResponse is the body of GET /health.
+type healthResponse struct {
+	Status string       `json:"status"` // "ok", or "degraded" while the Kapua circuit is not closed
+	Kapua  *kapuaHealth `json:"kapua,omitempty"`
+}
+
+type kapuaHealth struct {
+	Circuit services.BreakerStatus `json:"circuit"`
+}
+
+// serveHealth reports liveness. It answers 200 even while Kapua is unreachable, so
+// that orchestrators do not restart the server for a backend outage; the circuit
+// breaker state tells callers whether Kapua calls currently fail fast.
+func (s *Server) serveHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	health := healthResponse{Status: "ok"}
+	if s.kapuaClient != nil {
+		circuit := s.kapuaClient.BreakerStatus()
+		health.Kapua = &kapuaHealth{Circuit: circuit}
+		if circuit.State != services.CircuitClosed {
+			health.Status = "degraded"
+		}
+	}
+	body, _ := json.Marshal(health)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
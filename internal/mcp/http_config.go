@@ -6,12 +6,29 @@ import (
 	"strings"
 )
 
+// Session authentication modes for the HTTP transport.
+const (
+	// SessionAuthShared serves every MCP session with the Kapua client authenticated at startup.
+	SessionAuthShared = "shared"
+	// SessionAuthHeader authenticates each MCP session with the Kapua credentials sent by its client.
+	SessionAuthHeader = "header"
+)
+
 // HTTPConfig controls the HTTP transport settings for the MCP server.
 type HTTPConfig struct {
 	Host           string
 	Port           int
 	AllowedOrigins []string
+	SessionAuth    string
+	OAuth          *OAuthConfig // nil disables bearer token authorization
 
+	TLSCertFile     string // Server certificate (PEM); enables HTTPS together with TLSKeyFile
+	TLSKeyFile      string // Server private key (PEM)
+	TLSClientCAFile string // CA bundle used to require and verify client certificates (mTLS)
+	TLSMinVersion   string // "1.2" (default) or "1.3"
+	ToolPolicyFile  string // JSON file mapping client certificate subjects to allowed tools
+
+	toolPolicy *toolPolicy
 	rawOrigins []string
 }
 
@@ -19,8 +36,9 @@ type HTTPConfig struct {
 // It defaults to localhost:8000 when no explicit host/port are provided.
 func LoadHTTPConfig() (*HTTPConfig, error) {
 	cfg := &HTTPConfig{
-		Host: "localhost",
-		Port: 8000,
+		Host:        "localhost",
+		Port:        8000,
+		SessionAuth: SessionAuthShared,
 	}
 
 	var origins []string
@@ -31,9 +49,51 @@ func LoadHTTPConfig() (*HTTPConfig, error) {
 
 	cfg.SetAllowedOrigins(origins)
 
+	if mode := strings.TrimSpace(os.Getenv("MCP_SESSION_AUTH")); mode != "" {
+		switch mode {
+		case SessionAuthShared, SessionAuthHeader:
+			cfg.SessionAuth = mode
+		default:
+			return nil, fmt.Errorf("unsupported MCP_SESSION_AUTH %q: must be %q or %q", mode, SessionAuthShared, SessionAuthHeader)
+		}
+	}
+
+	oauth, err := loadOAuthConfig()
+	if err != nil {
+		return nil, err
+	}
+	cfg.OAuth = oauth
+
+	cfg.TLSCertFile = strings.TrimSpace(os.Getenv("MCP_TLS_CERT_FILE"))
+	cfg.TLSKeyFile = strings.TrimSpace(os.Getenv("MCP_TLS_KEY_FILE"))
+	cfg.TLSClientCAFile = strings.TrimSpace(os.Getenv("MCP_TLS_CLIENT_CA_FILE"))
+	cfg.TLSMinVersion = strings.TrimSpace(os.Getenv("MCP_TLS_MIN_VERSION"))
+	if _, err := parseTLSMinVersion(cfg.TLSMinVersion); err != nil {
+		return nil, err
+	}
+
+	if cfg.ToolPolicyFile = strings.TrimSpace(os.Getenv("MCP_TOOL_POLICY_FILE")); cfg.ToolPolicyFile != "" {
+		if cfg.toolPolicy, err = loadToolPolicy(cfg.ToolPolicyFile); err != nil {
+			return nil, err
+		}
+	}
+
 	return cfg, nil
 }
 
+// loadedToolPolicy returns the tool policy, reading ToolPolicyFile if it was not loaded yet.
+func (cfg *HTTPConfig) loadedToolPolicy() (*toolPolicy, error) {
+	if cfg.toolPolicy != nil || cfg.ToolPolicyFile == "" {
+		return cfg.toolPolicy, nil
+	}
+	policy, err := loadToolPolicy(cfg.ToolPolicyFile)
+	if err != nil {
+		return nil, err
+	}
+	cfg.toolPolicy = policy
+	return policy, nil
+}
+
 // SetPort updates the configured port and recomputes the derived origin list.
 func (cfg *HTTPConfig) SetPort(port int) {
 	cfg.Port = port
@@ -0,0 +1,27 @@
+package mcp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadHTTPConfigSessionAuth(t *testing.T) {
+	t.Setenv("MCP_SESSION_AUTH", "")
+	cfg, err := LoadHTTPConfig()
+	if err != nil {
+		t.Fatalf("LoadHTTPConfig returned error: %v", err)
+	}
+	if cfg.SessionAuth != SessionAuthShared {
+		t.Fatalf("expected default session auth %q, got %q", SessionAuthShared, cfg.SessionAuth)
+	}
+
+	t.Setenv("MCP_SESSION_AUTH", "header")
+	if cfg, err = LoadHTTPConfig(); err != nil || cfg.SessionAuth != SessionAuthHeader {
+		t.Fatalf("expected header session auth, got %+v (%v)", cfg, err)
+	}
+
+	t.Setenv("MCP_SESSION_AUTH", "cookie")
+	if _, err := LoadHTTPConfig(); err == nil || !strings.Contains(err.Error(), "unsupported MCP_SESSION_AUTH") {
+		t.Fatalf("expected unsupported MCP_SESSION_AUTH error, got %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.opentelemetry.io/otel/trace"
+
+	"kapua-mcp-server/pkg/utils"
+)
+
+// logFieldsMiddleware attaches the tool name, session ID, device or client ID and trace ID
+// of a tool call to its context, so loggers derived with WithContext include them.
+// It runs inside tracingMiddleware to see the call's span.
+func logFieldsMiddleware(next mcpsdk.MethodHandler) mcpsdk.MethodHandler {
+	return func(ctx context.Context, method string, req mcpsdk.Request) (mcpsdk.Result, error) {
+		var fields []any
+		switch r := req.(type) {
+		case *mcpsdk.CallToolRequest:
+			if r.Params == nil {
+				break
+			}
+			fields = append(fields, "tool", r.Params.Name)
+			var args struct {
+				DeviceID string `json:"deviceId"`
+				ClientID string `json:"clientId"`
+			}
+			if len(r.Params.Arguments) > 0 && json.Unmarshal(r.Params.Arguments, &args) == nil {
+				if args.DeviceID != "" {
+					fields = append(fields, "device_id", args.DeviceID)
+				}
+				if args.ClientID != "" {
+					fields = append(fields, "client_id", args.ClientID)
+				}
+			}
+		case *mcpsdk.ReadResourceRequest:
+			if r.Params != nil {
+				fields = append(fields, "resource", r.Params.URI)
+			}
+		default:
+			return next(ctx, method, req)
+		}
+
+		if session := req.GetSession(); session != nil && session.ID() != "" {
+			fields = append(fields, "session_id", session.ID())
+		}
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			fields = append(fields, "trace_id", sc.TraceID().String())
+		}
+		return next(utils.ContextWithLogFields(ctx, fields...), method, req)
+	}
+}
@@ -0,0 +1,66 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"kapua-mcp-server/pkg/utils"
+)
+
+type deviceToolInput struct {
+	DeviceID string `json:"deviceId,omitempty"`
+	ClientID string `json:"clientId,omitempty"`
+}
+
+func TestLogFieldsMiddleware(t *testing.T) {
+	server := mcpsdk.NewServer(&mcpsdk.Implementation{Name: "test", Version: "dev"}, nil)
+	server.AddReceivingMiddleware(logFieldsMiddleware)
+	var fields []any
+	mcpsdk.AddTool(server, &mcpsdk.Tool{Name: "kapua-device-get"}, func(ctx context.Context, _ *mcpsdk.CallToolRequest, _ deviceToolInput) (*mcpsdk.CallToolResult, any, error) {
+		fields = utils.LogFieldsFromContext(ctx)
+		return &mcpsdk.CallToolResult{Content: []mcpsdk.Content{&mcpsdk.TextContent{Text: "ok"}}}, nil, nil
+	})
+
+	serverTransport, clientTransport := mcpsdk.NewInMemoryTransports()
+	serverSession, err := server.Connect(context.Background(), serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server connect failed: %v", err)
+	}
+	defer serverSession.Close()
+	client, err := mcpsdk.NewClient(&mcpsdk.Implementation{Name: "client", Version: "dev"}, nil).Connect(context.Background(), clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect failed: %v", err)
+	}
+	defer client.Close()
+
+	if _, err := client.CallTool(context.Background(), &mcpsdk.CallToolParams{Name: "kapua-device-get", Arguments: map[string]any{"deviceId": "dev-42"}}); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+
+	got := map[any]any{}
+	for i := 0; i+1 < len(fields); i += 2 {
+		got[fields[i]] = fields[i+1]
+	}
+	if got["tool"] != "kapua-device-get" || got["device_id"] != "dev-42" {
+		t.Fatalf("unexpected log fields: %v", fields)
+	}
+	if _, ok := got["trace_id"]; ok {
+		t.Fatalf("expected no trace_id while tracing is disabled: %v", fields)
+	}
+
+	if _, err := client.CallTool(context.Background(), &mcpsdk.CallToolParams{Name: "kapua-device-get", Arguments: map[string]any{"clientId": "gateway-07"}}); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	got = map[any]any{}
+	for i := 0; i+1 < len(fields); i += 2 {
+		got[fields[i]] = fields[i+1]
+	}
+	if got["client_id"] != "gateway-07" {
+		t.Fatalf("expected client_id field, got %v", fields)
+	}
+	if _, ok := got["device_id"]; ok {
+		t.Fatalf("expected no device_id without deviceId: %v", fields)
+	}
+}
@@ -0,0 +1,85 @@
+package mcp
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"kapua-mcp-server/pkg/metrics"
+)
+
+var (
+	toolCalls = metrics.NewCounterVec(
+		"mcp_tool_calls_total",
+		"MCP tool calls by tool name.",
+		"tool")
+	toolErrors = metrics.NewCounterVec(
+		"mcp_tool_errors_total",
+		"MCP tool calls that failed or returned an error result, by tool name.",
+		"tool")
+	toolDuration = metrics.NewHistogramVec(
+		"mcp_tool_call_duration_seconds",
+		"Latency of MCP tool calls by tool name.",
+		nil, "tool")
+	activeSessions = metrics.NewGauge(
+		"mcp_active_sessions",
+		"MCP sessions currently open.")
+	activeSubscriptions = metrics.NewGauge(
+		"mcp_resource_subscriptions",
+		"Resource subscriptions currently held by MCP sessions.")
+)
+
+// unknownToolLabel labels calls of tools that no server registered, so that
+// clients cannot grow the metric series with arbitrary names.
+const unknownToolLabel = "unknown"
+
+// toolLabels holds the names of registered tools, the only tool labels recorded.
+var toolLabels sync.Map
+
+// registerToolLabel allows name as a tool label.
+func registerToolLabel(name string) {
+	toolLabels.Store(name, struct{}{})
+}
+
+// toolLabel returns name if a tool of that name is registered, and
+// unknownToolLabel otherwise.
+func toolLabel(name string) string {
+	if _, ok := toolLabels.Load(name); ok {
+		return name
+	}
+	return unknownToolLabel
+}
+
+// metricsMiddleware records tool call metrics and tracks open sessions.
+func metricsMiddleware(next mcpsdk.MethodHandler) mcpsdk.MethodHandler {
+	return func(ctx context.Context, method string, req mcpsdk.Request) (mcpsdk.Result, error) {
+		switch r := req.(type) {
+		case *mcpsdk.CallToolRequest:
+			if r.Params == nil {
+				break
+			}
+			tool := toolLabel(r.Params.Name)
+			start := time.Now()
+			result, err := next(ctx, method, req)
+			toolCalls.Inc(tool)
+			toolDuration.Observe(time.Since(start).Seconds(), tool)
+			if toolResult, ok := result.(*mcpsdk.CallToolResult); err != nil || (ok && toolResult.IsError) {
+				toolErrors.Inc(tool)
+			}
+			return result, err
+		case *mcpsdk.InitializedRequest:
+			result, err := next(ctx, method, req)
+			if err == nil && r.Session != nil {
+				activeSessions.Inc()
+				go func() {
+					_ = r.Session.Wait()
+					activeSessions.Dec()
+				}()
+			}
+			return result, err
+		}
+		return next(ctx, method, req)
+	}
+}
@@ -0,0 +1,94 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"kapua-mcp-server/internal/kapua/config"
+	"kapua-mcp-server/pkg/metrics"
+	"kapua-mcp-server/pkg/utils"
+)
+
+type emptyToolInput struct{}
+
+func TestMetricsMiddleware(t *testing.T) {
+	server := mcpsdk.NewServer(&mcpsdk.Implementation{Name: "test", Version: "dev"}, nil)
+	server.AddReceivingMiddleware(metricsMiddleware)
+	addTool(server, false, &mcpsdk.Tool{Name: "metrics-ok"}, func(context.Context, *mcpsdk.CallToolRequest, emptyToolInput) (*mcpsdk.CallToolResult, any, error) {
+		return &mcpsdk.CallToolResult{Content: []mcpsdk.Content{&mcpsdk.TextContent{Text: "ok"}}}, nil, nil
+	})
+	addTool(server, false, &mcpsdk.Tool{Name: "metrics-fail"}, func(context.Context, *mcpsdk.CallToolRequest, emptyToolInput) (*mcpsdk.CallToolResult, any, error) {
+		return nil, nil, errors.New("boom")
+	})
+
+	sessionsBefore := activeSessions.Value()
+	serverTransport, clientTransport := mcpsdk.NewInMemoryTransports()
+	serverSession, err := server.Connect(context.Background(), serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server connect failed: %v", err)
+	}
+	client, err := mcpsdk.NewClient(&mcpsdk.Implementation{Name: "client", Version: "dev"}, nil).Connect(context.Background(), clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect failed: %v", err)
+	}
+	waitForGauge(t, activeSessions, sessionsBefore+1)
+
+	unknownBefore := toolCalls.Value(unknownToolLabel)
+	for _, name := range []string{"metrics-ok", "metrics-ok", "metrics-fail", "metrics-no-such-tool"} {
+		_, _ = client.CallTool(context.Background(), &mcpsdk.CallToolParams{Name: name, Arguments: map[string]any{}})
+	}
+	if toolCalls.Value("metrics-ok") != 2 || toolErrors.Value("metrics-ok") != 0 {
+		t.Fatalf("unexpected metrics-ok counters: calls %v errors %v", toolCalls.Value("metrics-ok"), toolErrors.Value("metrics-ok"))
+	}
+	if toolCalls.Value("metrics-fail") != 1 || toolErrors.Value("metrics-fail") != 1 {
+		t.Fatalf("unexpected metrics-fail counters: calls %v errors %v", toolCalls.Value("metrics-fail"), toolErrors.Value("metrics-fail"))
+	}
+	if toolCalls.Value("metrics-no-such-tool") != 0 || toolCalls.Value(unknownToolLabel) != unknownBefore+1 {
+		t.Fatalf("expected the unregistered tool to be counted as %q", unknownToolLabel)
+	}
+	if toolDuration.Count("metrics-ok") != 2 {
+		t.Fatalf("expected two latency observations, got %d", toolDuration.Count("metrics-ok"))
+	}
+
+	_ = client.Close()
+	_ = serverSession.Wait()
+	waitForGauge(t, activeSessions, sessionsBefore)
+}
+
+func waitForGauge(t *testing.T, gauge *metrics.Gauge, want float64) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for gauge.Value() != want && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := gauge.Value(); got != want {
+		t.Fatalf("expected gauge value %v, got %v", want, got)
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	srv := &Server{
+		logger:    utils.NewDefaultLogger("test"),
+		kapuaCfg:  &config.Config{Kapua: config.KapuaConfig{APIEndpoint: "https://example"}},
+		mcpServer: mcpsdk.NewServer(&mcpsdk.Implementation{Name: "test", Version: "dev"}, nil),
+	}
+	handler := srv.Handler(&HTTPConfig{})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	for _, family := range []string{"mcp_active_sessions", "mcp_tool_calls_total", "kapua_request_duration_seconds", "kapua_token_refreshes_total", "kapua_fleet_health_scan_duration_seconds"} {
+		if !strings.Contains(rec.Body.String(), "# TYPE "+family+" ") {
+			t.Errorf("expected %s in /metrics output", family)
+		}
+	}
+}
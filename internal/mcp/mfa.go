@@ -0,0 +1,113 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"kapua-mcp-server/internal/kapua/services"
+	"kapua-mcp-server/pkg/utils"
+)
+
+// maxMFAAttempts bounds how many codes a single session is asked for.
+const maxMFAAttempts = 3
+
+// errMFAPending is returned by Kapua calls made before the MFA code has been supplied.
+var errMFAPending = errors.New("kapua login is waiting for a multi-factor authentication code; accept the elicitation request in your MCP client")
+
+// mfaElicitor completes a pending password login by asking the first MCP client that
+// supports elicitation for the MFA code.
+type mfaElicitor struct {
+	client *services.KapuaClient
+	logger *utils.Logger
+
+	mu       sync.Mutex
+	running  bool
+	finished bool
+}
+
+func newMFAElicitor(client *services.KapuaClient, logger *utils.Logger) *mfaElicitor {
+	client.SetAuthenticationPending(errMFAPending)
+	return &mfaElicitor{client: client, logger: logger}
+}
+
+// onInitialized is installed as the server InitializedHandler. Elicitation has to
+// happen outside the handler because the client only answers once initialization
+// has returned.
+func (e *mfaElicitor) onInitialized(ctx context.Context, req *mcpsdk.InitializedRequest) {
+	session := req.Session
+	params := session.InitializeParams()
+	if params == nil || params.Capabilities == nil || params.Capabilities.Elicitation == nil {
+		e.logger.Warn("MCP client does not support elicitation; Kapua MFA code cannot be requested")
+		return
+	}
+
+	e.mu.Lock()
+	if e.running || e.finished {
+		e.mu.Unlock()
+		return
+	}
+	e.running = true
+	e.mu.Unlock()
+
+	go func() {
+		done := e.elicit(context.WithoutCancel(ctx), session)
+		e.mu.Lock()
+		e.running = false
+		e.finished = done
+		e.mu.Unlock()
+	}()
+}
+
+// elicit asks session for MFA codes until Kapua accepts one, the user declines, or
+// maxMFAAttempts is reached. It reports whether authentication succeeded.
+func (e *mfaElicitor) elicit(ctx context.Context, session *mcpsdk.ServerSession) bool {
+	message := "Kapua requires a multi-factor authentication code. Enter the 6-digit code from your authenticator app."
+	for attempt := 1; attempt <= maxMFAAttempts; attempt++ {
+		result, err := session.Elicit(ctx, &mcpsdk.ElicitParams{
+			Message:         message,
+			RequestedSchema: mfaCodeSchema(),
+		})
+		if err != nil {
+			e.logger.Warn("MFA elicitation failed: %v", err)
+			return false
+		}
+		if result.Action != "accept" {
+			e.logger.Warn("MFA elicitation %s by the user; Kapua tools stay unavailable", result.Action)
+			return false
+		}
+
+		code, _ := result.Content["code"].(string)
+		code = strings.TrimSpace(code)
+		if _, err := e.client.AuthenticateWithMFACode(ctx, code); err != nil {
+			e.logger.Warn("MFA attempt %d/%d failed: %v", attempt, maxMFAAttempts, err)
+			if !errors.Is(err, services.ErrMFACodeRejected) && !errors.Is(err, services.ErrMFARequired) {
+				return false
+			}
+			message = fmt.Sprintf("Kapua rejected the code (attempt %d of %d). Enter a new multi-factor authentication code.", attempt, maxMFAAttempts)
+			continue
+		}
+
+		e.logger.Info("Successfully authenticated to Kapua with MFA code")
+		return true
+	}
+	return false
+}
+
+func mfaCodeSchema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"code": map[string]any{
+				"type":        "string",
+				"title":       "MFA code",
+				"description": "Time-based one-time password for the Kapua account",
+			},
+		},
+		"required": []string{"code"},
+	}
+}
@@ -0,0 +1,86 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"kapua-mcp-server/internal/kapua/config"
+	"kapua-mcp-server/internal/kapua/models"
+	"kapua-mcp-server/internal/kapua/services"
+)
+
+func TestNewServerElicitsMFACode(t *testing.T) {
+	var authenticated atomic.Bool
+	kapuaClientFactory = func(cfg *config.KapuaConfig) *services.KapuaClient {
+		client := services.NewKapuaClient(cfg)
+		client.SetHTTPClient(&http.Client{
+			Transport: handlerRoundTripper{handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v1/authentication/user" {
+					t.Errorf("unexpected path %s", r.URL.Path)
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				var creds models.UsernamePasswordCredentials
+				_ = json.NewDecoder(r.Body).Decode(&creds)
+				switch creds.AuthenticationCode {
+				case "":
+					w.WriteHeader(http.StatusUnauthorized)
+					_, _ = io.WriteString(w, `{"kapuaErrorCode":"REQUIRE_MFA_CREDENTIALS","message":"MFA required"}`)
+				case "654321":
+					authenticated.Store(true)
+					_, _ = io.WriteString(w, `{"tokenId":"token","refreshToken":"refresh","expiresOn":"2030-01-02T15:04:05Z","refreshExpiresOn":"2030-01-03T15:04:05Z","scopeId":"tenant"}`)
+				default:
+					w.WriteHeader(http.StatusUnauthorized)
+					_, _ = io.WriteString(w, `{"kapuaErrorCode":"INVALID_MFA_CODE","message":"wrong code"}`)
+				}
+			})},
+		})
+		return client
+	}
+	defer func() { kapuaClientFactory = services.NewKapuaClient }()
+
+	cfg := &config.Config{Kapua: config.KapuaConfig{APIEndpoint: "http://kapua.test", Timeout: 5, Username: "user", Password: "pass", AuthMethod: "password", MFAElicit: true}}
+
+	srv, err := NewServer(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("NewServer returned error: %v", err)
+	}
+
+	codes := []string{"000000", "654321"}
+	var asked atomic.Int32
+	client := mcpsdk.NewClient(&mcpsdk.Implementation{Name: "test-client", Version: "1.0.0"}, &mcpsdk.ClientOptions{
+		ElicitationHandler: func(ctx context.Context, req *mcpsdk.ElicitRequest) (*mcpsdk.ElicitResult, error) {
+			i := asked.Add(1) - 1
+			return &mcpsdk.ElicitResult{Action: "accept", Content: map[string]any{"code": codes[i]}}, nil
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	serverTransport, clientTransport := mcpsdk.NewInMemoryTransports()
+	go func() { _ = srv.RunTransport(ctx, "in-memory", serverTransport) }()
+
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect failed: %v", err)
+	}
+	defer session.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !authenticated.Load() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for MFA elicitation to authenticate")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := asked.Load(); got != 2 {
+		t.Fatalf("expected 2 elicitation requests, got %d", got)
+	}
+}
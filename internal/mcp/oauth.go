@@ -0,0 +1,248 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/auth"
+	"github.com/modelcontextprotocol/go-sdk/oauthex"
+
+	"kapua-mcp-server/pkg/utils"
+)
+
+// protectedResourceMetadataPath is the RFC 9728 well-known path.
+const protectedResourceMetadataPath = "/.well-known/oauth-protected-resource"
+
+// jwtClockSkew is the leeway applied to exp and nbf checks.
+const jwtClockSkew = time.Minute
+
+// OAuthConfig enables OAuth 2.1 bearer token authorization of the HTTP transport,
+// following the MCP authorization specification.
+type OAuthConfig struct {
+	Issuer   string   // Expected "iss" claim and advertised authorization server
+	Audience string   // Expected "aud" claim; defaults to Resource
+	Resource string   // Canonical URL of this MCP server
+	JWKSFile string   // JWK set file, re-read when it changes
+	JWKS     string   // Inline JWK set, mainly for testing
+	Scopes   []string // Scopes every token must carry
+
+	keys *jwksSource
+}
+
+// loadOAuthConfig reads MCP_OAUTH_* variables. It returns nil when MCP_OAUTH_ISSUER
+// is unset, leaving the transport unauthenticated.
+func loadOAuthConfig() (*OAuthConfig, error) {
+	issuer := strings.TrimSpace(os.Getenv("MCP_OAUTH_ISSUER"))
+	if issuer == "" {
+		return nil, nil
+	}
+
+	cfg := &OAuthConfig{
+		Issuer:   issuer,
+		Audience: strings.TrimSpace(os.Getenv("MCP_OAUTH_AUDIENCE")),
+		Resource: strings.TrimSpace(os.Getenv("MCP_OAUTH_RESOURCE")),
+		JWKSFile: strings.TrimSpace(os.Getenv("MCP_OAUTH_JWKS_FILE")),
+		JWKS:     strings.TrimSpace(os.Getenv("MCP_OAUTH_JWKS")),
+		Scopes:   strings.Fields(strings.ReplaceAll(os.Getenv("MCP_OAUTH_SCOPES"), ",", " ")),
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	keys, err := cfg.loadKeys()
+	if err != nil {
+		return nil, err
+	}
+	cfg.keys = keys
+	return cfg, nil
+}
+
+func (cfg *OAuthConfig) loadKeys() (*jwksSource, error) {
+	if cfg.keys != nil {
+		return cfg.keys, nil
+	}
+	if cfg.JWKSFile != "" {
+		return newFileJWKS(cfg.JWKSFile)
+	}
+	return newStaticJWKS(cfg.JWKS)
+}
+
+func (cfg *OAuthConfig) validate() error {
+	if cfg.Resource == "" {
+		return fmt.Errorf("MCP_OAUTH_RESOURCE is required when MCP_OAUTH_ISSUER is set")
+	}
+	resourceURL, err := url.Parse(cfg.Resource)
+	if err != nil || resourceURL.Scheme == "" || resourceURL.Host == "" || resourceURL.Fragment != "" {
+		return fmt.Errorf("invalid MCP_OAUTH_RESOURCE %q: must be an absolute URL without fragment", cfg.Resource)
+	}
+	switch {
+	case cfg.JWKSFile == "" && cfg.JWKS == "":
+		return fmt.Errorf("MCP_OAUTH_JWKS_FILE or MCP_OAUTH_JWKS is required when MCP_OAUTH_ISSUER is set")
+	case cfg.JWKSFile != "" && cfg.JWKS != "":
+		return fmt.Errorf("MCP_OAUTH_JWKS_FILE and MCP_OAUTH_JWKS are mutually exclusive")
+	}
+	return nil
+}
+
+func (cfg *OAuthConfig) audience() string {
+	if cfg.Audience != "" {
+		return cfg.Audience
+	}
+	return cfg.Resource
+}
+
+// metadataPath returns the well-known path for the configured resource: the base
+// path with the resource path appended, as RFC 9728 section 3.1 requires.
+func (cfg *OAuthConfig) metadataPath() string {
+	resourceURL, err := url.Parse(cfg.Resource)
+	if err != nil {
+		return protectedResourceMetadataPath
+	}
+	return protectedResourceMetadataPath + strings.TrimSuffix(resourceURL.EscapedPath(), "/")
+}
+
+// metadataURL returns the absolute protected resource metadata URL advertised in challenges.
+func (cfg *OAuthConfig) metadataURL() string {
+	resourceURL, err := url.Parse(cfg.Resource)
+	if err != nil {
+		return ""
+	}
+	return resourceURL.Scheme + "://" + resourceURL.Host + cfg.metadataPath()
+}
+
+// oauthGuard validates bearer tokens and serves the protected resource metadata.
+type oauthGuard struct {
+	cfg    *OAuthConfig
+	keys   *jwksSource
+	logger *utils.Logger
+	now    func() time.Time
+}
+
+func newOAuthGuard(cfg *OAuthConfig, logger *utils.Logger) (*oauthGuard, error) {
+	keys, err := cfg.loadKeys()
+	if err != nil {
+		return nil, err
+	}
+	return &oauthGuard{cfg: cfg, keys: keys, logger: logger, now: time.Now}, nil
+}
+
+// verifyToken implements auth.TokenVerifier for signed JWT access tokens.
+func (g *oauthGuard) verifyToken(_ context.Context, token string, _ *http.Request) (*auth.TokenInfo, error) {
+	keys, err := g.keys.current()
+	if err != nil {
+		return nil, err
+	}
+	claims, err := parseAndVerifyJWT(token, keys)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", auth.ErrInvalidToken, err)
+	}
+
+	now := g.now()
+	switch {
+	case claims.Issuer != g.cfg.Issuer:
+		return nil, fmt.Errorf("%w: unexpected issuer %q", auth.ErrInvalidToken, claims.Issuer)
+	case !slices.Contains(claims.Audience, g.cfg.audience()):
+		return nil, fmt.Errorf("%w: token audience does not include %s", auth.ErrInvalidToken, g.cfg.audience())
+	case claims.ExpiresAt == nil:
+		return nil, fmt.Errorf("%w: token has no expiration", auth.ErrInvalidToken)
+	case now.After(claims.ExpiresAt.Add(jwtClockSkew)):
+		return nil, fmt.Errorf("%w: token expired", auth.ErrInvalidToken)
+	case claims.NotBefore != nil && now.Add(jwtClockSkew).Before(claims.NotBefore.Time):
+		return nil, fmt.Errorf("%w: token not valid yet", auth.ErrInvalidToken)
+	}
+
+	extra := map[string]any{"sub": claims.Subject, "iss": claims.Issuer}
+	if clientID := claims.ClientID; clientID != "" {
+		extra["client_id"] = clientID
+	} else if claims.AZP != "" {
+		extra["client_id"] = claims.AZP
+	}
+	return &auth.TokenInfo{
+		Scopes:     claims.scopes(),
+		Expiration: claims.ExpiresAt.Add(jwtClockSkew),
+		Extra:      extra,
+	}, nil
+}
+
+// middleware rejects requests without a valid bearer token. Failures carry an RFC 6750
+// WWW-Authenticate challenge pointing to the protected resource metadata; valid tokens
+// are handed to the SDK so tool handlers see them in CallToolRequest.Extra.TokenInfo.
+func (g *oauthGuard) middleware(next http.Handler) http.Handler {
+	type verifiedKey struct{}
+	sdkAuth := auth.RequireBearerToken(func(ctx context.Context, _ string, _ *http.Request) (*auth.TokenInfo, error) {
+		if info, ok := ctx.Value(verifiedKey{}).(*auth.TokenInfo); ok {
+			return info, nil
+		}
+		return nil, auth.ErrInvalidToken
+	}, nil)(next)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+		token = strings.TrimSpace(token)
+		if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			g.challenge(w, http.StatusUnauthorized, "", "authorization required")
+			return
+		}
+
+		info, err := g.verifyToken(r.Context(), token, r)
+		if err != nil {
+			g.logger.Warn("Rejected bearer token: %v", err)
+			if errors.Is(err, auth.ErrInvalidToken) {
+				g.challenge(w, http.StatusUnauthorized, "invalid_token", strings.TrimPrefix(err.Error(), auth.ErrInvalidToken.Error()+": "))
+			} else {
+				http.Error(w, "unable to verify token", http.StatusInternalServerError)
+			}
+			return
+		}
+		for _, scope := range g.cfg.Scopes {
+			if !slices.Contains(info.Scopes, scope) {
+				g.challenge(w, http.StatusForbidden, "insufficient_scope", "token lacks required scope "+scope)
+				return
+			}
+		}
+
+		sdkAuth.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), verifiedKey{}, info)))
+	})
+}
+
+// challenge writes an error response with a Bearer WWW-Authenticate header.
+func (g *oauthGuard) challenge(w http.ResponseWriter, status int, code, description string) {
+	params := []string{fmt.Sprintf("resource_metadata=%q", g.cfg.metadataURL())}
+	if code != "" {
+		params = append(params, fmt.Sprintf("error=%q", code), fmt.Sprintf("error_description=%q", description))
+	}
+	if len(g.cfg.Scopes) > 0 {
+		params = append(params, fmt.Sprintf("scope=%q", strings.Join(g.cfg.Scopes, " ")))
+	}
+	w.Header().Set("WWW-Authenticate", "Bearer "+strings.Join(params, ", "))
+	http.Error(w, description, status)
+}
+
+// metadataHandler serves the RFC 9728 protected resource metadata document.
+func (g *oauthGuard) metadataHandler() http.Handler {
+	metadata := oauthex.ProtectedResourceMetadata{
+		Resource:               g.cfg.Resource,
+		AuthorizationServers:   []string{g.cfg.Issuer},
+		ScopesSupported:        g.cfg.Scopes,
+		BearerMethodsSupported: []string{"header"},
+		ResourceName:           "Kapua MCP Server",
+	}
+	body, _ := json.Marshal(metadata)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		_, _ = w.Write(body)
+	})
+}
@@ -0,0 +1,270 @@
+package mcp
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jsonWebKey is the subset of RFC 7517 fields needed for RSA and EC signature keys.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// verificationKey is a parsed public key from a JWK set.
+type verificationKey struct {
+	kid string
+	alg string
+	key crypto.PublicKey
+}
+
+// parseJWKS decodes a JWK set, skipping keys that are not usable for signatures.
+func parseJWKS(data []byte) ([]verificationKey, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+
+	keys := make([]verificationKey, 0, len(set.Keys))
+	for i, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWKS key %d (kid %q): %w", i, jwk.Kid, err)
+		}
+		if slices.Contains(jwtAlgorithms, jwk.Alg) && !keyMatchesAlgorithm(key, jwk.Alg) {
+			return nil, fmt.Errorf("invalid JWKS key %d (kid %q): algorithm %s does not match the key", i, jwk.Kid, jwk.Alg)
+		}
+		keys = append(keys, verificationKey{kid: jwk.Kid, alg: jwk.Alg, key: key})
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS contains no signature keys")
+	}
+	return keys, nil
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("modulus: %w", err)
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("exponent: %w", err)
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("unsupported exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("x coordinate: %w", err)
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("y coordinate: %w", err)
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("point is not on curve %s", k.Crv)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, fmt.Errorf("empty value")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// jwksSource provides verification keys from inline JSON or from a file that is
+// re-read whenever it changes, so keys can be rotated without a restart.
+type jwksSource struct {
+	file string
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	keys    []verificationKey
+}
+
+func newStaticJWKS(data string) (*jwksSource, error) {
+	keys, err := parseJWKS([]byte(data))
+	if err != nil {
+		return nil, err
+	}
+	return &jwksSource{keys: keys}, nil
+}
+
+func newFileJWKS(path string) (*jwksSource, error) {
+	source := &jwksSource{file: path}
+	if _, err := source.current(); err != nil {
+		return nil, err
+	}
+	return source, nil
+}
+
+// current returns the active key set, reloading the JWKS file if it changed.
+// A file that becomes unreadable or invalid keeps the last good keys.
+func (s *jwksSource) current() ([]verificationKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == "" {
+		return s.keys, nil
+	}
+
+	info, err := os.Stat(s.file)
+	if err != nil {
+		if s.keys != nil {
+			return s.keys, nil
+		}
+		return nil, fmt.Errorf("failed to stat JWKS file: %w", err)
+	}
+	if s.keys != nil && info.ModTime().Equal(s.modTime) && info.Size() == s.size {
+		return s.keys, nil
+	}
+
+	data, err := os.ReadFile(s.file)
+	if err == nil {
+		var keys []verificationKey
+		if keys, err = parseJWKS(data); err == nil {
+			s.keys, s.modTime, s.size = keys, info.ModTime(), info.Size()
+			return s.keys, nil
+		}
+	}
+	if s.keys != nil {
+		return s.keys, nil
+	}
+	return nil, fmt.Errorf("failed to load JWKS file: %w", err)
+}
+
+// jwtClaims holds the registered claims checked by the verifier plus scope claims.
+type jwtClaims struct {
+	jwt.RegisteredClaims
+	Scope    string   `json:"scope"`
+	Scp      []string `json:"scp"`
+	ClientID string   `json:"client_id"`
+	AZP      string   `json:"azp"`
+}
+
+// scopes returns the granted scopes from "scope" (space separated) or "scp".
+func (c jwtClaims) scopes() []string {
+	if c.Scope != "" {
+		return strings.Fields(c.Scope)
+	}
+	return c.Scp
+}
+
+// jwtAlgorithms are the accepted signing algorithms: asymmetric RS* and ES* only.
+var jwtAlgorithms = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}
+
+// jwtCurves pairs each ES* algorithm with the only curve it may be used with
+// (RFC 7518 §3.4).
+var jwtCurves = map[string]elliptic.Curve{
+	"ES256": elliptic.P256(),
+	"ES384": elliptic.P384(),
+	"ES512": elliptic.P521(),
+}
+
+// jwtParser verifies signatures only. Registered claims are checked by the caller,
+// which owns the clock and the expected issuer and audience.
+var jwtParser = jwt.NewParser(
+	jwt.WithValidMethods(jwtAlgorithms),
+	jwt.WithoutClaimsValidation(),
+)
+
+// parseAndVerifyJWT checks the compact JWS signature of token against keys and
+// returns its claims.
+func parseAndVerifyJWT(token string, keys []verificationKey) (*jwtClaims, error) {
+	var claims jwtClaims
+	if _, err := jwtParser.ParseWithClaims(token, &claims, func(t *jwt.Token) (any, error) {
+		return matchingKeys(t, keys)
+	}); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+// matchingKeys returns the keys that may verify t: those with its kid, if any, whose
+// JWK alg (when set) agrees with the header and whose type and curve suit it.
+func matchingKeys(t *jwt.Token, keys []verificationKey) (jwt.VerificationKeySet, error) {
+	alg := t.Method.Alg()
+	kid, _ := t.Header["kid"].(string)
+
+	var set jwt.VerificationKeySet
+	for _, key := range keys {
+		if kid != "" && key.kid != kid {
+			continue
+		}
+		if key.alg != "" && key.alg != alg {
+			continue
+		}
+		if !keyMatchesAlgorithm(key.key, alg) {
+			continue
+		}
+		set.Keys = append(set.Keys, key.key)
+	}
+	if len(set.Keys) == 0 {
+		return set, fmt.Errorf("no key for kid %q and algorithm %s", kid, alg)
+	}
+	return set, nil
+}
+
+// keyMatchesAlgorithm reports whether key may verify alg: RS* needs an RSA key and
+// ES* an EC key on the curve paired with the algorithm.
+func keyMatchesAlgorithm(key crypto.PublicKey, alg string) bool {
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		return strings.HasPrefix(alg, "RS")
+	case *ecdsa.PublicKey:
+		return jwtCurves[alg] == pub.Curve
+	default:
+		return false
+	}
+}
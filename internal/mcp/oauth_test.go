@@ -0,0 +1,371 @@
+package mcp
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/auth"
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"kapua-mcp-server/internal/kapua/config"
+	"kapua-mcp-server/pkg/utils"
+)
+
+const (
+	testIssuer   = "https://idp.example.com"
+	testResource = "https://mcp.example.com/kapua"
+)
+
+type testSigner struct {
+	kid    string
+	alg    string // Header alg; defaults to RS256 or ES256
+	jwkAlg string // Optional JWK "alg" member
+	rsa    *rsa.PrivateKey
+	ec     *ecdsa.PrivateKey
+}
+
+func newRSASigner(t *testing.T, kid string) testSigner {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	return testSigner{kid: kid, rsa: key}
+}
+
+func newECSigner(t *testing.T, kid string) testSigner {
+	t.Helper()
+	return newECSignerOnCurve(t, kid, elliptic.P256())
+}
+
+func newECSignerOnCurve(t *testing.T, kid string, curve elliptic.Curve) testSigner {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate EC key: %v", err)
+	}
+	return testSigner{kid: kid, ec: key}
+}
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+func (s testSigner) jwk() map[string]string {
+	var jwk map[string]string
+	if s.rsa != nil {
+		jwk = map[string]string{"kty": "RSA", "kid": s.kid, "use": "sig", "n": b64(s.rsa.N.Bytes()), "e": b64(big.NewInt(int64(s.rsa.E)).Bytes())}
+	} else {
+		params := s.ec.Curve.Params()
+		size := (params.BitSize + 7) / 8
+		jwk = map[string]string{"kty": "EC", "kid": s.kid, "crv": params.Name, "x": b64(s.ec.X.FillBytes(make([]byte, size))), "y": b64(s.ec.Y.FillBytes(make([]byte, size)))}
+	}
+	if s.jwkAlg != "" {
+		jwk["alg"] = s.jwkAlg
+	}
+	return jwk
+}
+
+func jwksJSON(t *testing.T, signers ...testSigner) string {
+	t.Helper()
+	keys := make([]map[string]string, 0, len(signers))
+	for _, s := range signers {
+		keys = append(keys, s.jwk())
+	}
+	data, err := json.Marshal(map[string]any{"keys": keys})
+	if err != nil {
+		t.Fatalf("failed to marshal JWKS: %v", err)
+	}
+	return string(data)
+}
+
+func (s testSigner) sign(t *testing.T, claims map[string]any) string {
+	t.Helper()
+	alg := s.alg
+	if alg == "" && s.rsa != nil {
+		alg = "RS256"
+	} else if alg == "" {
+		alg = "ES256"
+	}
+	hash := map[string]crypto.Hash{"256": crypto.SHA256, "384": crypto.SHA384, "512": crypto.SHA512}[alg[2:]]
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": s.kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signingInput := b64(header) + "." + b64(payload)
+	hasher := hash.New()
+	hasher.Write([]byte(signingInput))
+	digest := hasher.Sum(nil)
+
+	var sig []byte
+	var err error
+	if s.rsa != nil {
+		sig, err = rsa.SignPKCS1v15(rand.Reader, s.rsa, hash, digest)
+	} else {
+		var r, ss *big.Int
+		r, ss, err = ecdsa.Sign(rand.Reader, s.ec, digest)
+		size := (s.ec.Curve.Params().BitSize + 7) / 8
+		sig = append(r.FillBytes(make([]byte, size)), ss.FillBytes(make([]byte, size))...)
+	}
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return signingInput + "." + b64(sig)
+}
+
+func validClaims() map[string]any {
+	return map[string]any{
+		"iss":   testIssuer,
+		"sub":   "alice",
+		"aud":   []string{testResource, "other"},
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"scope": "kapua:read kapua:write",
+	}
+}
+
+func newTestOAuthGuard(t *testing.T, scopes []string, signers ...testSigner) *oauthGuard {
+	t.Helper()
+	guard, err := newOAuthGuard(&OAuthConfig{Issuer: testIssuer, Resource: testResource, JWKS: jwksJSON(t, signers...), Scopes: scopes}, utils.NewDefaultLogger("test"))
+	if err != nil {
+		t.Fatalf("newOAuthGuard returned error: %v", err)
+	}
+	return guard
+}
+
+func TestOAuthVerifyToken(t *testing.T) {
+	rsaSigner := newRSASigner(t, "rsa-1")
+	ecSigner := newECSigner(t, "ec-1")
+	guard := newTestOAuthGuard(t, nil, rsaSigner, ecSigner)
+
+	for _, signer := range []testSigner{rsaSigner, ecSigner} {
+		info, err := guard.verifyToken(context.Background(), signer.sign(t, validClaims()), nil)
+		if err != nil {
+			t.Fatalf("%s token rejected: %v", signer.kid, err)
+		}
+		if strings.Join(info.Scopes, ",") != "kapua:read,kapua:write" || info.Extra["sub"] != "alice" {
+			t.Fatalf("unexpected token info: %+v", info)
+		}
+	}
+
+	mutate := func(key string, value any) map[string]any {
+		claims := validClaims()
+		if value == nil {
+			delete(claims, key)
+		} else {
+			claims[key] = value
+		}
+		return claims
+	}
+	other := newRSASigner(t, "rsa-1")
+	cases := map[string]string{
+		"wrong issuer":   rsaSigner.sign(t, mutate("iss", "https://evil.example.com")),
+		"wrong audience": rsaSigner.sign(t, mutate("aud", "https://other.example.com")),
+		"expired":        rsaSigner.sign(t, mutate("exp", time.Now().Add(-time.Hour).Unix())),
+		"no expiration":  rsaSigner.sign(t, mutate("exp", nil)),
+		"not yet valid":  rsaSigner.sign(t, mutate("nbf", time.Now().Add(time.Hour).Unix())),
+		"unknown key":    other.sign(t, validClaims()),
+		"malformed":      "not-a-jwt",
+		"alg none":       b64([]byte(`{"alg":"none"}`)) + "." + b64([]byte(`{}`)) + ".",
+	}
+	for name, token := range cases {
+		if _, err := guard.verifyToken(context.Background(), token, nil); err == nil || !strings.Contains(err.Error(), auth.ErrInvalidToken.Error()) {
+			t.Errorf("%s: expected invalid token error, got %v", name, err)
+		}
+	}
+}
+
+func TestOAuthRejectsAlgorithmKeyMismatch(t *testing.T) {
+	p256 := newECSignerOnCurve(t, "p256", elliptic.P256())
+	p384 := newECSignerOnCurve(t, "p384", elliptic.P384())
+	p521 := newECSignerOnCurve(t, "p521", elliptic.P521())
+	rsaSigner := newRSASigner(t, "rsa")
+	guard := newTestOAuthGuard(t, nil, p256, p384, p521, rsaSigner)
+
+	for _, signer := range []testSigner{{kid: "p384", alg: "ES384", ec: p384.ec}, {kid: "p521", alg: "ES512", ec: p521.ec}} {
+		if _, err := guard.verifyToken(context.Background(), signer.sign(t, validClaims()), nil); err != nil {
+			t.Fatalf("%s token rejected: %v", signer.alg, err)
+		}
+	}
+
+	cases := map[string]string{
+		"ES384 with P-256 key": testSigner{kid: "p256", alg: "ES384", ec: p256.ec}.sign(t, validClaims()),
+		"ES256 with P-384 key": testSigner{kid: "p384", alg: "ES256", ec: p384.ec}.sign(t, validClaims()),
+		"ES512 with P-384 key": testSigner{kid: "p384", alg: "ES512", ec: p384.ec}.sign(t, validClaims()),
+		"RS256 with EC key":    testSigner{kid: "p256", alg: "RS256", rsa: rsaSigner.rsa}.sign(t, validClaims()),
+	}
+	for name, token := range cases {
+		if _, err := guard.verifyToken(context.Background(), token, nil); err == nil || !strings.Contains(err.Error(), auth.ErrInvalidToken.Error()) {
+			t.Errorf("%s: expected invalid token error, got %v", name, err)
+		}
+	}
+
+	// A JWK whose alg disagrees with the token header is not used for it.
+	pinned := newECSignerOnCurve(t, "pinned", elliptic.P256())
+	pinned.jwkAlg = "ES256"
+	guard = newTestOAuthGuard(t, nil, pinned, rsaSigner)
+	token := testSigner{kid: "pinned", alg: "RS256", rsa: rsaSigner.rsa}.sign(t, validClaims())
+	if _, err := guard.verifyToken(context.Background(), token, nil); err == nil {
+		t.Error("expected token with an alg other than the JWK's to be rejected")
+	}
+
+	// A JWK declaring an alg that cannot use its key is refused when loading.
+	mismatched := newECSignerOnCurve(t, "bad", elliptic.P256())
+	mismatched.jwkAlg = "ES384"
+	if _, err := parseJWKS([]byte(jwksJSON(t, mismatched))); err == nil {
+		t.Error("expected JWKS with a P-256 key declared as ES384 to be rejected")
+	}
+}
+
+func TestOAuthMiddleware(t *testing.T) {
+	signer := newRSASigner(t, "rsa-1")
+	guard := newTestOAuthGuard(t, []string{"kapua:read"}, signer)
+
+	var seen *auth.TokenInfo
+	handler := guard.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = auth.TokenInfoFromContext(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	serve := func(authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	metadata := `resource_metadata="https://mcp.example.com/.well-known/oauth-protected-resource/kapua"`
+
+	rec := serve("")
+	if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Header().Get("WWW-Authenticate"), metadata) {
+		t.Fatalf("expected 401 challenge, got %d %q", rec.Code, rec.Header().Get("WWW-Authenticate"))
+	}
+
+	rec = serve("Bearer garbage")
+	if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Header().Get("WWW-Authenticate"), `error="invalid_token"`) {
+		t.Fatalf("expected invalid_token challenge, got %d %q", rec.Code, rec.Header().Get("WWW-Authenticate"))
+	}
+
+	claims := validClaims()
+	claims["scope"] = "kapua:write"
+	rec = serve("Bearer " + signer.sign(t, claims))
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Header().Get("WWW-Authenticate"), `error="insufficient_scope"`) {
+		t.Fatalf("expected insufficient_scope challenge, got %d %q", rec.Code, rec.Header().Get("WWW-Authenticate"))
+	}
+
+	rec = serve("Bearer " + signer.sign(t, validClaims()))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected valid token to pass, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if seen == nil || seen.Extra["sub"] != "alice" {
+		t.Fatalf("expected token info in request context, got %+v", seen)
+	}
+}
+
+func TestOAuthProtectedResourceMetadataEndpoint(t *testing.T) {
+	signer := newRSASigner(t, "rsa-1")
+	srv := &Server{
+		logger:    utils.NewDefaultLogger("test"),
+		kapuaCfg:  &config.Config{Kapua: config.KapuaConfig{APIEndpoint: "https://example"}},
+		mcpServer: mcpsdk.NewServer(&mcpsdk.Implementation{Name: "test", Version: "dev"}, nil),
+	}
+	handler := srv.Handler(&HTTPConfig{OAuth: &OAuthConfig{Issuer: testIssuer, Resource: testResource, JWKS: jwksJSON(t, signer), Scopes: []string{"kapua:read"}}})
+
+	for _, path := range []string{"/.well-known/oauth-protected-resource", "/.well-known/oauth-protected-resource/kapua"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", path, rec.Code)
+		}
+		var metadata map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &metadata); err != nil {
+			t.Fatalf("%s: invalid metadata: %v", path, err)
+		}
+		if metadata["resource"] != testResource || fmt.Sprint(metadata["authorization_servers"]) != "["+testIssuer+"]" {
+			t.Fatalf("%s: unexpected metadata %v", path, metadata)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`)))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected MCP endpoint to require a token, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected health endpoint to stay public, got %d", rec.Code)
+	}
+}
+
+func TestOAuthJWKSFileRotation(t *testing.T) {
+	first := newRSASigner(t, "first")
+	second := newECSigner(t, "second")
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(jwksFile, []byte(jwksJSON(t, first)), 0o600); err != nil {
+		t.Fatalf("failed to write JWKS: %v", err)
+	}
+
+	guard, err := newOAuthGuard(&OAuthConfig{Issuer: testIssuer, Resource: testResource, JWKSFile: jwksFile}, utils.NewDefaultLogger("test"))
+	if err != nil {
+		t.Fatalf("newOAuthGuard returned error: %v", err)
+	}
+	if _, err := guard.verifyToken(context.Background(), second.sign(t, validClaims()), nil); err == nil {
+		t.Fatal("expected token signed by unknown key to be rejected")
+	}
+
+	if err := os.WriteFile(jwksFile, []byte(jwksJSON(t, first, second)), 0o600); err != nil {
+		t.Fatalf("failed to rotate JWKS: %v", err)
+	}
+	future := time.Now().Add(time.Minute)
+	_ = os.Chtimes(jwksFile, future, future)
+
+	if _, err := guard.verifyToken(context.Background(), second.sign(t, validClaims()), nil); err != nil {
+		t.Fatalf("expected rotated key to be accepted, got %v", err)
+	}
+}
+
+func TestLoadOAuthConfig(t *testing.T) {
+	signer := newRSASigner(t, "rsa-1")
+	t.Setenv("MCP_OAUTH_ISSUER", "")
+	if cfg, err := loadOAuthConfig(); err != nil || cfg != nil {
+		t.Fatalf("expected OAuth disabled without issuer, got %+v (%v)", cfg, err)
+	}
+
+	t.Setenv("MCP_OAUTH_ISSUER", testIssuer)
+	t.Setenv("MCP_OAUTH_RESOURCE", "")
+	if _, err := loadOAuthConfig(); err == nil || !strings.Contains(err.Error(), "MCP_OAUTH_RESOURCE is required") {
+		t.Fatalf("expected missing resource error, got %v", err)
+	}
+
+	t.Setenv("MCP_OAUTH_RESOURCE", testResource)
+	if _, err := loadOAuthConfig(); err == nil || !strings.Contains(err.Error(), "MCP_OAUTH_JWKS_FILE or MCP_OAUTH_JWKS") {
+		t.Fatalf("expected missing JWKS error, got %v", err)
+	}
+
+	t.Setenv("MCP_OAUTH_JWKS", jwksJSON(t, signer))
+	t.Setenv("MCP_OAUTH_SCOPES", "kapua:read, kapua:write")
+	cfg, err := loadOAuthConfig()
+	if err != nil {
+		t.Fatalf("loadOAuthConfig returned error: %v", err)
+	}
+	if cfg.audience() != testResource || strings.Join(cfg.Scopes, " ") != "kapua:read kapua:write" {
+		t.Fatalf("unexpected OAuth config: %+v", cfg)
+	}
+}
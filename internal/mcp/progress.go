@@ -0,0 +1,77 @@
+package mcp
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"kapua-mcp-server/internal/kapua/handlers"
+)
+
+// progressInterval is the shortest time between two progress notifications for
+// one request. The notification that completes the total is always sent.
+const progressInterval = 250 * time.Millisecond
+
+// progressMiddleware sends the progress of bulk tool calls and resource reads to
+// clients that asked for it with a progressToken, as notifications/progress.
+func progressMiddleware(next mcpsdk.MethodHandler) mcpsdk.MethodHandler {
+	return func(ctx context.Context, method string, req mcpsdk.Request) (mcpsdk.Result, error) {
+		var token any
+		switch r := req.(type) {
+		case *mcpsdk.CallToolRequest:
+			if r.Params != nil {
+				token = r.Params.GetProgressToken()
+			}
+		case *mcpsdk.ReadResourceRequest:
+			if r.Params != nil {
+				token = r.Params.GetProgressToken()
+			}
+		}
+		session, ok := req.GetSession().(*mcpsdk.ServerSession)
+		if token == nil || !ok {
+			return next(ctx, method, req)
+		}
+
+		notifier := &progressNotifier{ctx: ctx, session: session, token: token}
+		return next(handlers.WithProgress(ctx, notifier.report), method, req)
+	}
+}
+
+// progressNotifier turns the progress of one request into notifications,
+// dropping those that would go backwards or come too soon after the last one.
+type progressNotifier struct {
+	ctx     context.Context
+	session *mcpsdk.ServerSession
+	token   any
+
+	mu   sync.Mutex
+	last int
+	sent time.Time
+}
+
+func (n *progressNotifier) report(done, total int, message string) {
+	if !n.claim(done, total) {
+		return
+	}
+	// Progress is best effort: a client that went away learns nothing from an error.
+	_ = n.session.NotifyProgress(n.ctx, &mcpsdk.ProgressNotificationParams{
+		ProgressToken: n.token,
+		Message:       message,
+		Progress:      float64(done),
+		Total:         float64(total),
+	})
+}
+
+// claim reports whether done of total should be sent, and records it as the
+// last notification if so.
+func (n *progressNotifier) claim(done, total int) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if !n.sent.IsZero() && (done <= n.last || done < total && time.Since(n.sent) < progressInterval) {
+		return false
+	}
+	n.last, n.sent = done, time.Now()
+	return true
+}
@@ -0,0 +1,79 @@
+package mcp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"kapua-mcp-server/internal/kapua/config"
+	"kapua-mcp-server/internal/kapua/models"
+	"kapua-mcp-server/internal/kapua/services"
+)
+
+func TestProgressMiddleware(t *testing.T) {
+	client := services.NewKapuaClient(&config.KapuaConfig{APIEndpoint: "http://kapua.test", Timeout: 5})
+	client.SetHTTPClient(&http.Client{Transport: handlerRoundTripper{handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/devices") {
+			_, _ = io.WriteString(w, `{"totalCount":3,"items":[{"id":"dev-1"},{"id":"dev-2"},{"id":"dev-3"}]}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"items":[]}`)
+	})}})
+	client.SetTokenInfo(&models.AccessToken{KapuaEntity: models.KapuaEntity{ScopeID: "tenant"}, TokenID: "token", ExpiresOn: time.Now().Add(time.Hour)})
+
+	server := newSDKServer(client, nil, false, nil, nil, nil, nil)
+	server.AddReceivingMiddleware(progressMiddleware)
+	serverTransport, clientTransport := mcpsdk.NewInMemoryTransports()
+	serverSession, err := server.Connect(context.Background(), serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server connect failed: %v", err)
+	}
+	defer serverSession.Close()
+
+	notifications := make(chan *mcpsdk.ProgressNotificationParams, 10)
+	session, err := mcpsdk.NewClient(&mcpsdk.Implementation{Name: "client", Version: "dev"}, &mcpsdk.ClientOptions{
+		ProgressNotificationHandler: func(_ context.Context, req *mcpsdk.ProgressNotificationClientRequest) {
+			notifications <- req.Params
+		},
+	}).Connect(context.Background(), clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect failed: %v", err)
+	}
+	defer session.Close()
+
+	// Without a progress token nothing is sent.
+	if _, err := session.ReadResource(context.Background(), &mcpsdk.ReadResourceParams{URI: "kapua://fleet-health"}); err != nil {
+		t.Fatalf("ReadResource returned error: %v", err)
+	}
+	params := &mcpsdk.ReadResourceParams{URI: "kapua://fleet-health?noCache=true", Meta: mcpsdk.Meta{"progressToken": "scan-1"}}
+	if _, err := session.ReadResource(context.Background(), params); err != nil {
+		t.Fatalf("ReadResource returned error: %v", err)
+	}
+
+	var got []*mcpsdk.ProgressNotificationParams
+	for len(got) == 0 || got[len(got)-1].Progress < 3 {
+		select {
+		case notification := <-notifications:
+			got = append(got, notification)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for the final notification, got %+v", got)
+		}
+	}
+	first, last := got[0], got[len(got)-1]
+	if first.ProgressToken != "scan-1" || first.Progress != 0 || first.Total != 3 {
+		t.Fatalf("unexpected first notification %+v", first)
+	}
+	if last.Total != 3 || last.Message != "Checked events of 3 of 3 devices" {
+		t.Fatalf("unexpected last notification %+v", last)
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i].Progress <= got[i-1].Progress {
+			t.Fatalf("expected increasing progress, got %+v", got)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"kapua-mcp-server/internal/kapua/services"
+)
+
+const (
+	// readyProbeTTL is how long a sys-info probe result is reused by /ready.
+	readyProbeTTL = 10 * time.Second
+	// readyProbeTimeout bounds a single sys-info probe.
+	readyProbeTimeout = 5 * time.Second
+	// readyMaxErrorRate is the share of failed Kapua requests above which the
+	// server is not ready, once readyMinRequests requests have been made.
+	readyMaxErrorRate = 0.5
+	readyMinRequests  = 10
+)
+
+// readyResponse is the body of GET /ready.
+type readyResponse struct {
+	Status string      `json:"status"` // "ready" or "not ready"
+	Checks readyChecks `json:"checks"`
+}
+
+type readyChecks struct {
+	Token     tokenCheck     `json:"token"`
+	Kapua     kapuaCheck     `json:"kapua"`
+	ErrorRate errorRateCheck `json:"errorRate"`
+}
+
+type tokenCheck struct {
+	OK bool `json:"ok"`
+	services.TokenStatus
+}
+
+type kapuaCheck struct {
+	OK          bool       `json:"ok"`
+	CheckedAt   *time.Time `json:"checkedAt,omitempty"`
+	Version     string     `json:"version,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+}
+
+type errorRateCheck struct {
+	OK       bool    `json:"ok"`
+	Rate     float64 `json:"rate"`
+	Requests int     `json:"requests"`
+	Failures int     `json:"failures"`
+	Window   string  `json:"window"`
+}
+
+// readinessProbe caches the result of the last sys-info probe so that frequent
+// readiness checks do not add load on Kapua. The zero value is ready to use.
+type readinessProbe struct {
+	mu        sync.Mutex
+	checkedAt time.Time
+	version   string
+	err       error
+}
+
+// check returns the cached sys-info result, probing Kapua again when it is older
+// than readyProbeTTL or when force is set. Concurrent callers share one probe.
+func (p *readinessProbe) check(ctx context.Context, client *services.KapuaClient, force bool) kapuaCheck {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if force || p.checkedAt.IsZero() || time.Since(p.checkedAt) >= readyProbeTTL {
+		// The probe outlives a caller that gives up, so its result can be cached.
+		probeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), readyProbeTimeout)
+		info, err := client.GetSystemInfo(probeCtx)
+		cancel()
+		p.checkedAt, p.version, p.err = time.Now(), "", err
+		if err == nil {
+			p.version = info.Version
+		}
+	}
+	checkedAt := p.checkedAt
+	result := kapuaCheck{OK: p.err == nil, CheckedAt: &checkedAt, Version: p.version}
+	if p.err != nil {
+		result.Error = p.err.Error()
+	}
+	return result
+}
+
+// serveReady reports readiness: the server holds a valid Kapua token, Kapua answers
+// GET /sys-info and recent Kapua requests mostly succeed. Unlike /health it returns
+// 503 when any check fails, so traffic is routed away while Kapua is unusable.
+func (s *Server) serveReady(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var ready readyResponse
+	if s.kapuaClient == nil {
+		ready.Checks.Kapua.Error = "no Kapua client configured"
+	} else {
+		// The probe refreshes an expiring token, so probe again rather than report
+		// a token that expired since the cached result.
+		tokenValid := s.kapuaClient.TokenStatus().Valid(time.Now())
+		ready.Checks.Kapua = s.ready.check(r.Context(), s.kapuaClient, !tokenValid)
+
+		token := s.kapuaClient.TokenStatus()
+		ready.Checks.Token = tokenCheck{OK: token.Valid(time.Now()), TokenStatus: token}
+
+		upstream := s.kapuaClient.UpstreamStatus()
+		ready.Checks.Kapua.LastSuccess = upstream.LastSuccess
+		ready.Checks.ErrorRate = errorRateCheck{
+			OK:       upstream.Requests < readyMinRequests || upstream.ErrorRate() <= readyMaxErrorRate,
+			Rate:     upstream.ErrorRate(),
+			Requests: upstream.Requests,
+			Failures: upstream.Failures,
+			Window:   upstream.Window.String(),
+		}
+	}
+
+	status := http.StatusOK
+	ready.Status = "ready"
+	if !ready.Checks.Token.OK || !ready.Checks.Kapua.OK || !ready.Checks.ErrorRate.OK {
+		status = http.StatusServiceUnavailable
+		ready.Status = "not ready"
+	}
+	body, _ := json.Marshal(ready)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
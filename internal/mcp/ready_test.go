@@ -0,0 +1,134 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"kapua-mcp-server/internal/kapua/config"
+	"kapua-mcp-server/internal/kapua/models"
+	"kapua-mcp-server/internal/kapua/services"
+	"kapua-mcp-server/pkg/utils"
+)
+
+// newReadyTestServer returns a server whose Kapua client answers sys-info with
+// sysInfoStatus and device lookups with deviceStatus.
+func newReadyTestServer(t *testing.T, sysInfoStatus, deviceStatus *atomic.Int32, probes *atomic.Int32) (*Server, *services.KapuaClient) {
+	t.Helper()
+	client := services.NewKapuaClient(&config.KapuaConfig{APIEndpoint: "http://kapua.test", Username: "user", Password: "pass", AuthMethod: "password"})
+	client.SetHTTPClient(&http.Client{Transport: handlerRoundTripper{handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/sys-info":
+			probes.Add(1)
+			w.WriteHeader(int(sysInfoStatus.Load()))
+			_, _ = io.WriteString(w, `{"version":"2.0.0","buildNumber":"142"}`)
+		case "/v1/authentication/user":
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = io.WriteString(w, `{"code":"INVALID_USERNAME_PASSWORD"}`)
+		default:
+			w.WriteHeader(int(deviceStatus.Load()))
+			_, _ = io.WriteString(w, `{"message":"boom"}`)
+		}
+	})}})
+	client.SetTokenInfo(&models.AccessToken{TokenID: "token", ExpiresOn: time.Now().Add(time.Hour)})
+	return &Server{
+		logger:      utils.NewDefaultLogger("test"),
+		kapuaCfg:    &config.Config{},
+		kapuaClient: client,
+		mcpServer:   mcpsdk.NewServer(&mcpsdk.Implementation{Name: "test", Version: "dev"}, nil),
+	}, client
+}
+
+func getReady(t *testing.T, handler http.Handler) (int, readyResponse) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	var body readyResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid body %q: %v", rec.Body.String(), err)
+	}
+	return rec.Code, body
+}
+
+func TestReadyEndpoint(t *testing.T) {
+	var sysInfo, devices, probes atomic.Int32
+	sysInfo.Store(http.StatusOK)
+	devices.Store(http.StatusOK)
+	srv, client := newReadyTestServer(t, &sysInfo, &devices, &probes)
+	handler := srv.Handler(&HTTPConfig{})
+
+	code, body := getReady(t, handler)
+	if code != http.StatusOK || body.Status != "ready" {
+		t.Fatalf("expected ready, got %d %+v", code, body)
+	}
+	if !body.Checks.Token.OK || body.Checks.Token.ExpiresAt == nil || body.Checks.Kapua.Version != "2.0.0" || body.Checks.Kapua.LastSuccess == nil {
+		t.Fatalf("unexpected checks: %+v", body.Checks)
+	}
+
+	sysInfo.Store(http.StatusServiceUnavailable)
+	if code, _ := getReady(t, handler); code != http.StatusOK || probes.Load() != 1 {
+		t.Fatalf("expected the cached probe to be reused, got %d after %d probes", code, probes.Load())
+	}
+
+	srv.ready.checkedAt = time.Now().Add(-readyProbeTTL)
+	code, body = getReady(t, handler)
+	if code != http.StatusServiceUnavailable || body.Status != "not ready" || body.Checks.Kapua.OK || body.Checks.Kapua.Error == "" {
+		t.Fatalf("expected sys-info failure, got %d %+v", code, body)
+	}
+
+	sysInfo.Store(http.StatusOK)
+	srv.ready.checkedAt = time.Time{}
+	devices.Store(http.StatusInternalServerError)
+	for range readyMinRequests * 2 {
+		_, _ = client.GetDevice(context.Background(), "dev-1")
+	}
+	code, body = getReady(t, handler)
+	if code != http.StatusServiceUnavailable || !body.Checks.Kapua.OK || body.Checks.ErrorRate.OK || body.Checks.ErrorRate.Failures < readyMinRequests*2 {
+		t.Fatalf("expected error rate failure, got %d %+v", code, body.Checks)
+	}
+}
+
+func TestReadyEndpointExpiredToken(t *testing.T) {
+	var sysInfo, devices, probes atomic.Int32
+	sysInfo.Store(http.StatusOK)
+	devices.Store(http.StatusOK)
+	srv, client := newReadyTestServer(t, &sysInfo, &devices, &probes)
+	handler := srv.Handler(&HTTPConfig{})
+
+	if code, _ := getReady(t, handler); code != http.StatusOK {
+		t.Fatalf("expected ready, got %d", code)
+	}
+
+	// The token expires and the re-login is refused: the cached probe must not hide it.
+	client.SetTokenInfo(&models.AccessToken{TokenID: "token", ExpiresOn: time.Now().Add(-time.Minute)})
+	code, body := getReady(t, handler)
+	if code != http.StatusServiceUnavailable || body.Checks.Token.OK || probes.Load() != 2 {
+		t.Fatalf("expected expired token to fail readiness after a fresh probe, got %d %+v (%d probes)", code, body.Checks.Token, probes.Load())
+	}
+}
+
+func TestReadyEndpointWithoutClient(t *testing.T) {
+	srv := &Server{
+		logger:    utils.NewDefaultLogger("test"),
+		kapuaCfg:  &config.Config{},
+		mcpServer: mcpsdk.NewServer(&mcpsdk.Implementation{Name: "test", Version: "dev"}, nil),
+	}
+	handler := srv.Handler(&HTTPConfig{})
+
+	if code, body := getReady(t, handler); code != http.StatusServiceUnavailable || body.Checks.Kapua.Error == "" {
+		t.Fatalf("expected not ready without a Kapua client, got %d %+v", code, body)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/ready", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", rec.Code)
+	}
+}
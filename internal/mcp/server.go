@@ -2,21 +2,38 @@ package mcp
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"strings"
+	"sync"
 
 	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
 
 	"kapua-mcp-server/internal/kapua/config"
 	"kapua-mcp-server/internal/kapua/handlers"
+	"kapua-mcp-server/internal/kapua/models"
 	"kapua-mcp-server/internal/kapua/services"
+	"kapua-mcp-server/pkg/metrics"
 	"kapua-mcp-server/pkg/utils"
 )
 
 type Server struct {
-	logger    *utils.Logger
-	kapuaCfg  *config.Config
-	mcpServer *mcpsdk.Server
+	logger      *utils.Logger
+	kapuaCfg    *config.Config
+	kapuaClient *services.KapuaClient
+	serverInfo  *models.ServerInfo
+	fleetIndex  *handlers.FleetIndex // Nil unless KAPUA_FLEET_POLL_INTERVAL is set
+	subscribed  *resourceSubscriptions
+	prompts     []*handlers.PromptTemplate
+	mcpServer   *mcpsdk.Server
+	requests    requestTracker
+	ready       readinessProbe
+
+	mu          sync.Mutex
+	sessions    *sessionManager
+	httpServers []*http.Server
 }
 
 var kapuaClientFactory = services.NewKapuaClient
@@ -32,64 +49,182 @@ func NewServer(ctx context.Context, kapuaCfg *config.Config) (*Server, error) {
 	logger := utils.NewDefaultLogger("MCPServer")
 	logger.Info("Starting Kapua MCP Server")
 
+	prompts, err := handlers.LoadPrompts(kapuaCfg.Kapua.PromptsDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load prompts: %w", err)
+	}
+
 	kapuaClient := kapuaClientFactory(&kapuaCfg.Kapua)
 
+	var serverOpts *mcpsdk.ServerOptions
 	logger.Info("Authenticating to Kapua on startup...")
-	if _, err := kapuaClient.QuickAuthenticate(ctx); err != nil {
+	_, err = kapuaClient.QuickAuthenticate(ctx)
+	switch {
+	case err == nil:
+		logger.Info("Successfully authenticated to Kapua")
+	case errors.Is(err, services.ErrMFARequired) && kapuaCfg.Kapua.MFAElicit:
+		logger.Warn("Kapua requires an MFA code; it will be requested from the first MCP client that connects")
+		elicitor := newMFAElicitor(kapuaClient, logger)
+		serverOpts = &mcpsdk.ServerOptions{InitializedHandler: elicitor.onInitialized}
+	default:
 		return nil, fmt.Errorf("failed to authenticate to Kapua on startup: %w", err)
 	}
-	logger.Info("Successfully authenticated to Kapua")
 
+	var serverInfo *models.ServerInfo
+	if serverOpts == nil {
+		if serverInfo, err = kapuaClient.DetectServerInfo(ctx); err != nil {
+			logger.Warn("Unable to query Kapua system info, all tools will be registered: %v", err)
+		}
+	}
+
+	var fleetIndex *handlers.FleetIndex
+	if interval := kapuaCfg.Kapua.FleetPollInterval; interval > 0 {
+		logger.Info("Indexing the fleet in the background every %v", interval)
+		fleetIndex = handlers.NewFleetIndex(kapuaClient, interval)
+		fleetIndex.Start()
+	}
+
+	subscribed := newResourceSubscriptions(kapuaClient, fleetIndex, kapuaCfg.Kapua.SubscriptionPollInterval)
+	srv := &Server{
+		logger:      logger,
+		kapuaCfg:    kapuaCfg,
+		kapuaClient: kapuaClient,
+		serverInfo:  serverInfo,
+		fleetIndex:  fleetIndex,
+		subscribed:  subscribed,
+		prompts:     prompts,
+		mcpServer:   newSDKServer(kapuaClient, serverInfo, kapuaCfg.Kapua.ReadOnly, fleetIndex, subscribed, prompts, serverOpts),
+	}
+	srv.mcpServer.AddReceivingMiddleware(srv.requests.middleware, metricsMiddleware, tracingMiddleware, logFieldsMiddleware, progressMiddleware)
+	return srv, nil
+}
+
+// newSDKServer builds an MCP server whose tools and resources act through kapuaClient,
+// leaving out the tools that change devices when readOnly is set.
+// A non-nil fleetIndex serves fleet-level reads and enables kapua-devices-search;
+// a non-nil subscribed enables resource subscriptions. prompts are served as is, and
+// prompt arguments and resource template parameters get completions.
+func newSDKServer(kapuaClient *services.KapuaClient, serverInfo *models.ServerInfo, readOnly bool, fleetIndex *handlers.FleetIndex, subscribed *resourceSubscriptions, prompts []*handlers.PromptTemplate, opts *mcpsdk.ServerOptions) *mcpsdk.Server {
 	kapuaHandler := handlers.NewKapuaHandler(kapuaClient)
+	if fleetIndex != nil {
+		kapuaHandler.SetFleetIndex(fleetIndex)
+	}
 
+	serverOpts := subscribed.options(opts)
+	serverOpts.CompletionHandler = func(ctx context.Context, req *mcpsdk.CompleteRequest) (*mcpsdk.CompleteResult, error) {
+		return kapuaHandler.Complete(ctx, req.Params)
+	}
 	sdkServer := mcpsdk.NewServer(&mcpsdk.Implementation{
 		Name:    "kapua-mcp-server",
 		Version: "1.0.0",
-	}, nil)
+	}, serverOpts)
+	subscribed.attach(sdkServer)
 
-	registerKapuaTools(sdkServer, kapuaHandler)
+	registerKapuaTools(sdkServer, kapuaHandler, serverInfo, readOnly)
 	registerKapuaResources(sdkServer, kapuaHandler)
-
-	return &Server{
-		logger:    logger,
-		kapuaCfg:  kapuaCfg,
-		mcpServer: sdkServer,
-	}, nil
+	registerPrompts(sdkServer, prompts)
+	return sdkServer
 }
 
 func (s *Server) Handler(httpCfg *HTTPConfig) http.Handler {
-
-	streamHandler := mcpsdk.NewStreamableHTTPHandler(func(*http.Request) *mcpsdk.Server {
-		return s.mcpServer
-	}, nil)
+	if httpCfg == nil {
+		httpCfg = &HTTPConfig{}
+	}
 
 	logger := s.logger
 	if logger == nil {
 		logger = utils.NewDefaultLogger("MCPServer")
 	}
 
-	mcpHandler := newOriginMiddleware(httpCfg, logger, streamHandler)
+	var serverMiddleware []mcpsdk.Middleware
+	policy, policyErr := httpCfg.loadedToolPolicy()
+	if policy != nil {
+		serverMiddleware = append(serverMiddleware, policy.middleware(logger))
+		if httpCfg.TLSClientCAFile == "" {
+			logger.Warn("Tool policy is configured without MCP_TLS_CLIENT_CA_FILE; every client gets the default tools")
+		}
+	}
+
+	var streamHandler http.Handler
+	if httpCfg.SessionAuth == SessionAuthHeader {
+		sessions := newSessionManager(s, logger, append([]mcpsdk.Middleware{s.requests.middleware, metricsMiddleware, tracingMiddleware, logFieldsMiddleware, progressMiddleware}, serverMiddleware...)...)
+		s.mu.Lock()
+		s.sessions = sessions
+		s.mu.Unlock()
+		streamHandler = sessions.middleware(mcpsdk.NewStreamableHTTPHandler(sessions.getServer, nil))
+	} else {
+		s.mcpServer.AddReceivingMiddleware(serverMiddleware...)
+		streamHandler = mcpsdk.NewStreamableHTTPHandler(func(*http.Request) *mcpsdk.Server {
+			return s.mcpServer
+		}, nil)
+	}
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodGet {
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-			return
+
+	if httpCfg.OAuth != nil {
+		guard, err := newOAuthGuard(httpCfg.OAuth, logger)
+		if err != nil {
+			logger.Error("OAuth authorization unavailable, rejecting MCP requests: %v", err)
+			streamHandler = unavailableHandler("authorization unavailable")
+		} else {
+			streamHandler = guard.middleware(streamHandler)
+			mux.Handle(protectedResourceMetadataPath, guard.metadataHandler())
+			if path := httpCfg.OAuth.metadataPath(); path != protectedResourceMetadataPath {
+				mux.Handle(path, guard.metadataHandler())
+			}
 		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte(`{"status":"ok"}`))
-	})
+	} else if httpCfg.TLSClientCAFile == "" && httpCfg.Host != "" && !isLocalBindHost(httpCfg.Host) {
+		logger.Warn("HTTP transport on %q has no authentication; set MCP_OAUTH_ISSUER or MCP_TLS_CLIENT_CA_FILE before exposing it beyond localhost", httpCfg.Host)
+	}
+
+	if policyErr != nil {
+		logger.Error("Tool policy unavailable, rejecting MCP requests: %v", policyErr)
+		streamHandler = unavailableHandler("tool policy unavailable")
+	}
+	streamHandler = clientCertMiddleware(streamHandler)
+
+	mcpHandler := newOriginMiddleware(httpCfg, logger, streamHandler)
+
+	mux.HandleFunc("/health", s.serveHealth)
+	mux.HandleFunc("/ready", s.serveReady)
+	mux.Handle("/metrics", metrics.Handler())
 	mux.Handle("/", mcpHandler)
 
 	return mux
 }
 
-func (s *Server) ListenAndServe(addr string, handler http.Handler) error {
+// unavailableHandler fails closed when a configured security layer could not be set up.
+func unavailableHandler(message string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, message, http.StatusServiceUnavailable)
+	})
+}
+
+// isLocalBindHost reports whether listening on host only accepts local connections.
+func isLocalBindHost(host string) bool {
+	host = strings.Trim(host, "[]")
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// ListenAndServe serves handler over HTTP on addr until ctx is done. Call Shutdown
+// afterwards to drain the connections that are still open.
+func (s *Server) ListenAndServe(ctx context.Context, addr string, handler http.Handler) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
 	s.logStartup("streamable-http", addr)
-	return http.ListenAndServe(addr, handler)
+	server := &http.Server{Handler: handler}
+	return s.serveHTTP(ctx, listener, server, server.Serve)
 }
 
+// RunTransport serves a single MCP session over transport. It returns when the
+// client disconnects or ctx is done; in the latter case the session stays open
+// so Shutdown can let its in-flight requests finish.
 func (s *Server) RunTransport(ctx context.Context, transportName string, transport mcpsdk.Transport) error {
 	if transport == nil {
 		return fmt.Errorf("transport cannot be nil")
@@ -103,7 +238,19 @@ func (s *Server) RunTransport(ctx context.Context, transportName string, transpo
 
 	s.logStartup(transportName, "")
 
-	return s.mcpServer.Run(ctx, transport)
+	session, err := s.mcpServer.Connect(context.WithoutCancel(ctx), transport, nil)
+	if err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() { done <- session.Wait() }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return nil
+	}
 }
 
 func (s *Server) logStartup(transportName, endpoint string) {
@@ -114,112 +261,174 @@ func (s *Server) logStartup(transportName, endpoint string) {
 	}
 	if s.kapuaCfg != nil {
 		s.logger.Info("Kapua API endpoint: %s", s.kapuaCfg.Kapua.APIEndpoint)
+		if s.kapuaCfg.Kapua.ReadOnly {
+			s.logger.Info("Read-only mode: tools that change devices are not registered")
+		}
 	}
 }
 
-func registerKapuaTools(server *mcpsdk.Server, kapuaHandler *handlers.KapuaHandler) {
-	mcpsdk.AddTool(server, &mcpsdk.Tool{
+// registerKapuaTools adds the Kapua tools to server. Tools backed by APIs that the
+// detected flavour does not expose are left out; a nil serverInfo registers everything.
+// With readOnly, tools that change devices are left out too.
+func registerKapuaTools(server *mcpsdk.Server, kapuaHandler *handlers.KapuaHandler, serverInfo *models.ServerInfo, readOnly bool) {
+	addTool(server, readOnly, &mcpsdk.Tool{
 		Name:        "kapua-devices-list",
 		Description: "List Kapua IoT devices with optional filters for client ID, connection status (CONNECTED/DISCONNECTED/MISSING/NULL), and free-text search. Supports pagination via limit and offset. Returns device metadata including connection state, firmware, and OS info.",
+		Annotations: readAnnotations,
 	}, kapuaHandler.HandleListDevices)
 
-	mcpsdk.AddTool(server, &mcpsdk.Tool{
+	if kapuaHandler.HasFleetIndex() {
+		addTool(server, readOnly, &mcpsdk.Tool{
+			Name:        "kapua-devices-search",
+			Description: "Search the in-memory fleet index by free text, client ID, connection status, firmware version, tag ID and last-seen time. Answers in milliseconds without calling Kapua; asOf tells when the index was refreshed. Use kapua-devices-list for live data.",
+			Annotations: indexReadAnnotations,
+		}, kapuaHandler.HandleSearchDevices)
+	}
+
+	addTool(server, readOnly, &mcpsdk.Tool{
 		Name:        "kapua-device-events-list",
-		Description: "List lifecycle events for a Kapua device (requires deviceId). Filter by resource type, date range, and sort order. Returns timestamped events such as connection changes, command executions, and application updates.",
+		Description: "List lifecycle events for a Kapua device (requires deviceId or clientId). Filter by resource type, date range, and sort order. Returns timestamped events such as connection changes, command executions, and application updates.",
+		Annotations: readAnnotations,
 	}, kapuaHandler.HandleListDeviceEvents)
 
-	mcpsdk.AddTool(server, &mcpsdk.Tool{
-		Name:        "kapua-device-logs-list",
-		Description: "List device log entries stored in the Kapua datastore. Filter by clientId, channel, date range, and log property values. Returns structured log records with timestamps and metric payloads. Supports pagination.",
-	}, kapuaHandler.HandleListDeviceLogs)
+	if serverInfo == nil || serverInfo.Capabilities.DeviceLogs {
+		addTool(server, readOnly, &mcpsdk.Tool{
+			Name:        "kapua-device-logs-list",
+			Description: "List device log entries stored in the Kapua datastore. Filter by clientId, channel, date range, and log property values. Returns structured log records with timestamps and metric payloads. Supports pagination.",
+			Annotations: readAnnotations,
+		}, kapuaHandler.HandleListDeviceLogs)
+	}
 
-	mcpsdk.AddTool(server, &mcpsdk.Tool{
+	addTool(server, readOnly, &mcpsdk.Tool{
 		Name:        "kapua-data-messages-list",
 		Description: "List telemetry data messages stored in the Kapua datastore. Filter by one or more clientIds, channel, and date range. Returns message payloads with channel, timestamp, and metric values. Supports pagination.",
+		Annotations: readAnnotations,
 	}, kapuaHandler.HandleListDataMessages)
 
-	mcpsdk.AddTool(server, &mcpsdk.Tool{
+	addTool(server, readOnly, &mcpsdk.Tool{
 		Name:        "kapua-device-configurations-read",
-		Description: "Read all OSGi configuration components currently active on a Kapua device. Requires deviceId. Returns the full set of component configurations with their properties and values.",
+		Description: "Read all OSGi configuration components currently active on a Kapua device. Requires deviceId or clientId. Returns the full set of component configurations with their properties and values.",
+		Annotations: readAnnotations,
 	}, kapuaHandler.HandleDeviceConfigurationsRead)
 
-	mcpsdk.AddTool(server, &mcpsdk.Tool{
+	addTool(server, readOnly, &mcpsdk.Tool{
 		Name:        "kapua-device-snapshots-list",
-		Description: "List available configuration snapshots for a Kapua device. Requires deviceId. Returns snapshot IDs that can be used with kapua-device-snapshot-configurations-read or kapua-device-snapshot-rollback.",
+		Description: "List available configuration snapshots for a Kapua device. Requires deviceId or clientId. Returns snapshot IDs that can be used with kapua-device-snapshot-configurations-read or kapua-device-snapshot-rollback.",
+		Annotations: readAnnotations,
 	}, kapuaHandler.HandleDeviceSnapshotsList)
 
-	mcpsdk.AddTool(server, &mcpsdk.Tool{
+	addTool(server, readOnly, &mcpsdk.Tool{
 		Name:        "kapua-device-snapshot-configurations-read",
-		Description: "Read the component configurations stored in a specific device snapshot. Requires deviceId and snapshotId. Use kapua-device-snapshots-list first to discover available snapshot IDs.",
+		Description: "Read the component configurations stored in a specific device snapshot. Requires deviceId or clientId, and snapshotId. Use kapua-device-snapshots-list first to discover available snapshot IDs.",
+		Annotations: readAnnotations,
 	}, kapuaHandler.HandleDeviceSnapshotConfigurationsRead)
 
-	mcpsdk.AddTool(server, &mcpsdk.Tool{
+	addTool(server, readOnly, &mcpsdk.Tool{
 		Name:        "kapua-device-snapshot-rollback",
-		Description: "Trigger a configuration rollback on a Kapua device to a previously saved snapshot. Requires deviceId and snapshotId. This is a mutating operation that restores the device configuration to the snapshot state.",
+		Description: "Trigger a configuration rollback on a Kapua device to a previously saved snapshot. Requires deviceId or clientId, and snapshotId. This is a mutating operation that restores the device configuration to the snapshot state.",
+		Annotations: mutatingAnnotations,
 	}, kapuaHandler.HandleDeviceSnapshotRollback)
 
-	mcpsdk.AddTool(server, &mcpsdk.Tool{
+	addTool(server, readOnly, &mcpsdk.Tool{
 		Name:        "kapua-device-inventory-read",
-		Description: "Read the general software inventory for a Kapua device. Requires deviceId. Returns all inventory items (bundles, packages, containers) with name, version, and type.",
+		Description: "Read the general software inventory for a Kapua device. Requires deviceId or clientId. Returns all inventory items (bundles, packages, containers) with name, version, and type.",
+		Annotations: readAnnotations,
 	}, kapuaHandler.HandleDeviceInventoryRead)
 
-	mcpsdk.AddTool(server, &mcpsdk.Tool{
+	addTool(server, readOnly, &mcpsdk.Tool{
 		Name:        "kapua-device-inventory-bundles-list",
-		Description: "List OSGi bundle inventory entries for a Kapua device. Requires deviceId. Returns bundle ID, name, version, status (ACTIVE/RESOLVED/INSTALLED/etc.), and signed flag.",
+		Description: "List OSGi bundle inventory entries for a Kapua device. Requires deviceId or clientId. Returns bundle ID, name, version, status (ACTIVE/RESOLVED/INSTALLED/etc.), and signed flag.",
+		Annotations: readAnnotations,
 	}, kapuaHandler.HandleDeviceInventoryBundles)
 
-	mcpsdk.AddTool(server, &mcpsdk.Tool{
+	addTool(server, readOnly, &mcpsdk.Tool{
 		Name:        "kapua-device-inventory-bundle-start",
-		Description: "Request an OSGi bundle inventory start operation on a Kapua device. Requires deviceId and a bundle descriptor object. This is an asynchronous remote operation that triggers an inventory scan for the specified bundle.",
+		Description: "Request an OSGi bundle inventory start operation on a Kapua device. Requires deviceId or clientId, and a bundle descriptor object. This is an asynchronous remote operation that triggers an inventory scan for the specified bundle.",
+		Annotations: mutatingAnnotations,
 	}, kapuaHandler.HandleDeviceInventoryBundleStart)
 
-	mcpsdk.AddTool(server, &mcpsdk.Tool{
+	addTool(server, readOnly, &mcpsdk.Tool{
 		Name:        "kapua-device-inventory-bundle-stop",
-		Description: "Request an OSGi bundle inventory stop operation on a Kapua device. Requires deviceId and a bundle descriptor object. This is an asynchronous remote operation that stops an inventory scan for the specified bundle.",
+		Description: "Request an OSGi bundle inventory stop operation on a Kapua device. Requires deviceId or clientId, and a bundle descriptor object. This is an asynchronous remote operation that stops an inventory scan for the specified bundle.",
+		Annotations: mutatingAnnotations,
 	}, kapuaHandler.HandleDeviceInventoryBundleStop)
 
-	mcpsdk.AddTool(server, &mcpsdk.Tool{
+	addTool(server, readOnly, &mcpsdk.Tool{
 		Name:        "kapua-device-inventory-containers-list",
-		Description: "List container inventory entries for a Kapua device. Requires deviceId. Returns container name, version, type, and state (ACTIVE/INSTALLED/UNINSTALLED/UNKNOWN).",
+		Description: "List container inventory entries for a Kapua device. Requires deviceId or clientId. Returns container name, version, type, and state (ACTIVE/INSTALLED/UNINSTALLED/UNKNOWN).",
+		Annotations: readAnnotations,
 	}, kapuaHandler.HandleDeviceInventoryContainers)
 
-	mcpsdk.AddTool(server, &mcpsdk.Tool{
+	addTool(server, readOnly, &mcpsdk.Tool{
 		Name:        "kapua-device-inventory-container-start",
-		Description: "Request a container inventory start operation on a Kapua device. Requires deviceId and a container descriptor object. This is an asynchronous remote operation that triggers an inventory scan for the specified container.",
+		Description: "Request a container inventory start operation on a Kapua device. Requires deviceId or clientId, and a container descriptor object. This is an asynchronous remote operation that triggers an inventory scan for the specified container.",
+		Annotations: mutatingAnnotations,
 	}, kapuaHandler.HandleDeviceInventoryContainerStart)
 
-	mcpsdk.AddTool(server, &mcpsdk.Tool{
+	addTool(server, readOnly, &mcpsdk.Tool{
 		Name:        "kapua-device-inventory-container-stop",
-		Description: "Request a container inventory stop operation on a Kapua device. Requires deviceId and a container descriptor object. This is an asynchronous remote operation that stops an inventory scan for the specified container.",
+		Description: "Request a container inventory stop operation on a Kapua device. Requires deviceId or clientId, and a container descriptor object. This is an asynchronous remote operation that stops an inventory scan for the specified container.",
+		Annotations: mutatingAnnotations,
 	}, kapuaHandler.HandleDeviceInventoryContainerStop)
 
-	mcpsdk.AddTool(server, &mcpsdk.Tool{
+	addTool(server, readOnly, &mcpsdk.Tool{
 		Name:        "kapua-device-inventory-system-packages-list",
-		Description: "List system packages installed on a Kapua device. Requires deviceId. Returns package name, version, and type from the device OS inventory.",
+		Description: "List system packages installed on a Kapua device. Requires deviceId or clientId. Returns package name, version, and type from the device OS inventory.",
+		Annotations: readAnnotations,
 	}, kapuaHandler.HandleDeviceInventorySystemPackages)
 
-	mcpsdk.AddTool(server, &mcpsdk.Tool{
+	addTool(server, readOnly, &mcpsdk.Tool{
 		Name:        "kapua-device-inventory-deployment-packages-list",
-		Description: "List deployment packages installed on a Kapua device. Requires deviceId. Returns deployment package metadata including name, version, and contained bundles.",
+		Description: "List deployment packages installed on a Kapua device. Requires deviceId or clientId. Returns deployment package metadata including name, version, and contained bundles.",
+		Annotations: readAnnotations,
 	}, kapuaHandler.HandleDeviceInventoryDeploymentPackages)
 }
 
+// Annotations of the Kapua tools. Apart from kapua-devices-search, which answers
+// from the fleet index, every tool reaches out to Kapua and its devices. Rolling
+// back, starting or stopping interrupts what runs on the device, and repeating it
+// does so again, so mutating tools are destructive and not idempotent.
+var (
+	readAnnotations      = &mcpsdk.ToolAnnotations{ReadOnlyHint: true, DestructiveHint: boolPtr(false), IdempotentHint: true, OpenWorldHint: boolPtr(true)}
+	indexReadAnnotations = &mcpsdk.ToolAnnotations{ReadOnlyHint: true, DestructiveHint: boolPtr(false), IdempotentHint: true, OpenWorldHint: boolPtr(false)}
+	mutatingAnnotations  = &mcpsdk.ToolAnnotations{DestructiveHint: boolPtr(true), IdempotentHint: false, OpenWorldHint: boolPtr(true)}
+)
+
+// addTool registers tool on server, unless the server is read-only and the tool
+// is not annotated as read-only.
+func addTool[In, Out any](server *mcpsdk.Server, readOnly bool, tool *mcpsdk.Tool, handler mcpsdk.ToolHandlerFor[In, Out]) {
+	if readOnly && (tool.Annotations == nil || !tool.Annotations.ReadOnlyHint) {
+		return
+	}
+	mcpsdk.AddTool(server, tool, handler)
+	registerToolLabel(tool.Name)
+}
+
+func boolPtr(v bool) *bool {
+	return &v
+}
+
 func registerKapuaResources(server *mcpsdk.Server, kapuaHandler *handlers.KapuaHandler) {
-	server.AddResource(&mcpsdk.Resource{
-		URI:         "kapua://devices",
-		Name:        "Kapua Devices",
-		Description: "Live list of Kapua IoT devices",
-		MIMEType:    "application/json",
-	}, func(ctx context.Context, req *mcpsdk.ReadResourceRequest) (*mcpsdk.ReadResourceResult, error) {
+	read := func(ctx context.Context, req *mcpsdk.ReadResourceRequest) (*mcpsdk.ReadResourceResult, error) {
 		return kapuaHandler.ReadResource(ctx, req.Params.URI)
-	})
+	}
 
-	server.AddResource(&mcpsdk.Resource{
-		URI:         "kapua://fleet-health",
-		Name:        "Kapua Fleet Health",
-		Description: "Aggregated fleet health snapshot",
-		MIMEType:    "application/json",
-	}, func(ctx context.Context, req *mcpsdk.ReadResourceRequest) (*mcpsdk.ReadResourceResult, error) {
-		return kapuaHandler.ReadResource(ctx, req.Params.URI)
-	})
+	// Resources match exact URIs only; the templates also cover their query forms
+	// such as kapua://devices?noCache=true.
+	resources, _ := kapuaHandler.ListResources(context.Background())
+	for _, resource := range resources {
+		server.AddResource(&resource, read)
+	}
+	templates, _ := kapuaHandler.ListResourceTemplates(context.Background())
+	for _, template := range templates {
+		server.AddResourceTemplate(&template, read)
+	}
+}
+
+func registerPrompts(server *mcpsdk.Server, prompts []*handlers.PromptTemplate) {
+	for _, prompt := range prompts {
+		server.AddPrompt(prompt.Prompt(), func(_ context.Context, req *mcpsdk.GetPromptRequest) (*mcpsdk.GetPromptResult, error) {
+			return prompt.Render(req.Params.Arguments)
+		})
+	}
 }
@@ -2,18 +2,23 @@ package mcp
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 	"unsafe"
 
 	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
 
 	"kapua-mcp-server/internal/kapua/config"
 	"kapua-mcp-server/internal/kapua/handlers"
+	"kapua-mcp-server/internal/kapua/models"
 	"kapua-mcp-server/internal/kapua/services"
 	"kapua-mcp-server/pkg/utils"
 )
@@ -36,6 +41,10 @@ func TestNewServerSuccess(t *testing.T) {
 				switch {
 				case r.URL.Path == "/v1/authentication/user":
 					_, _ = io.WriteString(w, `{"tokenId":"token","refreshToken":"refresh","expiresOn":"2025-01-02T15:04:05Z","refreshExpiresOn":"2025-01-03T15:04:05Z","scopeId":"tenant"}`)
+				case r.URL.Path == "/v1/sys-info":
+					_, _ = io.WriteString(w, `{"version":"2.0.0","buildNumber":"142"}`)
+				case r.URL.Path == "/v1/tenant/deviceLogs":
+					w.WriteHeader(http.StatusNotFound)
 				case strings.HasSuffix(r.URL.Path, "/devices"):
 					_, _ = io.WriteString(w, `{"items":[]}`)
 				default:
@@ -86,23 +95,9 @@ func TestRegisterKapuaHelpers(t *testing.T) {
 	kapuaHandler := handlers.NewKapuaHandler(&services.KapuaClient{})
 	server := mcpsdk.NewServer(&mcpsdk.Implementation{Name: "test", Version: "dev"}, nil)
 
-	registerKapuaTools(server, kapuaHandler)
+	registerKapuaTools(server, kapuaHandler, nil, false)
 	registerKapuaResources(server, kapuaHandler)
 
-	// Access the server's internal tools registry via reflection to ensure the new
-	// tool has been registered. The MCP SDK does not currently expose a public API
-	// for enumerating tools outside of the JSON-RPC surface area, so reflection is
-	// used purely for test verification.
-	toolsField := reflect.ValueOf(server).Elem().FieldByName("tools")
-	if !toolsField.IsValid() {
-		t.Fatal("tools field not found on MCP server")
-	}
-
-	toolsValue := reflect.NewAt(toolsField.Type(), unsafe.Pointer(toolsField.UnsafeAddr())).Elem()
-	featuresField := toolsValue.Elem().FieldByName("features")
-	if !featuresField.IsValid() {
-		t.Fatal("features map not found on tools registry")
-	}
 	expectedTools := []string{
 		"kapua-devices-list",
 		"kapua-device-events-list",
@@ -123,15 +118,11 @@ func TestRegisterKapuaHelpers(t *testing.T) {
 		"kapua-device-inventory-deployment-packages-list",
 	}
 
-	if got := featuresField.Len(); got < len(expectedTools) {
+	registered := registeredToolNames(t, server)
+	if got := len(registered); got < len(expectedTools) {
 		t.Fatalf("expected at least %d tools to be registered, got %d", len(expectedTools), got)
 	}
 
-	registered := make(map[string]struct{})
-	for _, key := range featuresField.MapKeys() {
-		registered[key.String()] = struct{}{}
-	}
-
 	for _, name := range expectedTools {
 		if _, ok := registered[name]; !ok {
 			t.Fatalf("expected %s tool to be registered", name)
@@ -139,6 +130,130 @@ func TestRegisterKapuaHelpers(t *testing.T) {
 	}
 }
 
+func TestRegisterKapuaToolsHidesUnsupportedTools(t *testing.T) {
+	kapuaHandler := handlers.NewKapuaHandler(&services.KapuaClient{})
+	server := mcpsdk.NewServer(&mcpsdk.Implementation{Name: "test", Version: "dev"}, nil)
+
+	registerKapuaTools(server, kapuaHandler, &models.ServerInfo{Flavour: models.ServerFlavourEclipseKapua}, false)
+
+	registered := registeredToolNames(t, server)
+	if _, ok := registered["kapua-device-logs-list"]; ok {
+		t.Fatal("expected kapua-device-logs-list to be hidden on Eclipse Kapua")
+	}
+	if _, ok := registered["kapua-device-events-list"]; !ok {
+		t.Fatal("expected kapua-device-events-list to remain registered")
+	}
+}
+
+func TestRegisterKapuaToolsSearchNeedsFleetIndex(t *testing.T) {
+	client := &services.KapuaClient{}
+	kapuaHandler := handlers.NewKapuaHandler(client)
+	server := mcpsdk.NewServer(&mcpsdk.Implementation{Name: "test", Version: "dev"}, nil)
+	registerKapuaTools(server, kapuaHandler, nil, false)
+	if _, ok := registeredToolNames(t, server)["kapua-devices-search"]; ok {
+		t.Fatal("expected kapua-devices-search to be hidden without a fleet index")
+	}
+
+	kapuaHandler.SetFleetIndex(handlers.NewFleetIndex(client, time.Minute))
+	server = mcpsdk.NewServer(&mcpsdk.Implementation{Name: "test", Version: "dev"}, nil)
+	registerKapuaTools(server, kapuaHandler, nil, false)
+	if _, ok := registeredToolNames(t, server)["kapua-devices-search"]; !ok {
+		t.Fatal("expected kapua-devices-search to be registered with a fleet index")
+	}
+}
+
+func TestRegisterKapuaToolsReadOnly(t *testing.T) {
+	client := &services.KapuaClient{}
+	kapuaHandler := handlers.NewKapuaHandler(client)
+	kapuaHandler.SetFleetIndex(handlers.NewFleetIndex(client, time.Minute))
+	server := mcpsdk.NewServer(&mcpsdk.Implementation{Name: "test", Version: "dev"}, nil)
+	registerKapuaTools(server, kapuaHandler, nil, false)
+
+	serverTransport, clientTransport := mcpsdk.NewInMemoryTransports()
+	serverSession, err := server.Connect(context.Background(), serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server connect failed: %v", err)
+	}
+	defer serverSession.Close()
+	session, err := mcpsdk.NewClient(&mcpsdk.Implementation{Name: "client", Version: "dev"}, nil).Connect(context.Background(), clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect failed: %v", err)
+	}
+	defer session.Close()
+	tools, err := session.ListTools(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ListTools returned error: %v", err)
+	}
+
+	readOnlyTools := map[string]bool{}
+	annotations := map[string]*mcpsdk.ToolAnnotations{}
+	for _, tool := range tools.Tools {
+		a := tool.Annotations
+		if a == nil || a.DestructiveHint == nil || a.OpenWorldHint == nil {
+			t.Fatalf("tool %s lacks annotations: %+v", tool.Name, a)
+		}
+		readOnlyTools[tool.Name] = a.ReadOnlyHint
+		annotations[tool.Name] = a
+	}
+	mutating := []string{
+		"kapua-device-snapshot-rollback",
+		"kapua-device-inventory-bundle-start",
+		"kapua-device-inventory-bundle-stop",
+		"kapua-device-inventory-container-start",
+		"kapua-device-inventory-container-stop",
+	}
+	for _, name := range mutating {
+		a, ok := annotations[name]
+		if !ok {
+			t.Fatalf("expected %s to be registered", name)
+		}
+		if a.ReadOnlyHint || !*a.DestructiveHint || a.IdempotentHint {
+			t.Errorf("expected %s to be destructive and not idempotent, got %+v", name, a)
+		}
+	}
+	for name, readOnly := range readOnlyTools {
+		if !readOnly && !slices.Contains(mutating, name) {
+			t.Errorf("unexpected mutating tool %s", name)
+		}
+	}
+	if !readOnlyTools["kapua-devices-list"] || !readOnlyTools["kapua-devices-search"] {
+		t.Fatalf("expected reads to be annotated read-only: %v", readOnlyTools)
+	}
+
+	server = mcpsdk.NewServer(&mcpsdk.Implementation{Name: "test", Version: "dev"}, nil)
+	registerKapuaTools(server, kapuaHandler, nil, true)
+	registered := registeredToolNames(t, server)
+	for name, readOnly := range readOnlyTools {
+		if _, ok := registered[name]; ok != readOnly {
+			t.Errorf("read-only server: %s registered=%v, want %v", name, ok, readOnly)
+		}
+	}
+}
+
+// registeredToolNames reads the server's internal tools registry via reflection.
+// The MCP SDK does not currently expose a public API for enumerating tools outside
+// of the JSON-RPC surface area, so reflection is used purely for test verification.
+func registeredToolNames(t *testing.T, server *mcpsdk.Server) map[string]struct{} {
+	t.Helper()
+
+	toolsField := reflect.ValueOf(server).Elem().FieldByName("tools")
+	if !toolsField.IsValid() {
+		t.Fatal("tools field not found on MCP server")
+	}
+
+	toolsValue := reflect.NewAt(toolsField.Type(), unsafe.Pointer(toolsField.UnsafeAddr())).Elem()
+	featuresField := toolsValue.Elem().FieldByName("features")
+	if !featuresField.IsValid() {
+		t.Fatal("features map not found on tools registry")
+	}
+
+	registered := make(map[string]struct{})
+	for _, key := range featuresField.MapKeys() {
+		registered[key.String()] = struct{}{}
+	}
+	return registered
+}
+
 func TestHealthEndpoint(t *testing.T) {
 	srv := &Server{
 		logger: utils.NewDefaultLogger("test"),
@@ -200,3 +315,199 @@ func TestRunTransportNilTransport(t *testing.T) {
 		t.Fatalf("expected error about nil transport, got %v", err)
 	}
 }
+
+func TestHealthEndpointReportsCircuit(t *testing.T) {
+	client := services.NewKapuaClient(&config.KapuaConfig{APIEndpoint: "https://example", BreakerThreshold: 1, BreakerCooldown: time.Minute})
+	client.SetHTTPClient(&http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("dial tcp: connection refused")
+	})})
+	srv := &Server{
+		logger:      utils.NewDefaultLogger("test"),
+		kapuaCfg:    &config.Config{},
+		kapuaClient: client,
+		mcpServer:   mcpsdk.NewServer(&mcpsdk.Implementation{Name: "test", Version: "dev"}, nil),
+	}
+	handler := srv.Handler(&HTTPConfig{})
+
+	get := func() map[string]any {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200 even when degraded, got %d", rec.Code)
+		}
+		var body map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("invalid body %q: %v", rec.Body.String(), err)
+		}
+		return body
+	}
+
+	body := get()
+	if body["status"] != "ok" || body["kapua"].(map[string]any)["circuit"].(map[string]any)["state"] != "closed" {
+		t.Fatalf("unexpected healthy body: %v", body)
+	}
+
+	if _, err := client.GetDevice(context.Background(), "dev-1"); err == nil {
+		t.Fatal("expected Kapua call to fail")
+	}
+	body = get()
+	circuit := body["kapua"].(map[string]any)["circuit"].(map[string]any)
+	if body["status"] != "degraded" || circuit["state"] != "open" || circuit["unavailableSince"] == nil || circuit["consecutiveFailures"] != float64(1) {
+		t.Fatalf("unexpected degraded body: %v", body)
+	}
+}
+
+func TestRegisterKapuaResourcesTemplates(t *testing.T) {
+	client := services.NewKapuaClient(&config.KapuaConfig{APIEndpoint: "http://kapua.test", Timeout: 5})
+	client.SetHTTPClient(&http.Client{Transport: handlerRoundTripper{handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/tenant/devices/dev-1" {
+			_, _ = io.WriteString(w, `{"id":"dev-1","clientId":"gw-1"}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"totalCount":1,"items":[{"id":"dev-1","clientId":"gw-1"}]}`)
+	})}})
+	client.SetTokenInfo(&models.AccessToken{KapuaEntity: models.KapuaEntity{ScopeID: "tenant"}, TokenID: "token", ExpiresOn: time.Now().Add(time.Hour)})
+
+	serverTransport, clientTransport := mcpsdk.NewInMemoryTransports()
+	serverSession, err := newSDKServer(client, nil, false, nil, nil, nil, nil).Connect(context.Background(), serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server connect failed: %v", err)
+	}
+	defer serverSession.Close()
+	session, err := mcpsdk.NewClient(&mcpsdk.Implementation{Name: "client", Version: "dev"}, nil).Connect(context.Background(), clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect failed: %v", err)
+	}
+	defer session.Close()
+
+	templates, err := session.ListResourceTemplates(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ListResourceTemplates returned error: %v", err)
+	}
+	if len(templates.ResourceTemplates) != 8 {
+		t.Fatalf("expected 8 resource templates, got %d", len(templates.ResourceTemplates))
+	}
+	for _, uri := range []string{"kapua://devices", "kapua://devices?noCache=true", "kapua://devices/gw-1", "kapua://devices/gw-1?noCache=true"} {
+		result, err := session.ReadResource(context.Background(), &mcpsdk.ReadResourceParams{URI: uri})
+		if err != nil {
+			t.Fatalf("ReadResource(%s) returned error: %v", uri, err)
+		}
+		if !strings.Contains(result.Contents[0].Text, `"gw-1"`) {
+			t.Fatalf("unexpected %s content: %s", uri, result.Contents[0].Text)
+		}
+	}
+}
+
+func TestRegisterPrompts(t *testing.T) {
+	prompts, err := handlers.LoadPrompts("")
+	if err != nil {
+		t.Fatalf("LoadPrompts returned error: %v", err)
+	}
+	serverTransport, clientTransport := mcpsdk.NewInMemoryTransports()
+	serverSession, err := newSDKServer(&services.KapuaClient{}, nil, false, nil, nil, prompts, nil).Connect(context.Background(), serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server connect failed: %v", err)
+	}
+	defer serverSession.Close()
+	session, err := mcpsdk.NewClient(&mcpsdk.Implementation{Name: "client", Version: "dev"}, nil).Connect(context.Background(), clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect failed: %v", err)
+	}
+	defer session.Close()
+
+	listed, err := session.ListPrompts(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ListPrompts returned error: %v", err)
+	}
+	if len(listed.Prompts) != len(prompts) {
+		t.Fatalf("expected %d prompts, got %d", len(prompts), len(listed.Prompts))
+	}
+	result, err := session.GetPrompt(context.Background(), &mcpsdk.GetPromptParams{Name: "kapua-diagnose-offline-device", Arguments: map[string]string{"clientId": "gw-1"}})
+	if err != nil {
+		t.Fatalf("GetPrompt returned error: %v", err)
+	}
+	if text := result.Messages[0].Content.(*mcpsdk.TextContent).Text; !strings.Contains(text, "kapua://devices/gw-1") {
+		t.Fatalf("unexpected prompt text: %s", text)
+	}
+	if _, err := session.GetPrompt(context.Background(), &mcpsdk.GetPromptParams{Name: "kapua-diagnose-offline-device"}); err == nil {
+		t.Fatal("expected an error without the clientId argument")
+	}
+}
+
+func TestCompletions(t *testing.T) {
+	client := services.NewKapuaClient(&config.KapuaConfig{APIEndpoint: "http://kapua.test", Timeout: 5})
+	client.SetHTTPClient(&http.Client{Transport: handlerRoundTripper{handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"totalCount":2,"items":[{"id":"dev-1","clientId":"gw-1"},{"id":"dev-2","clientId":"pump-1"}]}`)
+	})}})
+	client.SetTokenInfo(&models.AccessToken{KapuaEntity: models.KapuaEntity{ScopeID: "tenant"}, TokenID: "token", ExpiresOn: time.Now().Add(time.Hour)})
+
+	serverTransport, clientTransport := mcpsdk.NewInMemoryTransports()
+	serverSession, err := newSDKServer(client, nil, false, nil, nil, nil, nil).Connect(context.Background(), serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server connect failed: %v", err)
+	}
+	defer serverSession.Close()
+	session, err := mcpsdk.NewClient(&mcpsdk.Implementation{Name: "client", Version: "dev"}, nil).Connect(context.Background(), clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect failed: %v", err)
+	}
+	defer session.Close()
+
+	if session.InitializeResult().Capabilities.Completions == nil {
+		t.Fatal("expected the completions capability")
+	}
+	result, err := session.Complete(context.Background(), &mcpsdk.CompleteParams{
+		Ref:      &mcpsdk.CompleteReference{Type: "ref/resource", URI: "kapua://devices/{clientId}/inventory{?noCache}"},
+		Argument: mcpsdk.CompleteParamsArgument{Name: "clientId", Value: "g"},
+	})
+	if err != nil {
+		t.Fatalf("Complete returned error: %v", err)
+	}
+	if values := result.Completion.Values; len(values) != 1 || values[0] != "gw-1" {
+		t.Fatalf("unexpected completions %v", values)
+	}
+}
+
+func TestToolsReturnStructuredContent(t *testing.T) {
+	client := services.NewKapuaClient(&config.KapuaConfig{APIEndpoint: "http://kapua.test", Timeout: 5})
+	client.SetHTTPClient(&http.Client{Transport: handlerRoundTripper{handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"totalCount":1,"items":[{"id":"dev-1","clientId":"gw-1","createdOn":"2024-08-01T12:00:00Z","connection":{"status":"CONNECTED"}}]}`)
+	})}})
+	client.SetTokenInfo(&models.AccessToken{KapuaEntity: models.KapuaEntity{ScopeID: "tenant"}, TokenID: "token", ExpiresOn: time.Now().Add(time.Hour)})
+
+	serverTransport, clientTransport := mcpsdk.NewInMemoryTransports()
+	serverSession, err := newSDKServer(client, nil, false, handlers.NewFleetIndex(client, time.Minute), nil, nil, nil).Connect(context.Background(), serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server connect failed: %v", err)
+	}
+	defer serverSession.Close()
+	session, err := mcpsdk.NewClient(&mcpsdk.Implementation{Name: "client", Version: "dev"}, nil).Connect(context.Background(), clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect failed: %v", err)
+	}
+	defer session.Close()
+
+	tools, err := session.ListTools(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ListTools returned error: %v", err)
+	}
+	for _, tool := range tools.Tools {
+		schema, ok := tool.OutputSchema.(map[string]any)
+		if !ok || schema["type"] != "object" {
+			t.Errorf("tool %s declares no object output schema: %v", tool.Name, tool.OutputSchema)
+		}
+	}
+
+	result, err := session.CallTool(context.Background(), &mcpsdk.CallToolParams{Name: "kapua-devices-list", Arguments: map[string]any{}})
+	if err != nil || result.IsError {
+		t.Fatalf("CallTool failed: %v %+v", err, result)
+	}
+	if summary := result.Content[0].(*mcpsdk.TextContent).Text; !strings.HasPrefix(summary, "Found 1 devices.") {
+		t.Fatalf("expected the summary to stay in the text content, got %q", summary)
+	}
+	data, _ := json.Marshal(result.StructuredContent)
+	var devices models.DeviceListResult
+	if err := json.Unmarshal(data, &devices); err != nil || len(devices.Items) != 1 || devices.Items[0].ClientID != "gw-1" {
+		t.Fatalf("unexpected structured content %s: %v", data, err)
+	}
+}
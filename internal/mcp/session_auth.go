@@ -0,0 +1,303 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"kapua-mcp-server/internal/kapua/config"
+	"kapua-mcp-server/internal/kapua/services"
+	"kapua-mcp-server/pkg/utils"
+)
+
+const (
+	// apiKeyHeader carries a Kapua API key for the MCP session.
+	apiKeyHeader = "X-Kapua-Api-Key"
+	// sessionIDHeader is the streamable HTTP session header set by the MCP SDK.
+	sessionIDHeader = "Mcp-Session-Id"
+	// sessionLogoutTimeout bounds the Kapua logout issued when a session ends.
+	sessionLogoutTimeout = 10 * time.Second
+	// maxInitializeBodyBytes caps the body read to recognise an initialize request.
+	maxInitializeBodyBytes = 1 << 20
+)
+
+// sessionCredentials are the Kapua credentials presented by an MCP client.
+type sessionCredentials struct {
+	method string // "apikey" or "jwt"
+	secret string
+}
+
+// fingerprint identifies the credentials without keeping the secret around.
+func (c sessionCredentials) fingerprint() string {
+	sum := sha256.Sum256([]byte(c.method + ":" + c.secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// kapuaConfig derives the Kapua configuration for a session from the server's one,
+// keeping endpoint and timeout but replacing the credentials.
+func (c sessionCredentials) kapuaConfig(base config.KapuaConfig) *config.KapuaConfig {
+	cfg := &config.KapuaConfig{
+		APIEndpoint: base.APIEndpoint,
+		Timeout:     base.Timeout,
+		AuthMethod:  c.method,
+	}
+	switch c.method {
+	case "apikey":
+		cfg.APIKey = c.secret
+	case "jwt":
+		cfg.JWT = c.secret
+	}
+	return cfg
+}
+
+// credentialsFromRequest extracts per-session credentials: an API key in
+// X-Kapua-Api-Key, or a bearer token exchanged through Kapua's JWT login.
+func credentialsFromRequest(r *http.Request) (sessionCredentials, bool) {
+	if apiKey := strings.TrimSpace(r.Header.Get(apiKeyHeader)); apiKey != "" {
+		return sessionCredentials{method: "apikey", secret: apiKey}, true
+	}
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") && strings.TrimSpace(token) != "" {
+		return sessionCredentials{method: "jwt", secret: strings.TrimSpace(token)}, true
+	}
+	return sessionCredentials{}, false
+}
+
+// sessionEntry binds an MCP session to the Kapua client created for it.
+type sessionEntry struct {
+	client      *services.KapuaClient
+	fingerprint string
+	id          string                // Set once the SDK assigns the session ID
+	server      *mcpsdk.Server        // The server built for the session
+	session     *mcpsdk.ServerSession // Set once the request that opened it ends
+}
+
+type sessionClientKey struct{}
+
+// sessionManager gives every MCP session its own Kapua client, authenticated with
+// the credentials sent when the session was opened. Later requests for the session
+// must present the same credentials.
+type sessionManager struct {
+	server           *Server
+	logger           *utils.Logger
+	serverMiddleware []mcpsdk.Middleware // Receiving middleware added to every session server
+
+	mu       sync.Mutex
+	sessions map[string]*sessionEntry
+}
+
+func newSessionManager(server *Server, logger *utils.Logger, middleware ...mcpsdk.Middleware) *sessionManager {
+	return &sessionManager{
+		server:           server,
+		logger:           logger,
+		serverMiddleware: middleware,
+		sessions:         make(map[string]*sessionEntry),
+	}
+}
+
+// middleware authenticates requests before they reach the streamable HTTP handler.
+func (m *sessionManager) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		creds, ok := credentialsFromRequest(r)
+		if !ok {
+			unauthorized(w, "missing Kapua credentials: send "+apiKeyHeader+" or Authorization: Bearer")
+			return
+		}
+
+		if sessionID := r.Header.Get(sessionIDHeader); sessionID != "" {
+			if entry := m.lookup(sessionID); entry != nil &&
+				subtle.ConstantTimeCompare([]byte(entry.fingerprint), []byte(creds.fingerprint())) != 1 {
+				m.logger.Warn("Rejected request for session %s with different credentials", sessionID)
+				http.Error(w, "credentials do not match the session", http.StatusForbidden)
+				return
+			}
+			// Unknown session IDs are answered by the SDK handler.
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Only initialize opens a session; the SDK turns anything else away
+		// without a Kapua login on its behalf.
+		initialize, err := isInitializeRequest(w, r)
+		if err != nil {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if !initialize {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		client := m.server.kapuaClient.NewSessionClient(creds.kapuaConfig(m.server.kapuaCfg.Kapua))
+		if _, err := client.QuickAuthenticate(r.Context()); err != nil {
+			m.logger.Warn("Kapua authentication failed for new MCP session: %v", err)
+			unauthorized(w, "Kapua rejected the session credentials")
+			return
+		}
+
+		entry := &sessionEntry{client: client, fingerprint: creds.fingerprint()}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), sessionClientKey{}, entry)))
+		m.track(entry)
+	})
+}
+
+// isInitializeRequest reports whether r posts an initialize request, leaving the
+// body for the next handler to read. Bodies over maxInitializeBodyBytes are refused
+// before any Kapua login is attempted.
+func isInitializeRequest(w http.ResponseWriter, r *http.Request) (bool, error) {
+	if r.Method != http.MethodPost || r.Body == nil {
+		return false, nil
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxInitializeBodyBytes))
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return false, err
+		}
+		return false, nil
+	}
+	var message struct {
+		Method string `json:"method"`
+	}
+	return json.Unmarshal(body, &message) == nil && message.Method == "initialize", nil
+}
+
+// track watches the session opened by the request that authenticated entry, so
+// that the client is logged out when the session ends, whether or not it was
+// ever initialized. A request that opened no session logs the client out at once.
+func (m *sessionManager) track(entry *sessionEntry) {
+	m.mu.Lock()
+	id, server := entry.id, entry.server
+	registered := id != "" && m.sessions[id] == entry
+	m.mu.Unlock()
+
+	var session *mcpsdk.ServerSession
+	if server != nil {
+		for candidate := range server.Sessions() {
+			if candidate.ID() == id {
+				session = candidate
+			}
+		}
+	}
+	switch {
+	case id != "" && !registered:
+		// closeAll ran meanwhile and already logged the client out.
+		if session != nil {
+			_ = session.Close()
+		}
+	case session == nil:
+		if registered {
+			m.mu.Lock()
+			delete(m.sessions, id)
+			m.mu.Unlock()
+		}
+		m.logout(entry, "request without session")
+	default:
+		m.mu.Lock()
+		entry.session = session
+		m.mu.Unlock()
+		go m.closeWhenDone(session)
+	}
+}
+
+// getServer builds a dedicated MCP server for a new session from the client the
+// middleware authenticated.
+func (m *sessionManager) getServer(r *http.Request) *mcpsdk.Server {
+	entry, _ := r.Context().Value(sessionClientKey{}).(*sessionEntry)
+	if entry == nil {
+		return nil
+	}
+
+	opts := &mcpsdk.ServerOptions{
+		GetSessionID: func() string {
+			id := newSessionID()
+			m.mu.Lock()
+			entry.id = id
+			m.sessions[id] = entry
+			m.mu.Unlock()
+			return id
+		},
+	}
+	// No fleet index: it holds what the server account sees, not this session's user.
+	// Subscriptions poll with the session's own credentials for the same reason.
+	subscribed := newResourceSubscriptions(entry.client, nil, m.server.kapuaCfg.Kapua.SubscriptionPollInterval)
+	server := newSDKServer(entry.client, m.server.serverInfo, m.server.kapuaCfg.Kapua.ReadOnly, nil, subscribed, m.server.prompts, opts)
+	server.AddReceivingMiddleware(m.serverMiddleware...)
+	m.mu.Lock()
+	entry.server = server
+	m.mu.Unlock()
+	return server
+}
+
+// closeWhenDone logs the session's Kapua client out once the MCP session ends.
+func (m *sessionManager) closeWhenDone(session *mcpsdk.ServerSession) {
+	_ = session.Wait()
+
+	m.mu.Lock()
+	entry := m.sessions[session.ID()]
+	delete(m.sessions, session.ID())
+	m.mu.Unlock()
+	if entry == nil {
+		return
+	}
+	m.logout(entry, "session "+session.ID())
+}
+
+// logout logs the Kapua client of entry out; what names the session in warnings.
+func (m *sessionManager) logout(entry *sessionEntry, what string) {
+	ctx, cancel := context.WithTimeout(context.Background(), sessionLogoutTimeout)
+	defer cancel()
+	if err := entry.client.Logout(ctx); err != nil {
+		m.logger.Warn("Kapua logout failed for %s: %v", what, err)
+	}
+}
+
+// closeAll ends every open session and logs its Kapua client out.
+func (m *sessionManager) closeAll() {
+	m.mu.Lock()
+	entries := m.sessions
+	m.sessions = make(map[string]*sessionEntry)
+	sessions := make(map[string]*mcpsdk.ServerSession, len(entries))
+	for id, entry := range entries {
+		sessions[id] = entry.session
+	}
+	m.mu.Unlock()
+
+	for id, entry := range entries {
+		if session := sessions[id]; session != nil {
+			_ = session.Close()
+		}
+		m.logout(entry, "session "+id)
+	}
+}
+
+func (m *sessionManager) lookup(sessionID string) *sessionEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.sessions[sessionID]
+}
+
+func newSessionID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+func unauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="kapua-mcp-server"`)
+	http.Error(w, message, http.StatusUnauthorized)
+}
@@ -0,0 +1,335 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"kapua-mcp-server/internal/kapua/config"
+	"kapua-mcp-server/internal/kapua/models"
+	"kapua-mcp-server/internal/kapua/services"
+)
+
+type headerRoundTripper struct {
+	header http.Header
+	base   http.RoundTripper
+}
+
+func (rt headerRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for key, values := range rt.header {
+		req.Header[key] = values
+	}
+	return rt.base.RoundTrip(req)
+}
+
+// kapuaCallLog records the bearer tokens the fake Kapua saw.
+type kapuaCallLog struct {
+	mu      sync.Mutex
+	logins  int // API key and JWT logins
+	devices []string
+	logouts []string
+}
+
+func (l *kapuaCallLog) snapshot() (devices, logouts []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.devices...), append([]string(nil), l.logouts...)
+}
+
+func (l *kapuaCallLog) loginCount() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.logins
+}
+
+// waitForLogouts waits until the fake Kapua saw want logouts.
+func (l *kapuaCallLog) waitForLogouts(t *testing.T, want ...string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, logouts := l.snapshot()
+		if strings.Join(logouts, ",") == strings.Join(want, ",") {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for logouts %v, got %v", want, logouts)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// newSessionAuthTestServer starts an HTTP MCP server in header session mode backed by
+// a fake Kapua that issues "token-<credential>" tokens and records which token
+// listed devices.
+func newSessionAuthTestServer(t *testing.T) (*httptest.Server, *kapuaCallLog) {
+	t.Helper()
+	calls := &kapuaCallLog{}
+
+	kapuaClientFactory = func(cfg *config.KapuaConfig) *services.KapuaClient {
+		client := services.NewKapuaClient(cfg)
+		client.SetHTTPClient(&http.Client{
+			Transport: handlerRoundTripper{handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				token := func(credential string) {
+					_, _ = fmt.Fprintf(w, `{"tokenId":"token-%s","expiresOn":"2030-01-02T15:04:05Z","scopeId":"tenant"}`, credential)
+				}
+				switch r.URL.Path {
+				case "/v1/authentication/user":
+					token("service")
+				case "/v1/authentication/apikey":
+					calls.mu.Lock()
+					calls.logins++
+					calls.mu.Unlock()
+					var creds models.APIKeyCredentials
+					_ = json.NewDecoder(r.Body).Decode(&creds)
+					if creds.APIKey == "bad-key" {
+						w.WriteHeader(http.StatusUnauthorized)
+						_, _ = io.WriteString(w, `{"kapuaErrorCode":"INVALID_CREDENTIALS"}`)
+						return
+					}
+					token(creds.APIKey)
+				case "/v1/authentication/jwt":
+					calls.mu.Lock()
+					calls.logins++
+					calls.mu.Unlock()
+					var creds models.JWTCredentials
+					_ = json.NewDecoder(r.Body).Decode(&creds)
+					token(creds.JWT)
+				case "/v1/authentication/logout":
+					calls.mu.Lock()
+					calls.logouts = append(calls.logouts, r.Header.Get("Authorization"))
+					calls.mu.Unlock()
+				case "/v1/sys-info":
+					_, _ = io.WriteString(w, `{"version":"2.0.0"}`)
+				case "/v1/tenant/deviceLogs":
+					w.WriteHeader(http.StatusNotFound)
+				case "/v1/tenant/devices":
+					calls.mu.Lock()
+					calls.devices = append(calls.devices, r.Header.Get("Authorization"))
+					calls.mu.Unlock()
+					_, _ = io.WriteString(w, `{"items":[]}`)
+				default:
+					t.Errorf("unexpected path %s", r.URL.Path)
+					w.WriteHeader(http.StatusNotFound)
+				}
+			})},
+		})
+		return client
+	}
+	t.Cleanup(func() { kapuaClientFactory = services.NewKapuaClient })
+
+	cfg := &config.Config{Kapua: config.KapuaConfig{APIEndpoint: "http://kapua.test", Timeout: 5, Username: "user", Password: "pass", AuthMethod: "password"}}
+	srv, err := NewServer(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("NewServer returned error: %v", err)
+	}
+
+	httpServer := httptest.NewServer(srv.Handler(&HTTPConfig{AllowedOrigins: []string{"*"}, SessionAuth: SessionAuthHeader}))
+	t.Cleanup(httpServer.Close)
+	return httpServer, calls
+}
+
+func connectWithHeaders(t *testing.T, endpoint string, header http.Header) (*mcpsdk.ClientSession, error) {
+	t.Helper()
+	client := mcpsdk.NewClient(&mcpsdk.Implementation{Name: "test-client", Version: "1.0.0"}, nil)
+	transport := &mcpsdk.StreamableClientTransport{
+		Endpoint:   endpoint,
+		HTTPClient: &http.Client{Transport: headerRoundTripper{header: header, base: http.DefaultTransport}},
+		MaxRetries: -1,
+	}
+	return client.Connect(context.Background(), transport, nil)
+}
+
+func TestSessionAuthUsesPerSessionKapuaClients(t *testing.T) {
+	httpServer, calls := newSessionAuthTestServer(t)
+
+	alice, err := connectWithHeaders(t, httpServer.URL, http.Header{apiKeyHeader: {"alice-key"}})
+	if err != nil {
+		t.Fatalf("alice connect failed: %v", err)
+	}
+	bob, err := connectWithHeaders(t, httpServer.URL, http.Header{"Authorization": {"Bearer bob-jwt"}})
+	if err != nil {
+		t.Fatalf("bob connect failed: %v", err)
+	}
+
+	for _, session := range []*mcpsdk.ClientSession{alice, bob} {
+		result, err := session.CallTool(context.Background(), &mcpsdk.CallToolParams{Name: "kapua-devices-list", Arguments: map[string]any{}})
+		if err != nil || result.IsError {
+			t.Fatalf("tool call failed: %v %+v", err, result)
+		}
+	}
+
+	devices, _ := calls.snapshot()
+	expected := "Bearer token-alice-key,Bearer token-bob-jwt"
+	if got := strings.Join(devices, ","); got != expected {
+		t.Fatalf("expected Kapua calls as %s, got %s", expected, got)
+	}
+
+	if err := alice.Close(); err != nil {
+		t.Fatalf("closing alice session failed: %v", err)
+	}
+	calls.waitForLogouts(t, "Bearer token-alice-key")
+	_ = bob.Close()
+}
+
+func TestSessionAuthRejectsMissingOrInvalidCredentials(t *testing.T) {
+	httpServer, _ := newSessionAuthTestServer(t)
+
+	for name, header := range map[string]http.Header{
+		"missing": {},
+		"invalid": {apiKeyHeader: {"bad-key"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, httpServer.URL, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`))
+			req.Header = header.Clone()
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Accept", "application/json, text/event-stream")
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusUnauthorized {
+				t.Fatalf("expected 401, got %d", resp.StatusCode)
+			}
+			if resp.Header.Get("WWW-Authenticate") == "" {
+				t.Fatal("expected WWW-Authenticate challenge")
+			}
+		})
+	}
+}
+
+func TestSessionAuthRejectsCredentialSwitch(t *testing.T) {
+	httpServer, _ := newSessionAuthTestServer(t)
+
+	var sessionID string
+	capture := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := http.DefaultTransport.RoundTrip(req)
+		if err == nil && sessionID == "" {
+			sessionID = resp.Header.Get(sessionIDHeader)
+		}
+		return resp, err
+	})
+	client := mcpsdk.NewClient(&mcpsdk.Implementation{Name: "test-client", Version: "1.0.0"}, nil)
+	session, err := client.Connect(context.Background(), &mcpsdk.StreamableClientTransport{
+		Endpoint:   httpServer.URL,
+		HTTPClient: &http.Client{Transport: headerRoundTripper{header: http.Header{apiKeyHeader: {"alice-key"}}, base: capture}},
+		MaxRetries: -1,
+	}, nil)
+	if err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	defer session.Close()
+	if sessionID == "" {
+		t.Fatal("expected session ID from server")
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, httpServer.URL, strings.NewReader(`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`))
+	req.Header.Set(sessionIDHeader, sessionID)
+	req.Header.Set(apiKeyHeader, "mallory-key")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 for credential switch, got %d", resp.StatusCode)
+	}
+}
+
+// postMCP posts body to the MCP endpoint with header and the headers the SDK
+// expects, unless header sets them.
+func postMCP(t *testing.T, url string, header http.Header, body string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	req.Header = header.Clone()
+	req.Header.Set("Content-Type", "application/json")
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/json, text/event-stream")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return resp
+}
+
+func TestSessionAuthSkipsLoginForStrayRequests(t *testing.T) {
+	httpServer, calls := newSessionAuthTestServer(t)
+
+	resp := postMCP(t, httpServer.URL, http.Header{apiKeyHeader: {"alice-key"}}, `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	if resp.StatusCode < 400 {
+		t.Fatalf("expected a request without session to be rejected, got %d", resp.StatusCode)
+	}
+	if calls.loginCount() != 0 {
+		t.Fatalf("expected no Kapua login for a request that opens no session, got %d", calls.loginCount())
+	}
+}
+
+func TestSessionAuthRejectsOversizedBodies(t *testing.T) {
+	httpServer, calls := newSessionAuthTestServer(t)
+
+	body := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"padding":"` + strings.Repeat("x", maxInitializeBodyBytes) + `"}}`
+	resp := postMCP(t, httpServer.URL, http.Header{apiKeyHeader: {"alice-key"}}, body)
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d", resp.StatusCode)
+	}
+	if calls.loginCount() != 0 {
+		t.Fatalf("expected no Kapua login for an oversized body, got %d", calls.loginCount())
+	}
+}
+
+func TestSessionAuthLogsOutWhenNoSessionOpens(t *testing.T) {
+	httpServer, calls := newSessionAuthTestServer(t)
+
+	// The SDK rejects the initialize request after the middleware logged in.
+	header := http.Header{apiKeyHeader: {"alice-key"}, "Accept": {"application/json"}}
+	resp := postMCP(t, httpServer.URL, header, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", resp.StatusCode)
+	}
+	if calls.loginCount() != 1 {
+		t.Fatalf("expected one Kapua login, got %d", calls.loginCount())
+	}
+	calls.waitForLogouts(t, "Bearer token-alice-key")
+}
+
+func TestSessionAuthLogsOutUninitializedSessions(t *testing.T) {
+	httpServer, calls := newSessionAuthTestServer(t)
+
+	header := http.Header{apiKeyHeader: {"alice-key"}}
+	params := `{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"test-client","version":"1.0.0"}}`
+	resp := postMCP(t, httpServer.URL, header, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":`+params+`}`)
+	sessionID := resp.Header.Get(sessionIDHeader)
+	if resp.StatusCode != http.StatusOK || sessionID == "" {
+		t.Fatalf("expected a session, got %d %q", resp.StatusCode, sessionID)
+	}
+
+	// The client goes away without sending notifications/initialized.
+	req, _ := http.NewRequest(http.MethodDelete, httpServer.URL, nil)
+	req.Header = header.Clone()
+	req.Header.Set(sessionIDHeader, sessionID)
+	deleted, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("DELETE failed: %v", err)
+	}
+	deleted.Body.Close()
+	calls.waitForLogouts(t, "Bearer token-alice-key")
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
@@ -0,0 +1,177 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// shutdownAbortGrace bounds how long shutdown waits for cancelled requests to return.
+const shutdownAbortGrace = 5 * time.Second
+
+// errShuttingDown is returned for MCP requests received after shutdown has started.
+var errShuttingDown = errors.New("server is shutting down")
+
+// requestTracker counts MCP requests being handled so that shutdown can wait for
+// them, and cancels the ones still running once the drain deadline has passed.
+type requestTracker struct {
+	mu       sync.Mutex
+	active   int
+	draining bool
+	idle     chan struct{} // Closed when active drops to zero while draining
+	abort    context.Context
+	cancel   context.CancelFunc
+}
+
+// begin registers a request. It returns false once shutdown has started.
+func (t *requestTracker) begin() (context.Context, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.draining {
+		return nil, false
+	}
+	t.init()
+	t.active++
+	return t.abort, true
+}
+
+func (t *requestTracker) end() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.active--
+	if t.draining && t.active == 0 {
+		close(t.idle)
+	}
+}
+
+func (t *requestTracker) init() {
+	if t.abort == nil {
+		t.abort, t.cancel = context.WithCancel(context.Background())
+	}
+}
+
+// drain refuses new requests and waits for the active ones until ctx is done, then
+// cancels them. It returns the number of requests that had to be cancelled.
+func (t *requestTracker) drain(ctx context.Context) int {
+	t.mu.Lock()
+	t.init()
+	if !t.draining {
+		t.draining = true
+		t.idle = make(chan struct{})
+		if t.active == 0 {
+			close(t.idle)
+		}
+	}
+	idle := t.idle
+	t.mu.Unlock()
+
+	select {
+	case <-idle:
+		return 0
+	case <-ctx.Done():
+	}
+
+	t.mu.Lock()
+	cancelled := t.active
+	t.mu.Unlock()
+	t.cancel()
+
+	timer := time.NewTimer(shutdownAbortGrace)
+	defer timer.Stop()
+	select {
+	case <-idle:
+	case <-timer.C:
+	}
+	return cancelled
+}
+
+// middleware tracks every MCP request. Notifications pass through untouched so
+// clients can still cancel their requests while the server drains.
+func (t *requestTracker) middleware(next mcpsdk.MethodHandler) mcpsdk.MethodHandler {
+	return func(ctx context.Context, method string, req mcpsdk.Request) (mcpsdk.Result, error) {
+		if strings.HasPrefix(method, "notifications/") {
+			return next(ctx, method, req)
+		}
+		abort, ok := t.begin()
+		if !ok {
+			return nil, errShuttingDown
+		}
+		defer t.end()
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		stop := context.AfterFunc(abort, cancel)
+		defer stop()
+		return next(ctx, method, req)
+	}
+}
+
+// serveHTTP runs server on listener until serve fails or ctx is done. On
+// cancellation the listener is closed so no new connections are accepted, while
+// open connections keep being served until Shutdown closes them.
+func (s *Server) serveHTTP(ctx context.Context, listener net.Listener, server *http.Server, serve func(net.Listener) error) error {
+	s.mu.Lock()
+	s.httpServers = append(s.httpServers, server)
+	s.mu.Unlock()
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- serve(listener) }()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		s.logger.Info("Stopped accepting connections on %s", listener.Addr())
+		server.SetKeepAlivesEnabled(false)
+		_ = listener.Close()
+		<-errCh
+		return nil
+	}
+}
+
+// Shutdown stops the server gracefully. New MCP requests are refused and in-flight
+// ones get until ctx is done to finish before they are cancelled. Then every MCP
+// session and HTTP connection is closed and the Kapua tokens are invalidated.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.logger.Info("Shutting down, waiting for in-flight requests")
+	if cancelled := s.requests.drain(ctx); cancelled > 0 {
+		s.logger.Warn("Shutdown timeout reached, cancelled %d in-flight request(s)", cancelled)
+	} else {
+		s.logger.Info("All in-flight requests completed")
+	}
+
+	s.mu.Lock()
+	sessions, httpServers := s.sessions, s.httpServers
+	s.mu.Unlock()
+
+	if sessions != nil {
+		sessions.closeAll()
+	}
+	if s.mcpServer != nil {
+		for session := range s.mcpServer.Sessions() {
+			_ = session.Close()
+		}
+	}
+	for _, server := range httpServers {
+		_ = server.Close()
+	}
+	s.subscribed.close()
+	s.fleetIndex.Stop()
+
+	if s.kapuaClient == nil || !s.kapuaClient.IsAuthenticated() {
+		return nil
+	}
+	logoutCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), sessionLogoutTimeout)
+	defer cancel()
+	if err := s.kapuaClient.Logout(logoutCtx); err != nil {
+		return fmt.Errorf("kapua logout failed: %w", err)
+	}
+	return nil
+}
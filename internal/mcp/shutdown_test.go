@@ -0,0 +1,165 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"kapua-mcp-server/internal/kapua/config"
+	"kapua-mcp-server/internal/kapua/services"
+	"kapua-mcp-server/pkg/utils"
+)
+
+type slowToolInput struct{}
+
+// newShutdownTestServer returns a server with a "slow" tool that blocks until
+// release is closed or its context is cancelled, connected to an in-memory client.
+func newShutdownTestServer(t *testing.T) (srv *Server, client *mcpsdk.ClientSession, started, release chan struct{}, logouts *atomic.Int32) {
+	t.Helper()
+	started, release = make(chan struct{}, 1), make(chan struct{})
+	logouts = &atomic.Int32{}
+
+	kapuaClient := services.NewKapuaClient(&config.KapuaConfig{APIEndpoint: "http://kapua.test", Timeout: 5})
+	kapuaClient.SetHTTPClient(&http.Client{Transport: handlerRoundTripper{handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/authentication/logout" {
+			logouts.Add(1)
+		}
+	})}})
+	kapuaClient.SetToken("service-token")
+
+	srv = &Server{
+		logger:      utils.NewDefaultLogger("test"),
+		kapuaClient: kapuaClient,
+		mcpServer:   mcpsdk.NewServer(&mcpsdk.Implementation{Name: "test", Version: "dev"}, nil),
+	}
+	srv.mcpServer.AddReceivingMiddleware(srv.requests.middleware)
+	mcpsdk.AddTool(srv.mcpServer, &mcpsdk.Tool{Name: "slow"}, func(ctx context.Context, _ *mcpsdk.CallToolRequest, _ slowToolInput) (*mcpsdk.CallToolResult, any, error) {
+		started <- struct{}{}
+		select {
+		case <-release:
+			return &mcpsdk.CallToolResult{Content: []mcpsdk.Content{&mcpsdk.TextContent{Text: "done"}}}, nil, nil
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	})
+
+	serverTransport, clientTransport := mcpsdk.NewInMemoryTransports()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go func() { _ = srv.RunTransport(ctx, "test", serverTransport) }()
+
+	client, err := mcpsdk.NewClient(&mcpsdk.Implementation{Name: "client", Version: "dev"}, nil).Connect(context.Background(), clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect failed: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return srv, client, started, release, logouts
+}
+
+func callSlowTool(client *mcpsdk.ClientSession) chan *mcpsdk.CallToolResult {
+	results := make(chan *mcpsdk.CallToolResult, 1)
+	go func() {
+		result, err := client.CallTool(context.Background(), &mcpsdk.CallToolParams{Name: "slow", Arguments: map[string]any{}})
+		if err != nil {
+			result = &mcpsdk.CallToolResult{IsError: true, Content: []mcpsdk.Content{&mcpsdk.TextContent{Text: err.Error()}}}
+		}
+		results <- result
+	}()
+	return results
+}
+
+func waitForDraining(t *testing.T, tracker *requestTracker) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		tracker.mu.Lock()
+		draining := tracker.draining
+		tracker.mu.Unlock()
+		if draining {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("shutdown did not start draining")
+}
+
+func TestShutdownDrainsInFlightRequests(t *testing.T) {
+	srv, client, started, release, logouts := newShutdownTestServer(t)
+
+	results := callSlowTool(client)
+	<-started
+
+	shutdownErr := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdownErr <- srv.Shutdown(ctx)
+	}()
+	waitForDraining(t, &srv.requests)
+
+	if _, err := client.CallTool(context.Background(), &mcpsdk.CallToolParams{Name: "slow", Arguments: map[string]any{}}); err == nil || !strings.Contains(err.Error(), errShuttingDown.Error()) {
+		t.Fatalf("expected new request to be refused, got %v", err)
+	}
+
+	close(release)
+	result := <-results
+	if result.IsError || result.Content[0].(*mcpsdk.TextContent).Text != "done" {
+		t.Fatalf("expected in-flight call to complete, got %+v", result.Content[0])
+	}
+	if err := <-shutdownErr; err != nil {
+		t.Fatalf("Shutdown returned error: %v", err)
+	}
+	if logouts.Load() != 1 {
+		t.Fatalf("expected one Kapua logout, got %d", logouts.Load())
+	}
+}
+
+func TestShutdownCancelsRequestsAfterTimeout(t *testing.T) {
+	srv, client, started, _, logouts := newShutdownTestServer(t)
+
+	results := callSlowTool(client)
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	begin := time.Now()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown returned error: %v", err)
+	}
+	if elapsed := time.Since(begin); elapsed > shutdownAbortGrace {
+		t.Fatalf("shutdown took %v", elapsed)
+	}
+
+	if result := <-results; !result.IsError {
+		t.Fatalf("expected cancelled call to fail, got %+v", result.Content)
+	}
+	if logouts.Load() != 1 {
+		t.Fatalf("expected one Kapua logout, got %d", logouts.Load())
+	}
+}
+
+func TestListenAndServeStopsOnCancel(t *testing.T) {
+	srv := &Server{logger: utils.NewDefaultLogger("test")}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.ListenAndServe(ctx, "127.0.0.1:0", http.NotFoundHandler()) }()
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("ListenAndServe returned error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("ListenAndServe did not return after cancellation")
+	}
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown returned error: %v", err)
+	}
+}
@@ -0,0 +1,228 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"kapua-mcp-server/internal/kapua/handlers"
+	"kapua-mcp-server/internal/kapua/services"
+	"kapua-mcp-server/pkg/utils"
+)
+
+// maxNotifiedChanges bounds the device changes listed in one update notification.
+const maxNotifiedChanges = 100
+
+// subscribableResources are the fleet-level resources clients can subscribe to,
+// with any query. A device change updates all of them.
+var subscribableResources = map[string]bool{
+	"kapua://devices":      true,
+	"kapua://fleet-health": true,
+}
+
+// subscribableDeviceResources are the kapua://devices/{clientId} sub-resources
+// clients can subscribe to; "" is the device itself. They are updated when that
+// device changes.
+var subscribableDeviceResources = map[string]bool{
+	"":       true,
+	"events": true,
+}
+
+// resourceSubscriptions implements resources/subscribe for one MCP server. A
+// FleetWatcher runs while at least one session is subscribed, and each change it
+// reports is sent as notifications/resources/updated for every subscribed URI.
+type resourceSubscriptions struct {
+	watcher *handlers.FleetWatcher
+	logger  *utils.Logger
+
+	runMu    sync.Mutex // Serialises starting and stopping the watcher
+	mu       sync.Mutex
+	server   *mcpsdk.Server
+	sessions map[*mcpsdk.ServerSession]map[string]bool // Subscribed URIs by session
+}
+
+// newResourceSubscriptions returns subscriptions whose changes come from polling
+// through client, or from index when it is set, every interval. It returns nil,
+// which disables subscriptions, when interval is 0.
+func newResourceSubscriptions(client *services.KapuaClient, index *handlers.FleetIndex, interval time.Duration) *resourceSubscriptions {
+	if interval <= 0 {
+		return nil
+	}
+	rs := &resourceSubscriptions{
+		logger:   utils.NewDefaultLogger("Subscriptions"),
+		sessions: map[*mcpsdk.ServerSession]map[string]bool{},
+	}
+	rs.watcher = handlers.NewFleetWatcher(client, index, interval, rs.notify)
+	return rs
+}
+
+// options returns opts with the subscription handlers set.
+func (rs *resourceSubscriptions) options(opts *mcpsdk.ServerOptions) *mcpsdk.ServerOptions {
+	var withHandlers mcpsdk.ServerOptions
+	if opts != nil {
+		withHandlers = *opts
+	}
+	if rs != nil {
+		withHandlers.SubscribeHandler = rs.subscribe
+		withHandlers.UnsubscribeHandler = rs.unsubscribe
+	}
+	return &withHandlers
+}
+
+// attach sets the server that notifications are sent through.
+func (rs *resourceSubscriptions) attach(server *mcpsdk.Server) {
+	if rs == nil {
+		return
+	}
+	rs.mu.Lock()
+	rs.server = server
+	rs.mu.Unlock()
+}
+
+func (rs *resourceSubscriptions) subscribe(ctx context.Context, req *mcpsdk.SubscribeRequest) error {
+	if !subscribable(req.Params.URI) {
+		return fmt.Errorf("resource %s does not support subscriptions", req.Params.URI)
+	}
+
+	rs.mu.Lock()
+	uris, known := rs.sessions[req.Session]
+	if !known {
+		uris = map[string]bool{}
+		rs.sessions[req.Session] = uris
+	}
+	if !uris[req.Params.URI] {
+		uris[req.Params.URI] = true
+		activeSubscriptions.Inc()
+	}
+	rs.mu.Unlock()
+
+	if !known && req.Session != nil {
+		go func() {
+			_ = req.Session.Wait()
+			rs.drop(req.Session)
+		}()
+	}
+	rs.logger.WithContext(ctx).Info("Subscribed to %s", req.Params.URI)
+	rs.sync()
+	return nil
+}
+
+func (rs *resourceSubscriptions) unsubscribe(ctx context.Context, req *mcpsdk.UnsubscribeRequest) error {
+	rs.mu.Lock()
+	if uris := rs.sessions[req.Session]; uris[req.Params.URI] {
+		delete(uris, req.Params.URI)
+		activeSubscriptions.Dec()
+	}
+	rs.mu.Unlock()
+	rs.logger.WithContext(ctx).Info("Unsubscribed from %s", req.Params.URI)
+	rs.sync()
+	return nil
+}
+
+// drop forgets the subscriptions of a session that ended.
+func (rs *resourceSubscriptions) drop(session *mcpsdk.ServerSession) {
+	rs.mu.Lock()
+	activeSubscriptions.Add(-float64(len(rs.sessions[session])))
+	delete(rs.sessions, session)
+	rs.mu.Unlock()
+	rs.sync()
+}
+
+// sync runs the watcher exactly while someone is subscribed.
+func (rs *resourceSubscriptions) sync() {
+	rs.runMu.Lock()
+	defer rs.runMu.Unlock()
+	if len(rs.subscribedURIs()) > 0 {
+		rs.watcher.Start()
+	} else {
+		rs.watcher.Stop()
+	}
+}
+
+// close stops the watcher; used on shutdown.
+func (rs *resourceSubscriptions) close() {
+	if rs == nil {
+		return
+	}
+	rs.runMu.Lock()
+	defer rs.runMu.Unlock()
+	rs.watcher.Stop()
+}
+
+func (rs *resourceSubscriptions) subscribedURIs() map[string]bool {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	all := map[string]bool{}
+	for _, uris := range rs.sessions {
+		for uri := range uris {
+			all[uri] = true
+		}
+	}
+	return all
+}
+
+// notify tells every subscriber that its resource changed. The notification
+// lists the device changes so clients can react without reading the resource.
+func (rs *resourceSubscriptions) notify(ctx context.Context, changes []handlers.DeviceChange) {
+	rs.mu.Lock()
+	server := rs.server
+	rs.mu.Unlock()
+	if server == nil {
+		return
+	}
+
+	for uri := range rs.subscribedURIs() {
+		relevant := changes
+		if clientID, ok := deviceSubscription(uri); ok {
+			relevant = nil
+			for _, change := range changes {
+				if change.ClientID == clientID {
+					relevant = append(relevant, change)
+				}
+			}
+			if len(relevant) == 0 {
+				continue
+			}
+		}
+
+		meta := mcpsdk.Meta{"changes": relevant[:min(len(relevant), maxNotifiedChanges)]}
+		if len(relevant) > maxNotifiedChanges {
+			meta["truncated"] = true
+		}
+		if err := server.ResourceUpdated(ctx, &mcpsdk.ResourceUpdatedNotificationParams{URI: uri, Meta: meta}); err != nil {
+			rs.logger.Warn("Failed to notify subscribers of %s: %v", uri, err)
+		}
+	}
+}
+
+// subscribable reports whether uri names a resource that supports subscriptions.
+func subscribable(uri string) bool {
+	if _, ok := deviceSubscription(uri); ok {
+		return true
+	}
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return false
+	}
+	return subscribableResources[parsed.Scheme+"://"+parsed.Host+parsed.Path]
+}
+
+// deviceSubscription returns the client ID of a subscribable per-device URI such
+// as kapua://devices/gw-1/events.
+func deviceSubscription(uri string) (string, bool) {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Scheme != "kapua" || parsed.Host != "devices" {
+		return "", false
+	}
+	escapedID, sub, _ := strings.Cut(strings.TrimPrefix(parsed.EscapedPath(), "/"), "/")
+	clientID, err := url.PathUnescape(escapedID)
+	if err != nil || clientID == "" || !subscribableDeviceResources[sub] {
+		return "", false
+	}
+	return clientID, true
+}
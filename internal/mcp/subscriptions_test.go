@@ -0,0 +1,154 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"kapua-mcp-server/internal/kapua/config"
+	"kapua-mcp-server/internal/kapua/models"
+	"kapua-mcp-server/internal/kapua/services"
+)
+
+func TestResourceSubscriptionsNotifyOnDeviceChanges(t *testing.T) {
+	var status atomic.Value
+	status.Store(string(models.ConnectionStatusConnected))
+	var polls atomic.Int32
+	client := services.NewKapuaClient(&config.KapuaConfig{APIEndpoint: "http://kapua.test", Timeout: 5})
+	client.SetHTTPClient(&http.Client{Transport: handlerRoundTripper{handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		polls.Add(1)
+		_, _ = fmt.Fprintf(w, `{"type":"deviceListResult","totalCount":1,"items":[{"id":"dev-1","clientId":"gw-1","connection":{"status":%q}}]}`, status.Load())
+	})}})
+	client.SetTokenInfo(&models.AccessToken{KapuaEntity: models.KapuaEntity{ScopeID: "tenant"}, TokenID: "token", ExpiresOn: time.Now().Add(time.Hour)})
+
+	subscribed := newResourceSubscriptions(client, nil, 10*time.Millisecond)
+	defer subscribed.close()
+	server := newSDKServer(client, nil, false, nil, subscribed, nil, nil)
+
+	updates := make(chan *mcpsdk.ResourceUpdatedNotificationParams, 10)
+	mcpClient := mcpsdk.NewClient(&mcpsdk.Implementation{Name: "client", Version: "dev"}, &mcpsdk.ClientOptions{
+		ResourceUpdatedHandler: func(_ context.Context, req *mcpsdk.ResourceUpdatedNotificationRequest) {
+			updates <- req.Params
+		},
+	})
+	serverTransport, clientTransport := mcpsdk.NewInMemoryTransports()
+	serverSession, err := server.Connect(context.Background(), serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server connect failed: %v", err)
+	}
+	defer serverSession.Close()
+	session, err := mcpClient.Connect(context.Background(), clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect failed: %v", err)
+	}
+	defer session.Close()
+
+	if caps := session.InitializeResult().Capabilities; caps.Resources == nil || !caps.Resources.Subscribe {
+		t.Fatalf("expected the subscribe capability, got %+v", caps.Resources)
+	}
+	if err := session.Subscribe(context.Background(), &mcpsdk.SubscribeParams{URI: "kapua://server-info"}); err == nil {
+		t.Fatal("expected kapua://server-info subscriptions to be rejected")
+	}
+	if polls.Load() != 0 {
+		t.Fatal("expected no polling without subscribers")
+	}
+	uri := "kapua://fleet-health?staleMinutes=30"
+	for _, subscription := range []string{uri, "kapua://devices/gw-1", "kapua://devices/gw-2/events"} {
+		if err := session.Subscribe(context.Background(), &mcpsdk.SubscribeParams{URI: subscription}); err != nil {
+			t.Fatalf("Subscribe to %s returned error: %v", subscription, err)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for polls.Load() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("subscription did not start polling")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	status.Store(string(models.ConnectionStatusDisconnected))
+
+	// gw-2 did not change, so only the fleet and gw-1 subscriptions are updated.
+	notified := map[string]bool{}
+	for range 2 {
+		select {
+		case update := <-updates:
+			changes, _ := update.Meta["changes"].([]any)
+			if len(changes) != 1 {
+				t.Fatalf("unexpected notification: %+v", update)
+			}
+			if change := changes[0].(map[string]any); change["deviceId"] != "dev-1" || change["change"] != "disconnected" {
+				t.Fatalf("unexpected change: %v", change)
+			}
+			notified[update.URI] = true
+		case <-time.After(5 * time.Second):
+			t.Fatal("no resource update notification")
+		}
+	}
+	if !notified[uri] || !notified["kapua://devices/gw-1"] {
+		t.Fatalf("unexpected notified URIs: %v", notified)
+	}
+	select {
+	case update := <-updates:
+		t.Fatalf("unexpected notification for %s", update.URI)
+	case <-time.After(30 * time.Millisecond):
+	}
+
+	for _, subscription := range []string{uri, "kapua://devices/gw-1", "kapua://devices/gw-2/events"} {
+		if err := session.Unsubscribe(context.Background(), &mcpsdk.UnsubscribeParams{URI: subscription}); err != nil {
+			t.Fatalf("Unsubscribe returned error: %v", err)
+		}
+	}
+	settled := polls.Load()
+	time.Sleep(30 * time.Millisecond)
+	if polls.Load() != settled {
+		t.Fatal("expected polling to stop after the last unsubscribe")
+	}
+}
+
+func TestResourceSubscriptionsDisabled(t *testing.T) {
+	if newResourceSubscriptions(&services.KapuaClient{}, nil, 0) != nil {
+		t.Fatal("expected a zero interval to disable subscriptions")
+	}
+	server := newSDKServer(&services.KapuaClient{}, nil, false, nil, nil, nil, nil)
+	serverTransport, clientTransport := mcpsdk.NewInMemoryTransports()
+	serverSession, err := server.Connect(context.Background(), serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server connect failed: %v", err)
+	}
+	defer serverSession.Close()
+	session, err := mcpsdk.NewClient(&mcpsdk.Implementation{Name: "client", Version: "dev"}, nil).Connect(context.Background(), clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect failed: %v", err)
+	}
+	defer session.Close()
+	if caps := session.InitializeResult().Capabilities; caps.Resources != nil && caps.Resources.Subscribe {
+		t.Fatal("expected no subscribe capability")
+	}
+}
+
+func TestSubscribable(t *testing.T) {
+	for uri, want := range map[string]bool{
+		"kapua://devices":                        true,
+		"kapua://devices?noCache=true":           true,
+		"kapua://fleet-health?criticalMinutes=5": true,
+		"kapua://devices/gw-1":                   true,
+		"kapua://devices/site%2Fgw-1/events":     true,
+		"kapua://devices/gw-1/inventory":         false,
+		"kapua://devices/gw-1/events/extra":      false,
+		"kapua://server-info":                    false,
+		"kapua://unknown":                        false,
+	} {
+		if got := subscribable(uri); got != want {
+			t.Errorf("subscribable(%s) = %v, want %v", uri, got, want)
+		}
+	}
+	if clientID, _ := deviceSubscription("kapua://devices/site%2Fgw-1/events"); clientID != "site/gw-1" {
+		t.Errorf("unexpected client ID %q", clientID)
+	}
+}
@@ -0,0 +1,201 @@
+package mcp
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"kapua-mcp-server/pkg/utils"
+)
+
+// clientSubjectHeader carries the verified mTLS client certificate subject from the
+// HTTP layer to MCP handlers (via CallToolRequest.Extra.Header). Incoming values are
+// always discarded so clients cannot spoof it.
+const clientSubjectHeader = "X-Mcp-Client-Subject"
+
+// TLSEnabled reports whether the HTTP transport should serve HTTPS.
+func (cfg *HTTPConfig) TLSEnabled() bool {
+	return cfg != nil && cfg.TLSCertFile != ""
+}
+
+// parseTLSMinVersion maps "1.2" / "1.3" to the crypto/tls constants.
+func parseTLSMinVersion(value string) (uint16, error) {
+	switch value {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS minimum version %q: must be 1.2 or 1.3", value)
+	}
+}
+
+func (cfg *HTTPConfig) validateTLS() error {
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		return fmt.Errorf("MCP_TLS_CERT_FILE and MCP_TLS_KEY_FILE must be set together")
+	}
+	if cfg.TLSClientCAFile != "" && cfg.TLSCertFile == "" {
+		return fmt.Errorf("MCP_TLS_CLIENT_CA_FILE requires MCP_TLS_CERT_FILE and MCP_TLS_KEY_FILE")
+	}
+	_, err := parseTLSMinVersion(cfg.TLSMinVersion)
+	return err
+}
+
+// TLSConfig builds the server TLS configuration. Certificate, key and client CA
+// files are reloaded on the next handshake after any of them changes on disk.
+func (cfg *HTTPConfig) TLSConfig() (*tls.Config, error) {
+	if err := cfg.validateTLS(); err != nil {
+		return nil, err
+	}
+	minVersion, _ := parseTLSMinVersion(cfg.TLSMinVersion)
+
+	reloader := &certReloader{
+		certFile: cfg.TLSCertFile,
+		keyFile:  cfg.TLSKeyFile,
+		caFile:   cfg.TLSClientCAFile,
+		logger:   utils.NewDefaultLogger("TLS"),
+	}
+	if err := reloader.reload(); err != nil {
+		return nil, err
+	}
+
+	base := &tls.Config{
+		MinVersion: minVersion,
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+	base.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		return reloader.certificate(), nil
+	}
+	base.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		reloader.maybeReload()
+		config := base.Clone()
+		config.GetConfigForClient = nil
+		if pool := reloader.clientCAs(); pool != nil {
+			config.ClientCAs = pool
+			config.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+		return config, nil
+	}
+	return base, nil
+}
+
+// certReloader keeps the current key pair and client CA pool, reloading them when
+// the files' modification times change.
+type certReloader struct {
+	certFile, keyFile, caFile string
+	logger                    *utils.Logger
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	pool     *x509.CertPool
+	modTimes [3]time.Time
+}
+
+func (r *certReloader) certificate() *tls.Certificate {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert
+}
+
+func (r *certReloader) clientCAs() *x509.CertPool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.pool
+}
+
+func (r *certReloader) currentModTimes() ([3]time.Time, error) {
+	var times [3]time.Time
+	for i, file := range []string{r.certFile, r.keyFile, r.caFile} {
+		if file == "" {
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return times, err
+		}
+		times[i] = info.ModTime()
+	}
+	return times, nil
+}
+
+// maybeReload reloads the files if they changed. Failures keep the previous
+// certificates so a half-written rotation does not take the listener down.
+func (r *certReloader) maybeReload() {
+	times, err := r.currentModTimes()
+	if err != nil {
+		return
+	}
+	r.mu.RLock()
+	changed := times != r.modTimes
+	r.mu.RUnlock()
+	if !changed {
+		return
+	}
+	if err := r.reload(); err != nil {
+		r.logger.Warn("TLS certificate reload failed, keeping previous certificates: %v", err)
+		return
+	}
+	r.logger.Info("Reloaded TLS certificates")
+}
+
+func (r *certReloader) reload() error {
+	times, err := r.currentModTimes()
+	if err != nil {
+		return fmt.Errorf("failed to stat TLS files: %w", err)
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS key pair: %w", err)
+	}
+
+	var pool *x509.CertPool
+	if r.caFile != "" {
+		data, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return fmt.Errorf("client CA file %s contains no PEM certificates", r.caFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.pool = pool
+	r.modTimes = times
+	r.mu.Unlock()
+	return nil
+}
+
+// ListenAndServeTLS serves handler over HTTPS on addr using tlsConfig until ctx is done.
+func (s *Server) ListenAndServeTLS(ctx context.Context, addr string, handler http.Handler, tlsConfig *tls.Config) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	s.logStartup("streamable-http (TLS)", addr)
+	server := &http.Server{Handler: handler, TLSConfig: tlsConfig}
+	return s.serveHTTP(ctx, listener, server, func(l net.Listener) error {
+		return server.ServeTLS(l, "", "")
+	})
+}
+
+// clientCertMiddleware replaces any client-supplied clientSubjectHeader with the
+// subject of the verified mTLS client certificate, if there is one.
+func clientCertMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Del(clientSubjectHeader)
+		if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+			r.Header.Set(clientSubjectHeader, r.TLS.VerifiedChains[0][0].Subject.String())
+		}
+		next.ServeHTTP(w, r)
+	})
+}
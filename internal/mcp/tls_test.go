@@ -0,0 +1,171 @@
+package mcp
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+func issueTestCert(t *testing.T, subject pkix.Name, parent *testCert, isCA bool, usage x509.ExtKeyUsage) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               subject,
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{usage},
+		DNSNames:              []string{"localhost"},
+	}
+	signerCert, signerKey := template, key
+	if parent != nil {
+		signerCert, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signerCert, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+func (c *testCert) writeFiles(t *testing.T, certFile, keyFile string) {
+	t.Helper()
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der})
+	if err := os.WriteFile(certFile, certPEM, 0o600); err != nil {
+		t.Fatalf("failed to write certificate: %v", err)
+	}
+	if keyFile == "" {
+		return
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(c.key)
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.der}, PrivateKey: c.key}
+}
+
+func TestTLSConfigMutualTLSAndReload(t *testing.T) {
+	dir := t.TempDir()
+	ca := issueTestCert(t, pkix.Name{CommonName: "test-ca"}, nil, true, x509.ExtKeyUsageAny)
+	server := issueTestCert(t, pkix.Name{CommonName: "server-v1"}, ca, false, x509.ExtKeyUsageServerAuth)
+	client := issueTestCert(t, pkix.Name{CommonName: "fleet-ops", Organization: []string{"Example"}}, ca, false, x509.ExtKeyUsageClientAuth)
+
+	certFile, keyFile, caFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), filepath.Join(dir, "ca.crt")
+	server.writeFiles(t, certFile, keyFile)
+	ca.writeFiles(t, caFile, "")
+
+	httpCfg := &HTTPConfig{TLSCertFile: certFile, TLSKeyFile: keyFile, TLSClientCAFile: caFile, TLSMinVersion: "1.3"}
+	tlsCfg, err := httpCfg.TLSConfig()
+	if err != nil {
+		t.Fatalf("TLSConfig returned error: %v", err)
+	}
+
+	ts := httptest.NewUnstartedServer(clientCertMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get(clientSubjectHeader)))
+	})))
+	ts.TLS = tlsCfg
+	ts.StartTLS()
+	defer ts.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	get := func(certs ...tls.Certificate) (*http.Response, error) {
+		httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, ServerName: "localhost", Certificates: certs}}}
+		req, _ := http.NewRequest(http.MethodGet, ts.URL, nil)
+		req.Header.Set(clientSubjectHeader, "CN=spoofed")
+		return httpClient.Do(req)
+	}
+
+	resp, err := get(client.tlsCertificate())
+	if err != nil {
+		t.Fatalf("mTLS request failed: %v", err)
+	}
+	body := make([]byte, 256)
+	n, _ := resp.Body.Read(body)
+	resp.Body.Close()
+	if got := string(body[:n]); got != "CN=fleet-ops,O=Example" {
+		t.Fatalf("expected verified subject, got %q", got)
+	}
+	if resp.TLS.Version != tls.VersionTLS13 || resp.TLS.PeerCertificates[0].Subject.CommonName != "server-v1" {
+		t.Fatalf("unexpected TLS state: version %x, server %s", resp.TLS.Version, resp.TLS.PeerCertificates[0].Subject.CommonName)
+	}
+
+	if _, err := get(); err == nil {
+		t.Fatal("expected handshake without client certificate to fail")
+	}
+
+	rotated := issueTestCert(t, pkix.Name{CommonName: "server-v2"}, ca, false, x509.ExtKeyUsageServerAuth)
+	rotated.writeFiles(t, certFile, keyFile)
+	future := time.Now().Add(time.Minute)
+	_ = os.Chtimes(certFile, future, future)
+	_ = os.Chtimes(keyFile, future, future)
+
+	resp, err = get(client.tlsCertificate())
+	if err != nil {
+		t.Fatalf("request after rotation failed: %v", err)
+	}
+	resp.Body.Close()
+	if cn := resp.TLS.PeerCertificates[0].Subject.CommonName; cn != "server-v2" {
+		t.Fatalf("expected reloaded certificate, got %s", cn)
+	}
+}
+
+func TestClientCertMiddlewareStripsSpoofedSubject(t *testing.T) {
+	var subject string
+	handler := clientCertMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		subject = r.Header.Get(clientSubjectHeader)
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set(clientSubjectHeader, "CN=admin")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if subject != "" {
+		t.Fatalf("expected spoofed subject to be removed, got %q", subject)
+	}
+}
+
+func TestTLSConfigValidation(t *testing.T) {
+	cases := map[string]*HTTPConfig{
+		"cert without key": {TLSCertFile: "tls.crt"},
+		"ca without cert":  {TLSClientCAFile: "ca.crt"},
+		"bad min version":  {TLSCertFile: "tls.crt", TLSKeyFile: "tls.key", TLSMinVersion: "1.0"},
+	}
+	for name, cfg := range cases {
+		if _, err := cfg.TLSConfig(); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	t.Setenv("MCP_TLS_MIN_VERSION", "1.1")
+	if _, err := LoadHTTPConfig(); err == nil || !strings.Contains(err.Error(), "unsupported TLS minimum version") {
+		t.Fatalf("expected min version error, got %v", err)
+	}
+}
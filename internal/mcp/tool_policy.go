@@ -0,0 +1,119 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"kapua-mcp-server/pkg/utils"
+)
+
+// toolPolicy maps mTLS client certificate subjects to the tools they may call.
+// Patterns use path.Match syntax, e.g. "kapua-*-list". Clients without a matching
+// subject (or without a certificate) get the Default list.
+//
+//	{
+//	  "subjects": {
+//	    "CN=fleet-ops,O=Example": ["*"],
+//	    "CN=dashboard": ["kapua-devices-list", "kapua-*-list"]
+//	  },
+//	  "default": []
+//	}
+type toolPolicy struct {
+	Subjects map[string][]string `json:"subjects"`
+	Default  []string            `json:"default"`
+}
+
+// loadToolPolicy reads and validates a tool policy file.
+func loadToolPolicy(file string) (*toolPolicy, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tool policy: %w", err)
+	}
+	var policy toolPolicy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("invalid tool policy %s: %w", file, err)
+	}
+	for subject, patterns := range policy.Subjects {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid tool pattern %q for subject %q: %w", pattern, subject, err)
+			}
+		}
+	}
+	for _, pattern := range policy.Default {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid default tool pattern %q: %w", pattern, err)
+		}
+	}
+	return &policy, nil
+}
+
+// patternsFor returns the tool patterns granted to subject. A policy key matches
+// either the full subject ("CN=a,O=b") or just its common name ("CN=a").
+func (p *toolPolicy) patternsFor(subject string) []string {
+	if subject == "" {
+		return p.Default
+	}
+	if patterns, ok := p.Subjects[subject]; ok {
+		return patterns
+	}
+	for _, rdn := range strings.Split(subject, ",") {
+		if strings.HasPrefix(rdn, "CN=") {
+			if patterns, ok := p.Subjects[rdn]; ok {
+				return patterns
+			}
+		}
+	}
+	return p.Default
+}
+
+// allows reports whether subject may call tool.
+func (p *toolPolicy) allows(subject, tool string) bool {
+	for _, pattern := range p.patternsFor(subject) {
+		if ok, _ := path.Match(pattern, tool); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// middleware filters tools/list and rejects tools/call for tools outside the
+// caller's grant. The subject comes from clientSubjectHeader, set by
+// clientCertMiddleware from the verified client certificate.
+func (p *toolPolicy) middleware(logger *utils.Logger) mcpsdk.Middleware {
+	return func(next mcpsdk.MethodHandler) mcpsdk.MethodHandler {
+		return func(ctx context.Context, method string, req mcpsdk.Request) (mcpsdk.Result, error) {
+			subject := ""
+			if extra := req.GetExtra(); extra != nil && extra.Header != nil {
+				subject = extra.Header.Get(clientSubjectHeader)
+			}
+
+			switch r := req.(type) {
+			case *mcpsdk.CallToolRequest:
+				if r.Params != nil && !p.allows(subject, r.Params.Name) {
+					logger.Warn("Tool %q denied for client %q by tool policy", r.Params.Name, subject)
+					return nil, fmt.Errorf("tool %q is not allowed for this client", r.Params.Name)
+				}
+			case *mcpsdk.ListToolsRequest:
+				result, err := next(ctx, method, req)
+				if list, ok := result.(*mcpsdk.ListToolsResult); ok && err == nil {
+					allowed := list.Tools[:0:0]
+					for _, tool := range list.Tools {
+						if p.allows(subject, tool.Name) {
+							allowed = append(allowed, tool)
+						}
+					}
+					list.Tools = allowed
+				}
+				return result, err
+			}
+			return next(ctx, method, req)
+		}
+	}
+}
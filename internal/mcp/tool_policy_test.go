@@ -0,0 +1,90 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"kapua-mcp-server/pkg/utils"
+)
+
+func writeToolPolicy(t *testing.T, content string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write policy: %v", err)
+	}
+	return file
+}
+
+func TestToolPolicyAllows(t *testing.T) {
+	policy, err := loadToolPolicy(writeToolPolicy(t, `{
+		"subjects": {
+			"CN=fleet-ops,O=Example": ["*"],
+			"CN=dashboard": ["kapua-devices-list", "kapua-*-list"]
+		},
+		"default": ["kapua-devices-list"]
+	}`))
+	if err != nil {
+		t.Fatalf("loadToolPolicy returned error: %v", err)
+	}
+
+	cases := []struct {
+		subject, tool string
+		allowed       bool
+	}{
+		{"CN=fleet-ops,O=Example", "kapua-device-command-execute", true},
+		{"CN=dashboard,O=Other", "kapua-device-events-list", true},
+		{"CN=dashboard,O=Other", "kapua-device-command-execute", false},
+		{"CN=unknown", "kapua-devices-list", true},
+		{"CN=unknown", "kapua-device-events-list", false},
+		{"", "kapua-devices-list", true},
+	}
+	for _, tc := range cases {
+		if got := policy.allows(tc.subject, tc.tool); got != tc.allowed {
+			t.Errorf("allows(%q, %q) = %v, want %v", tc.subject, tc.tool, got, tc.allowed)
+		}
+	}
+}
+
+func TestLoadToolPolicyInvalid(t *testing.T) {
+	if _, err := loadToolPolicy(writeToolPolicy(t, `{"subjects":{"CN=a":["["]}}`)); err == nil {
+		t.Fatal("expected invalid pattern error")
+	}
+	if _, err := loadToolPolicy(writeToolPolicy(t, `not json`)); err == nil {
+		t.Fatal("expected invalid JSON error")
+	}
+}
+
+func TestToolPolicyMiddleware(t *testing.T) {
+	policy := &toolPolicy{Subjects: map[string][]string{"CN=dashboard": {"kapua-*-list"}}}
+	next := func(ctx context.Context, method string, req mcpsdk.Request) (mcpsdk.Result, error) {
+		if method == "tools/list" {
+			return &mcpsdk.ListToolsResult{Tools: []*mcpsdk.Tool{{Name: "kapua-devices-list"}, {Name: "kapua-device-command-execute"}}}, nil
+		}
+		return &mcpsdk.CallToolResult{}, nil
+	}
+	handler := policy.middleware(utils.NewDefaultLogger("test"))(next)
+	extra := &mcpsdk.RequestExtra{Header: http.Header{clientSubjectHeader: {"CN=dashboard,O=Example"}}}
+
+	result, err := handler(context.Background(), "tools/list", &mcpsdk.ListToolsRequest{Params: &mcpsdk.ListToolsParams{}, Extra: extra})
+	if err != nil {
+		t.Fatalf("tools/list returned error: %v", err)
+	}
+	if tools := result.(*mcpsdk.ListToolsResult).Tools; len(tools) != 1 || tools[0].Name != "kapua-devices-list" {
+		t.Fatalf("expected filtered tool list, got %+v", tools)
+	}
+
+	if _, err := handler(context.Background(), "tools/call", &mcpsdk.CallToolRequest{Params: &mcpsdk.CallToolParamsRaw{Name: "kapua-devices-list"}, Extra: extra}); err != nil {
+		t.Fatalf("expected allowed tool call, got %v", err)
+	}
+	_, err = handler(context.Background(), "tools/call", &mcpsdk.CallToolRequest{Params: &mcpsdk.CallToolParamsRaw{Name: "kapua-device-command-execute"}, Extra: extra})
+	if err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Fatalf("expected denied tool call, got %v", err)
+	}
+}
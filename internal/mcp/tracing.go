@@ -0,0 +1,71 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"kapua-mcp-server/pkg/tracing"
+)
+
+// traceparentKey is the W3C trace context header, also accepted in params._meta.
+const traceparentKey = "traceparent"
+
+// tracingMiddleware wraps tool calls and resource reads in server spans. The parent
+// comes from the traceparent HTTP header or, for stdio clients, from
+// params._meta.traceparent.
+func tracingMiddleware(next mcpsdk.MethodHandler) mcpsdk.MethodHandler {
+	return func(ctx context.Context, method string, req mcpsdk.Request) (mcpsdk.Result, error) {
+		var name string
+		var attrs []attribute.KeyValue
+		switch r := req.(type) {
+		case *mcpsdk.CallToolRequest:
+			if r.Params == nil {
+				return next(ctx, method, req)
+			}
+			name = "tools/call " + r.Params.Name
+			attrs = append(attrs, attribute.String("mcp.tool.name", r.Params.Name))
+		case *mcpsdk.ReadResourceRequest:
+			if r.Params == nil {
+				return next(ctx, method, req)
+			}
+			name = "resources/read"
+			attrs = append(attrs, attribute.String("mcp.resource.uri", r.Params.URI))
+		default:
+			return next(ctx, method, req)
+		}
+
+		ctx = remoteParent(ctx, req)
+		if session := req.GetSession(); session != nil && session.ID() != "" {
+			attrs = append(attrs, attribute.String("mcp.session.id", session.ID()))
+		}
+		attrs = append(attrs, attribute.String("mcp.method.name", method))
+		ctx, span := tracing.Tracer().Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+		defer span.End()
+
+		result, err := next(ctx, method, req)
+		tracing.RecordError(span, err)
+		if toolResult, ok := result.(*mcpsdk.CallToolResult); ok && toolResult.IsError {
+			span.SetStatus(codes.Error, "tool returned an error result")
+		}
+		return result, err
+	}
+}
+
+// remoteParent attaches the caller's trace context from the HTTP headers or _meta.
+func remoteParent(ctx context.Context, req mcpsdk.Request) context.Context {
+	if extra := req.GetExtra(); extra != nil && extra.Header.Get(traceparentKey) != "" {
+		return tracing.Propagator.Extract(ctx, propagation.HeaderCarrier(extra.Header))
+	}
+	if params := req.GetParams(); params != nil {
+		if value, ok := params.GetMeta()[traceparentKey].(string); ok {
+			return tracing.Propagator.Extract(ctx, propagation.HeaderCarrier(http.Header{"Traceparent": {value}}))
+		}
+	}
+	return ctx
+}
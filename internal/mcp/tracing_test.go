@@ -0,0 +1,70 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+
+	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestTracingMiddlewareUsesIncomingTraceparent(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	server := mcpsdk.NewServer(&mcpsdk.Implementation{Name: "test", Version: "dev"}, nil)
+	server.AddReceivingMiddleware(tracingMiddleware)
+	var toolSpan trace.SpanContext
+	mcpsdk.AddTool(server, &mcpsdk.Tool{Name: "traced"}, func(ctx context.Context, _ *mcpsdk.CallToolRequest, _ emptyToolInput) (*mcpsdk.CallToolResult, any, error) {
+		toolSpan = trace.SpanContextFromContext(ctx)
+		return &mcpsdk.CallToolResult{IsError: true, Content: []mcpsdk.Content{&mcpsdk.TextContent{Text: "failed"}}}, nil, nil
+	})
+
+	serverTransport, clientTransport := mcpsdk.NewInMemoryTransports()
+	serverSession, err := server.Connect(context.Background(), serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server connect failed: %v", err)
+	}
+	defer serverSession.Close()
+	client, err := mcpsdk.NewClient(&mcpsdk.Implementation{Name: "client", Version: "dev"}, nil).Connect(context.Background(), clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect failed: %v", err)
+	}
+	defer client.Close()
+
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	_, err = client.CallTool(context.Background(), &mcpsdk.CallToolParams{
+		Meta:      mcpsdk.Meta{"traceparent": traceparent},
+		Name:      "traced",
+		Arguments: map[string]any{},
+	})
+	if err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected one span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "tools/call traced" || span.SpanKind != trace.SpanKindServer || span.Status.Code != codes.Error {
+		t.Fatalf("unexpected tool span: %+v", span)
+	}
+	if span.SpanContext.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" || span.Parent.SpanID().String() != "00f067aa0ba902b7" {
+		t.Fatalf("tool span not parented by the incoming traceparent: %+v", span)
+	}
+	if span.SpanContext.SpanID() != toolSpan.SpanID() {
+		t.Fatalf("tool handler did not run inside the tool span")
+	}
+	attrs := attribute.NewSet(span.Attributes...)
+	if value, _ := attrs.Value("mcp.tool.name"); value.AsString() != "traced" {
+		t.Fatalf("unexpected attributes: %v", span.Attributes)
+	}
+}
@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistryWriteText(t *testing.T) {
+	r := NewRegistry()
+	calls := r.NewCounterVec("tool_calls_total", "Tool calls.", "tool")
+	sessions := r.NewGauge("active_sessions", "Open sessions.")
+	latency := r.NewHistogramVec("latency_seconds", "Latency.", []float64{0.5, 0.1}, "endpoint")
+
+	calls.Inc("list")
+	calls.Add(2, `quote"and\newline`+"\n")
+	sessions.Inc()
+	sessions.Inc()
+	sessions.Dec()
+	latency.Observe(0.05, "/devices")
+	latency.Observe(0.5, "/devices")
+	latency.Observe(3, "/devices")
+
+	var out strings.Builder
+	if err := r.WriteText(&out); err != nil {
+		t.Fatalf("WriteText returned error: %v", err)
+	}
+
+	want := `# HELP active_sessions Open sessions.
+# TYPE active_sessions gauge
+active_sessions 1
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{endpoint="/devices",le="0.1"} 1
+latency_seconds_bucket{endpoint="/devices",le="0.5"} 2
+latency_seconds_bucket{endpoint="/devices",le="+Inf"} 3
+latency_seconds_sum{endpoint="/devices"} 3.55
+latency_seconds_count{endpoint="/devices"} 3
+# HELP tool_calls_total Tool calls.
+# TYPE tool_calls_total counter
+tool_calls_total{tool="list"} 1
+tool_calls_total{tool="quote\"and\\newline\n"} 2
+`
+	if out.String() != want {
+		t.Fatalf("unexpected exposition:\n%s\nwant:\n%s", out.String(), want)
+	}
+	if calls.Value("list") != 1 || latency.Count("/devices") != 3 {
+		t.Fatalf("unexpected accessor values")
+	}
+
+	sessions.Set(7)
+	if sessions.Value() != 7 {
+		t.Fatalf("expected gauge to be set to 7, got %v", sessions.Value())
+	}
+}
+
+func TestRegistryPanics(t *testing.T) {
+	r := NewRegistry()
+	counter := r.NewCounterVec("dup_total", "Dup.", "a")
+
+	for name, fn := range map[string]func(){
+		"duplicate name": func() { r.NewGauge("dup_total", "Dup.") },
+		"label mismatch": func() { counter.Inc() },
+		"negative add":   func() { counter.Add(-1, "x") },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected panic", name)
+				}
+			}()
+			fn()
+		}()
+	}
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.NewGauge("up", "Up.").Inc()
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("unexpected response %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rec.Body.String(), "up 1\n") {
+		t.Fatalf("unexpected body: %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/metrics", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,93 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// defaultServiceName is reported as service.name unless OTEL_SERVICE_NAME is set.
+const defaultServiceName = "kapua-mcp-server"
+
+// SetupFromEnv installs a global provider configured by the standard OTEL_*
+// variables and returns a function that flushes and stops it:
+//
+//   - OTEL_TRACES_EXPORTER: "none" (default), "otlp", "console" (stderr) or "file"
+//   - OTEL_EXPORTER_OTLP_PROTOCOL or OTEL_EXPORTER_OTLP_TRACES_PROTOCOL:
+//     "http/protobuf" (default) or "grpc"
+//   - MCP_TRACES_FILE: JSON lines output for the "file" exporter
+//
+// The OTLP exporters read their endpoint, headers, timeout and TLS settings
+// from the OTEL_EXPORTER_OTLP_* variables themselves, and the resource honours
+// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES.
+func SetupFromEnv() (func(context.Context) error, error) {
+	ctx := context.Background()
+	exporter, err := exporterFromEnv(ctx)
+	if err != nil || exporter == nil {
+		return func(context.Context) error { return nil }, err
+	}
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", defaultServiceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK())
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+func exporterFromEnv(ctx context.Context) (sdktrace.SpanExporter, error) {
+	switch kind := envValue("OTEL_TRACES_EXPORTER"); kind {
+	case "", "none":
+		return nil, nil
+	case "console":
+		return jsonLinesExporter(os.Stderr)
+	case "file":
+		path := strings.TrimSpace(os.Getenv("MCP_TRACES_FILE"))
+		if path == "" {
+			return nil, fmt.Errorf("MCP_TRACES_FILE is required when OTEL_TRACES_EXPORTER=file")
+		}
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open traces file: %w", err)
+		}
+		return jsonLinesExporter(file)
+	case "otlp":
+		protocol := envValue("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL")
+		if protocol == "" {
+			protocol = envValue("OTEL_EXPORTER_OTLP_PROTOCOL")
+		}
+		switch protocol {
+		case "", "http/protobuf":
+			return otlptracehttp.New(ctx)
+		case "grpc":
+			return otlptracegrpc.New(ctx)
+		default:
+			return nil, fmt.Errorf("unsupported OTLP protocol %q: must be http/protobuf or grpc", protocol)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported OTEL_TRACES_EXPORTER %q: must be none, otlp, console or file", kind)
+	}
+}
+
+// jsonLinesExporter writes every span to w as one JSON object per line.
+func jsonLinesExporter(w io.Writer) (sdktrace.SpanExporter, error) {
+	return stdouttrace.New(stdouttrace.WithWriter(w))
+}
+
+func envValue(key string) string {
+	return strings.ToLower(strings.TrimSpace(os.Getenv(key)))
+}
@@ -0,0 +1,33 @@
+// Package tracing sets up OpenTelemetry tracing from the standard OTEL_*
+// environment variables. Code records spans with the OpenTelemetry API through
+// Tracer, and propagates trace context in the W3C traceparent format.
+package tracing
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName names the tracer that records the server's spans.
+const instrumentationName = "kapua-mcp-server"
+
+// Propagator reads and writes the W3C traceparent header. It works whether or
+// not tracing is set up, so the caller's trace context reaches Kapua either way.
+var Propagator propagation.TextMapPropagator = propagation.TraceContext{}
+
+// Tracer returns the tracer of the global provider. Its spans record nothing
+// until SetupFromEnv installs a provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// RecordError marks span as failed with err. A nil err is ignored.
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,167 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// restoreProvider puts the no-op provider back after a test installs one.
+func restoreProvider(t *testing.T) {
+	t.Helper()
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+}
+
+func TestSetupFromEnvDisabled(t *testing.T) {
+	restoreProvider(t)
+	t.Setenv("OTEL_TRACES_EXPORTER", "none")
+	shutdown, err := SetupFromEnv()
+	if err != nil {
+		t.Fatalf("SetupFromEnv returned error: %v", err)
+	}
+	defer shutdown(context.Background())
+
+	_, span := Tracer().Start(context.Background(), "noop")
+	if span.IsRecording() {
+		t.Fatal("expected spans to record nothing without an exporter")
+	}
+}
+
+func TestSetupFromEnvRejectsUnsupportedSettings(t *testing.T) {
+	for name, env := range map[string]map[string]string{
+		"exporter":        {"OTEL_TRACES_EXPORTER": "zipkin"},
+		"protocol":        {"OTEL_TRACES_EXPORTER": "otlp", "OTEL_EXPORTER_OTLP_PROTOCOL": "http/json"},
+		"missing file":    {"OTEL_TRACES_EXPORTER": "file", "MCP_TRACES_FILE": ""},
+		"traces protocol": {"OTEL_TRACES_EXPORTER": "otlp", "OTEL_EXPORTER_OTLP_TRACES_PROTOCOL": "thrift"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			for key, value := range env {
+				t.Setenv(key, value)
+			}
+			if _, err := SetupFromEnv(); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestSetupFromEnvFileExporter(t *testing.T) {
+	restoreProvider(t)
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	t.Setenv("OTEL_TRACES_EXPORTER", "file")
+	t.Setenv("MCP_TRACES_FILE", path)
+	t.Setenv("OTEL_SERVICE_NAME", "kapua-test")
+	shutdown, err := SetupFromEnv()
+	if err != nil {
+		t.Fatalf("SetupFromEnv returned error: %v", err)
+	}
+
+	_, span := Tracer().Start(context.Background(), "GET /{scopeId}/devices")
+	RecordError(span, errors.New("HTTP 500"))
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown returned error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read traces file: %v", err)
+	}
+	var line struct {
+		Name     string
+		Status   struct{ Code, Description string }
+		Resource []struct {
+			Key   string
+			Value struct{ Value any }
+		}
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(string(data))), &line); err != nil {
+		t.Fatalf("expected one JSON line, got %q: %v", data, err)
+	}
+	if line.Name != "GET /{scopeId}/devices" || line.Status.Code != "Error" || line.Status.Description != "HTTP 500" {
+		t.Fatalf("unexpected span: %+v", line)
+	}
+	service := ""
+	for _, attr := range line.Resource {
+		if attr.Key == "service.name" {
+			service, _ = attr.Value.Value.(string)
+		}
+	}
+	if service != "kapua-test" {
+		t.Fatalf("expected service.name from OTEL_SERVICE_NAME, got %q", service)
+	}
+}
+
+func TestSetupFromEnvOTLP(t *testing.T) {
+	restoreProvider(t)
+	received := make(chan *http.Request, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		select {
+		case received <- r:
+		default:
+		}
+	}))
+	defer collector.Close()
+
+	t.Setenv("OTEL_TRACES_EXPORTER", "otlp")
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", collector.URL)
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "Authorization=Bearer%20secret")
+	shutdown, err := SetupFromEnv()
+	if err != nil {
+		t.Fatalf("SetupFromEnv returned error: %v", err)
+	}
+	_, span := Tracer().Start(context.Background(), "tools/call kapua-devices-list")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown returned error: %v", err)
+	}
+
+	select {
+	case r := <-received:
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/x-protobuf" || r.Header.Get("Authorization") != "Bearer secret" {
+			t.Fatalf("unexpected OTLP request %s %v", r.URL.Path, r.Header)
+		}
+	default:
+		t.Fatal("expected spans to be exported to the collector")
+	}
+
+	// gRPC connects lazily, so choosing it succeeds without a collector.
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "grpc")
+	exporter, err := exporterFromEnv(context.Background())
+	if err != nil || exporter == nil {
+		t.Fatalf("expected a gRPC exporter, got %v", err)
+	}
+	_ = exporter.Shutdown(context.Background())
+}
+
+func TestRecordError(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer provider.Shutdown(context.Background())
+
+	_, ok := provider.Tracer("test").Start(context.Background(), "ok")
+	RecordError(ok, nil)
+	ok.End()
+	_, failed := provider.Tracer("test").Start(context.Background(), "failed")
+	RecordError(failed, context.Canceled)
+	failed.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 || spans[0].Status.Code != codes.Unset || spans[1].Status.Code != codes.Error || len(spans[1].Events) != 1 {
+		t.Fatalf("unexpected spans: %+v", spans)
+	}
+}
@@ -1,8 +1,10 @@
 package utils
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
 	"strings"
 )
@@ -17,67 +19,139 @@ const (
 	LogLevelError
 )
 
-// Logger provides structured logging capabilities
+// slogLevel maps the level onto log/slog.
+func (l LogLevel) slogLevel() slog.Level {
+	switch l {
+	case LogLevelDebug:
+		return slog.LevelDebug
+	case LogLevelWarn:
+		return slog.LevelWarn
+	case LogLevelError:
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// Logger provides structured logging on top of log/slog. Messages are still
+// printf-style; use With or WithContext to attach fields. Secrets are redacted
+// from both messages and field values before they are written.
 type Logger struct {
 	level  LogLevel
 	prefix string
-	logger *log.Logger
+	logger *slog.Logger
 }
 
-// NewLogger creates a new logger instance
+// NewLogger creates a new logger instance writing to stderr in the LOG_FORMAT format
 func NewLogger(prefix string, level LogLevel) *Logger {
-	return &Logger{
-		level:  level,
-		prefix: prefix,
-		logger: log.New(os.Stderr, "", log.LstdFlags),
+	return newLogger(os.Stderr, prefix, level, os.Getenv("LOG_FORMAT"))
+}
+
+func newLogger(w io.Writer, prefix string, level LogLevel, format string) *Logger {
+	logger := slog.New(NewHandler(w, level, format))
+	if prefix != "" {
+		logger = logger.With("component", prefix)
 	}
+	return &Logger{level: level, prefix: prefix, logger: logger}
 }
 
 // NewDefaultLogger creates a logger with default settings
 func NewDefaultLogger(prefix string) *Logger {
-	level := LogLevelInfo
+	return NewLogger(prefix, levelFromEnv())
+}
+
+// NewHandler returns the slog handler used by every Logger: JSON when format is
+// "json", logfmt-style text otherwise, with secret redaction applied.
+func NewHandler(w io.Writer, level LogLevel, format string) slog.Handler {
+	opts := &slog.HandlerOptions{Level: level.slogLevel()}
+	var handler slog.Handler
+	if strings.EqualFold(strings.TrimSpace(format), "json") {
+		handler = slog.NewJSONHandler(w, opts)
+	} else {
+		handler = slog.NewTextHandler(w, opts)
+	}
+	return &redactingHandler{next: handler}
+}
+
+// SetupDefaultLogger routes slog's default logger, and with it the standard log
+// package, through the configured handler so every line shares one format.
+func SetupDefaultLogger() {
+	slog.SetDefault(slog.New(NewHandler(os.Stderr, levelFromEnv(), os.Getenv("LOG_FORMAT"))))
+}
+
+func levelFromEnv() LogLevel {
 	if levelStr := os.Getenv("LOG_LEVEL"); levelStr != "" {
-		level = parseLogLevel(levelStr)
+		return parseLogLevel(levelStr)
+	}
+	return LogLevelInfo
+}
+
+// With returns a logger that adds the given key/value pairs to every entry.
+func (l *Logger) With(args ...any) *Logger {
+	return &Logger{level: l.level, prefix: l.prefix, logger: l.logger.With(args...)}
+}
+
+// WithContext returns a logger carrying the fields attached to ctx with
+// ContextWithLogFields, such as the tool name, session ID and trace ID.
+func (l *Logger) WithContext(ctx context.Context) *Logger {
+	fields := LogFieldsFromContext(ctx)
+	if len(fields) == 0 {
+		return l
 	}
-	return NewLogger(prefix, level)
+	return l.With(fields...)
 }
 
 // Debug logs a debug message
 func (l *Logger) Debug(format string, args ...interface{}) {
 	if l.level <= LogLevelDebug {
-		l.log("DEBUG", format, args...)
+		l.log(slog.LevelDebug, format, args...)
 	}
 }
 
 // Info logs an info message
 func (l *Logger) Info(format string, args ...interface{}) {
 	if l.level <= LogLevelInfo {
-		l.log("INFO", format, args...)
+		l.log(slog.LevelInfo, format, args...)
 	}
 }
 
 // Warn logs a warning message
 func (l *Logger) Warn(format string, args ...interface{}) {
 	if l.level <= LogLevelWarn {
-		l.log("WARN", format, args...)
+		l.log(slog.LevelWarn, format, args...)
 	}
 }
 
 // Error logs an error message
 func (l *Logger) Error(format string, args ...interface{}) {
 	if l.level <= LogLevelError {
-		l.log("ERROR", format, args...)
+		l.log(slog.LevelError, format, args...)
 	}
 }
 
 // log performs the actual logging
-func (l *Logger) log(level, format string, args ...interface{}) {
-	message := fmt.Sprintf(format, args...)
-	if l.prefix != "" {
-		l.logger.Printf("[%s] [%s] %s", level, l.prefix, message)
-	} else {
-		l.logger.Printf("[%s] %s", level, message)
+func (l *Logger) log(level slog.Level, format string, args ...interface{}) {
+	l.logger.Log(context.Background(), level, fmt.Sprintf(format, args...))
+}
+
+type logFieldsKey struct{}
+
+// ContextWithLogFields returns a context whose loggers (see Logger.WithContext)
+// add the given key/value pairs. Fields already on ctx are kept.
+func ContextWithLogFields(ctx context.Context, args ...any) context.Context {
+	if len(args) == 0 {
+		return ctx
 	}
+	existing := LogFieldsFromContext(ctx)
+	fields := make([]any, 0, len(existing)+len(args))
+	fields = append(append(fields, existing...), args...)
+	return context.WithValue(ctx, logFieldsKey{}, fields)
+}
+
+// LogFieldsFromContext returns the key/value pairs attached to ctx.
+func LogFieldsFromContext(ctx context.Context) []any {
+	fields, _ := ctx.Value(logFieldsKey{}).([]any)
+	return fields
 }
 
 // parseLogLevel converts a string to LogLevel
@@ -1,6 +1,9 @@
 package utils
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"io"
 	"os"
@@ -72,3 +75,40 @@ func TestNewDefaultLoggerEnv(t *testing.T) {
 		t.Errorf("expected level %v, got %v", LogLevelError, logger.level)
 	}
 }
+
+func TestLoggerJSONFormat(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newLogger(&buf, "Kapua", LogLevelDebug, "json")
+	ctx := ContextWithLogFields(context.Background(), "tool", "kapua-device-get", "session_id", "s-1")
+	ctx = ContextWithLogFields(ctx, "device_id", "dev-1")
+	logger.WithContext(ctx).Debug("Getting device %s", "dev-1")
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("expected a JSON line, got %q: %v", buf.String(), err)
+	}
+	want := map[string]any{
+		"level":      "DEBUG",
+		"msg":        "Getting device dev-1",
+		"component":  "Kapua",
+		"tool":       "kapua-device-get",
+		"session_id": "s-1",
+		"device_id":  "dev-1",
+	}
+	for key, value := range want {
+		if entry[key] != value {
+			t.Errorf("expected %s=%v, got %v", key, value, entry[key])
+		}
+	}
+}
+
+func TestLoggerTextFormatByDefault(t *testing.T) {
+	var buf bytes.Buffer
+	newLogger(&buf, "MCPServer", LogLevelInfo, "").With("attempt", 2).Warn("retrying")
+	out := buf.String()
+	for _, want := range []string{"level=WARN", "msg=retrying", "component=MCPServer", "attempt=2"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in %q", want, out)
+		}
+	}
+}
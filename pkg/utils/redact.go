@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces secret values in log output.
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys are field names whose values never reach the log, compared
+// case-insensitively and ignoring '_' and '-'.
+var sensitiveKeys = map[string]bool{
+	"password":           true,
+	"apikey":             true,
+	"tokenid":            true,
+	"refreshtoken":       true,
+	"authorization":      true,
+	"trustkey":           true,
+	"jwt":                true,
+	"authenticationcode": true,
+}
+
+var (
+	// sensitivePair matches key/value pairs in JSON ("password":"x"), Go's %v
+	// rendering of maps (password:x) and query or header syntax (apiKey=x).
+	sensitivePair = regexp.MustCompile(`(?i)("?(?:password|api_?key|token_?id|refresh_?token|authorization|trust_?key|jwt|authentication_?code)"?\s*[:=]\s*)("(?:[^"\\]|\\.)*"|(?:bearer\s+)?[^\s,;&}\]]+)`)
+	bearerToken   = regexp.MustCompile(`(?i)\b(bearer\s+)[A-Za-z0-9\-._~+/]+=*`)
+)
+
+// RedactString masks credential values embedded in s, such as request bodies
+// sent to /authentication/user or Authorization headers.
+func RedactString(s string) string {
+	s = sensitivePair.ReplaceAllStringFunc(s, func(match string) string {
+		parts := sensitivePair.FindStringSubmatch(match)
+		if strings.HasPrefix(parts[2], "[REDACTED") {
+			return match
+		}
+		if strings.HasPrefix(parts[2], `"`) {
+			return parts[1] + `"` + Redacted + `"`
+		}
+		return parts[1] + Redacted
+	})
+	return bearerToken.ReplaceAllString(s, "${1}"+Redacted)
+}
+
+func isSensitiveKey(key string) bool {
+	key = strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(key))
+	return sensitiveKeys[key]
+}
+
+// redactingHandler masks secrets in the message and in attribute values.
+type redactingHandler struct {
+	next slog.Handler
+}
+
+func (h *redactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *redactingHandler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, RedactString(record.Message), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(redactAttr(attr))
+		return true
+	})
+	return h.next.Handle(ctx, redacted)
+}
+
+func (h *redactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redacted[i] = redactAttr(attr)
+	}
+	return &redactingHandler{next: h.next.WithAttrs(redacted)}
+}
+
+func (h *redactingHandler) WithGroup(name string) slog.Handler {
+	return &redactingHandler{next: h.next.WithGroup(name)}
+}
+
+func redactAttr(attr slog.Attr) slog.Attr {
+	if isSensitiveKey(attr.Key) {
+		return slog.String(attr.Key, Redacted)
+	}
+	value := attr.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, RedactString(value.String()))
+	case slog.KindGroup:
+		group := value.Group()
+		redacted := make([]any, len(group))
+		for i, member := range group {
+			redacted[i] = redactAttr(member)
+		}
+		return slog.Group(attr.Key, redacted...)
+	case slog.KindAny:
+		return slog.String(attr.Key, RedactString(value.String()))
+	default:
+		return slog.Attr{Key: attr.Key, Value: value}
+	}
+}
@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRedactString(t *testing.T) {
+	cases := map[string]string{
+		`Request body: {"username":"kapua-sys","password":"kapua-password"}`:                 `Request body: {"username":"kapua-sys","password":"[REDACTED]"}`,
+		`Request body: {"apiKey":"12345678"}`:                                                `Request body: {"apiKey":"[REDACTED]"}`,
+		`Response body: {"tokenId":"eyJ.a.b","refreshToken":"r-1","expiresOn":"2024-01-01"}`: `Response body: {"tokenId":"[REDACTED]","refreshToken":"[REDACTED]","expiresOn":"2024-01-01"}`,
+		`{"escaped":"x","password":"with \"quote\""}`:                                        `{"escaped":"x","password":"[REDACTED]"}`,
+		`headers map[Authorization:Bearer abc.def]`:                                          `headers map[Authorization:[REDACTED]]`,
+		`Authorization: Bearer abc.def`:                                                      `Authorization: [REDACTED]`,
+		`sending bearer abc.def upstream`:                                                    `sending bearer [REDACTED] upstream`,
+		`GET /devices?api_key=secret&limit=5`:                                                `GET /devices?api_key=[REDACTED]&limit=5`,
+		`Request body: {"jwt":"eyJhbGciOiJSUzI1NiJ9.e30.sig"}`:                               `Request body: {"jwt":"[REDACTED]"}`,
+		`Request body: {"username":"u","password":"p","authenticationCode":"123456"}`:        `Request body: {"username":"u","password":"[REDACTED]","authenticationCode":"[REDACTED]"}`,
+		`Listing devices for scope: AQ`:                                                      `Listing devices for scope: AQ`,
+	}
+	for input, want := range cases {
+		if got := RedactString(input); got != want {
+			t.Errorf("RedactString(%q)\n got %q\nwant %q", input, got, want)
+		}
+	}
+}
+
+func TestLoggerRedactsSecrets(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newLogger(&buf, "Kapua", LogLevelDebug, "json").With("password", "hunter2", "header", "Bearer token-1")
+	logger.Debug(`Request body: {"username":"u","password":"hunter2"}`)
+	logger.With("api_key", "k-1", "err", errors.New(`login failed for {"apiKey":"k-1"}`)).Error("failed")
+
+	out := buf.String()
+	for _, secret := range []string{"hunter2", "token-1", "k-1"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("secret %q leaked into %s", secret, out)
+		}
+	}
+	if !strings.Contains(out, `"username\":\"u\"`) && !strings.Contains(out, `\"username\":\"u\"`) {
+		t.Errorf("expected non-secret fields to remain: %s", out)
+	}
+}
+
+func TestRedactStringIsIdempotent(t *testing.T) {
+	once := RedactString(`Authorization: Bearer abc password=x {"apiKey":"y"}`)
+	if twice := RedactString(once); twice != once {
+		t.Errorf("expected idempotent redaction, got %q then %q", once, twice)
+	}
+}